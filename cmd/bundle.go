@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput         string
+	exportIncludeSecrets bool
+	exportSignKey        string
+	exportNewKey         string
+	importVerifyKey      string
+	importDryRun         bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the settings, profiles and tool selection as a bundle",
+	Long: `Writes a JSON bundle that "jtpck import" turns into the same setup on
+another machine: settings, profiles and enabled tools. Directory mappings
+and the secret backend stay behind, and user IDs are only included with
+--include-secrets.
+
+Sign bundles you hand out so recipients can check them:
+  jtpck export --new-signing-key team.key      # writes team.key and team.key.pub
+  jtpck export --sign team.key -o onboarding.json
+  jtpck import onboarding.json --verify team.key.pub`,
+	Args: cobra.NoArgs,
+	Run:  runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import FILE|- [user_id]",
+	Short: "Set up JTPCK from an exported bundle",
+	Long: `Applies a bundle made by "jtpck export": its settings replace the current
+ones, its profiles are added, and the wrappers, aliases and tool config
+files are regenerated as setup would.
+
+When the bundle carries no user ID for its active profile, give yours as
+the second argument or enter it when asked.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run:  runImport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the bundle to a file instead of stdout")
+	exportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "Include user IDs (the bundle then grants access to your accounts)")
+	exportCmd.Flags().StringVar(&exportSignKey, "sign", "", "Sign the bundle with this signing key")
+	exportCmd.Flags().StringVar(&exportNewKey, "new-signing-key", "", "Create a signing key at this path (and PATH.pub) and exit")
+	importCmd.Flags().StringVar(&importVerifyKey, "verify", "", "Require a signature by this public key (a .pub file or the key itself)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what the bundle would change without writing anything")
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	if exportNewKey != "" {
+		pub, err := config.GenerateSigningKey(exportNewKey)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Signing key written to %s (keep it private)\n", exportNewKey)
+		fmt.Printf("  Public key %s.pub: %s\n", exportNewKey, config.KeyFingerprint(pub))
+		return
+	}
+
+	bundle, err := loadConfigOrExit().Export(exportIncludeSecrets)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if exportSignKey != "" {
+		key, err := config.ReadSigningKey(exportSignKey)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := bundle.Sign(key); err != nil {
+			fmt.Printf("Error signing bundle: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+
+	if exportOutput == "" || exportOutput == "-" {
+		if exportIncludeSecrets {
+			fmt.Fprintln(os.Stderr, "jtpck: warning: the bundle contains user IDs; treat it like a password")
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	write := func(path string, data []byte) error { return os.WriteFile(path, data, 0644) }
+	if exportIncludeSecrets {
+		write = config.WritePrivateFile
+	}
+	if err := write(exportOutput, data); err != nil {
+		fmt.Printf("Error writing bundle: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Bundle written to %s\n", exportOutput)
+	if bundle.Signature != nil {
+		fmt.Printf("  Signed by %s\n", bundle.Signer())
+	}
+	if exportIncludeSecrets {
+		fmt.Println("  It contains user IDs; treat it like a password.")
+	}
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	bundle := readBundleOrExit(args[0])
+	verifyBundleOrExit(bundle)
+
+	cfg := config.New("", "")
+	if config.Exists() {
+		cfg = loadConfigOrExit()
+	}
+	updated, err := cfg.ImportBundle(bundle)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	active := updated.ActiveProfileName()
+	if len(args) > 1 {
+		userID := strings.ToLower(strings.TrimSpace(args[1]))
+		if !validateUUID(userID) {
+			fmt.Printf("Error: Invalid user ID format. Must be a valid UUID (e.g., 12345678-1234-1234-1234-123456789abc)\n")
+			os.Exit(1)
+		}
+		updated.SetProfileUserID(active, userID)
+	}
+	if p, _ := updated.Profile(active); p.UserID == "" {
+		switch {
+		case importDryRun || demoMode:
+			fmt.Printf("The bundle has no user ID for profile %s; the import will ask for it.\n", active)
+		case args[0] == "-":
+			fmt.Printf("Error: the bundle has no user ID for profile %s; pass yours: jtpck import - USER_ID\n", active)
+			os.Exit(1)
+		default:
+			updated.SetProfileUserID(active, promptUserIDOrExit())
+		}
+	}
+
+	describeImport(cfg, updated)
+	if importDryRun || demoMode {
+		fmt.Println("(dry run - no files were modified)")
+		return
+	}
+
+	saveConfigOrExit(updated)
+	err = refreshInstall(updated, config.AffectsAll, false, func(action string) {
+		fmt.Printf("  %s\n", action)
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Bundle imported. Open a new shell to pick up the aliases.")
+}
+
+func readBundleOrExit(path string) *config.Bundle {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Printf("Error reading bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	bundle, err := config.ParseBundle(data)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return bundle
+}
+
+// verifyBundleOrExit requires a signature by --verify when given, and
+// otherwise still rejects a bundle whose signature does not match
+func verifyBundleOrExit(bundle *config.Bundle) {
+	var trusted ed25519.PublicKey
+	if importVerifyKey != "" {
+		key, err := config.ReadPublicKey(importVerifyKey)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		trusted = key
+	} else if bundle.Signature == nil {
+		fmt.Println("⚠ The bundle is not signed.")
+		return
+	}
+
+	if err := bundle.Verify(trusted); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if trusted != nil {
+		fmt.Printf("✓ Signed by the trusted key %s\n", bundle.Signer())
+	} else {
+		fmt.Printf("⚠ Signed by %s, which was not checked (use --verify)\n", bundle.Signer())
+	}
+}
+
+// promptUserIDOrExit asks for a user ID with the setup input screen
+func promptUserIDOrExit() string {
+	finalModel, err := runProgram(ui.NewInputModel(""))
+	if err != nil {
+		fmt.Printf("Error running input: %v\n", err)
+		os.Exit(1)
+	}
+	input := finalModel.(ui.InputModel)
+	if !input.Done() {
+		fmt.Println("Import cancelled.")
+		os.Exit(1)
+	}
+	return input.GetUserID()
+}
+
+// describeImport lists the settings and profiles the import changes
+func describeImport(before, after *config.Config) {
+	var changes []string
+	keys := after.SettingKeys()
+	for _, key := range before.SettingKeys() {
+		if !contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		old, _ := before.Get(key)
+		value, _ := after.Get(key)
+		if old != value {
+			changes = append(changes, fmt.Sprintf("  %s: %s → %s", key, old, value))
+		}
+	}
+
+	var added []string
+	for name := range after.Profiles {
+		if _, ok := before.Profiles[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	if len(added) > 0 {
+		changes = append(changes, "  profiles added: "+strings.Join(added, ", "))
+	}
+	if before.ActiveProfileName() != after.ActiveProfileName() {
+		changes = append(changes, "  active profile: "+after.ActiveProfileName())
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("The bundle matches the current settings.")
+		return
+	}
+	fmt.Println("Importing:")
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+}
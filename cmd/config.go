@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change individual settings",
+	Long: `Reads and changes settings in config.json without rerunning setup.
+Changes are applied to the wrappers, shell aliases and tool config files
+they affect.
+
+Lists are comma-separated:
+  jtpck config set tools claude,codex
+  jtpck config set env_policy.OTEL_SERVICE_NAME keep
+
+Settings are layered; later layers win:
+  system   /etc/jtpck/config.json (may also lock keys)
+  user     config.json, edited by these commands
+  project  .jtpck.json in the working directory or a parent
+  env      JTPCK_<KEY>, e.g. JTPCK_LOG_PROMPTS=true
+  flag     --setting KEY=VALUE
+
+Project files can only set settings read at launch, and can turn prompt and
+tool logging off but not on. get and list print the effective value for the
+working directory.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print a setting",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Change a setting",
+	Args:  cobra.ExactArgs(2),
+	Run:   runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Restore a setting's default",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigUnset,
+}
+
+var configShowOrigin bool
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print every setting",
+	Args:  cobra.NoArgs,
+	Run:   runConfigList,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit config.json in $EDITOR",
+	Args:  cobra.NoArgs,
+	Run:   runConfigEdit,
+}
+
+func init() {
+	configListCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "Show which layer set each value")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// effectiveConfig resolves every layer for the working directory
+func effectiveConfig(cfg *config.Config) *config.Resolved {
+	cwd, _ := os.Getwd()
+	return resolveConfigOrExit(cfg, cwd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) {
+	value, err := effectiveConfig(loadConfigOrExit()).Get(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) {
+	cfg := loadConfigOrExit()
+	exitIfLocked(cfg, args[0])
+	affects, err := cfg.Set(args[0], args[1])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	applySettings(cfg, affects)
+	value, _ := cfg.Get(args[0])
+	fmt.Printf("✓ %s = %s\n", args[0], value)
+	noteOverride(cfg, args[0])
+	if args[0] == "claude_mode" && value == config.ClaudeModeSettings {
+		fmt.Println(claudeSettingsModeNote)
+	}
+	if args[0] == "codex_profiles" && value != "" {
+		fmt.Println(codexProfilesNote)
+	}
+}
+
+const codexProfilesNote = "  Note: only the jtpck wrapper applies codex_profiles; Codex started without it (IDE extensions,\n" +
+	"        a full path) sends telemetry from every profile"
+
+func runConfigUnset(cmd *cobra.Command, args []string) {
+	cfg := loadConfigOrExit()
+	exitIfLocked(cfg, args[0])
+	affects, err := cfg.Unset(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	applySettings(cfg, affects)
+
+	resolved := effectiveConfig(cfg)
+	value, _ := resolved.Get(args[0])
+	fmt.Printf("✓ %s = %s (%s)\n", args[0], value, resolved.Origin(args[0]).Layer)
+}
+
+func runConfigList(cmd *cobra.Command, args []string) {
+	resolved := effectiveConfig(loadConfigOrExit())
+	for _, key := range resolved.SettingKeys() {
+		value, _ := resolved.Get(key)
+		if configShowOrigin {
+			fmt.Printf("%s\t%s=%s\n", resolved.Origin(key), key, value)
+		} else {
+			fmt.Printf("%s=%s\n", key, value)
+		}
+	}
+}
+
+// exitIfLocked refuses to change a setting pinned by the system config
+func exitIfLocked(cfg *config.Config, key string) {
+	if _, err := config.LookupSetting(key); err != nil {
+		return
+	}
+	if resolved := resolveConfigOrExit(cfg, ""); resolved.Locked(key) {
+		fmt.Printf("Error: %s is locked by %s\n", key, resolved.Origin(key).Source)
+		os.Exit(1)
+	}
+}
+
+// noteOverride points out when a layer above config.json hides a new value
+func noteOverride(cfg *config.Config, key string) {
+	resolved := effectiveConfig(cfg)
+	switch origin := resolved.Origin(key); origin.Layer {
+	case config.LayerProject, config.LayerEnv, config.LayerFlag:
+		value, _ := resolved.Get(key)
+		fmt.Printf("  Note: %s overrides it here (%s = %s)\n", origin.Source, key, value)
+	}
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) {
+	cfg := loadConfigOrExit()
+	original, err := os.ReadFile(config.ConfigPath())
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Edit a private copy so a half-written file is never live
+	tmp, err := os.CreateTemp("", "jtpck-config-*.json")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.Remove(tmp.Name())
+	tmp.Write(original)
+	tmp.Close()
+
+	var updated *config.Config
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			fmt.Printf("Error running editor: %v\n", err)
+			os.Exit(1)
+		}
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("No changes.")
+			return
+		}
+
+		updated, err = cfg.ApplyJSON(edited)
+		if err == nil {
+			break
+		}
+		fmt.Printf("Error: %v\n", err)
+		fmt.Print("Edit again? (y/n): ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Changes discarded.")
+			return
+		}
+	}
+
+	affects := config.ChangedSettings(cfg, updated)
+	if updated.ActiveProfileName() != cfg.ActiveProfileName() || !reflect.DeepEqual(updated.Profiles, cfg.Profiles) {
+		affects |= config.AffectsWrappers | config.AffectsCodex | config.AffectsGemini
+	}
+	applySettings(updated, affects)
+	fmt.Println("✓ Config updated")
+}
+
+// applySettings saves the config and regenerates the files a change affects
+func applySettings(cfg *config.Config, affects config.Affects) {
+	saveConfigOrExit(cfg)
+	err := refreshInstall(cfg, affects, demoMode, func(action string) {
+		fmt.Printf("  %s\n", action)
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runEditor opens a file in $VISUAL or $EDITOR (default vi). Like git, the
+// variable may hold arguments, so it runs through the shell.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+	}
+
+	c := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jtpck/installer/config"
 	"github.com/jtpck/installer/shell"
 	"github.com/jtpck/installer/ui"
@@ -13,35 +12,44 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var configureProfile string
+
 var configureCmd = &cobra.Command{
 	Use:   "configure",
 	Short: "Reconfigure JTPCK telemetry settings",
-	Long:  `Update your user ID and regenerate wrapper scripts.`,
-	Run:   runConfigure,
+	Long: `Update your user ID and regenerate wrapper scripts.
+
+Edits the active profile unless --profile names another one.`,
+	Run: runConfigure,
 }
 
 func init() {
+	configureCmd.Flags().StringVar(&configureProfile, "profile", "", "Profile to edit (default: the active profile)")
+	configureCmd.Flags().BoolVar(&supervise, "supervise", false, "Supervise tool sessions and report their start, duration and exit code")
 	rootCmd.AddCommand(configureCmd)
 }
 
 func runConfigure(cmd *cobra.Command, args []string) {
-	tools := []string{"claude", "codex", "gemini"}
-
 	// Load existing config
 	var currentValue string
+	target := configureProfile
 	if config.Exists() {
 		cfg, err := config.Load()
 		if err != nil {
 			fmt.Printf("Error loading config: %v\n", err)
 			os.Exit(1)
 		}
-		currentValue = cfg.UserID
+		if target == "" {
+			target = cfg.ActiveProfileName()
+		}
+		if profile, ok := cfg.Profile(target); ok {
+			currentValue = profile.UserID
+		}
 	}
 
 	// Run input screen (skip animation for reconfigure)
 	inputModel := ui.NewInputModel(currentValue)
-	p := tea.NewProgram(inputModel)
-	finalModel, err := p.Run()
+	finalModel, err := runProgram(inputModel)
 	if err != nil {
 		fmt.Printf("Error running input: %v\n", err)
 		os.Exit(1)
@@ -54,31 +62,19 @@ func runConfigure(cmd *cobra.Command, args []string) {
 	}
 
 	userID := inputResult.GetUserID()
+	cfg := updatedConfig(cmd, target, userID)
 
-	var actions []string
-
-	// Generate per-application env vars
-	appEnvs := config.AppEnvs(userID, endpoint)
+	effective := resolveConfigOrExit(cfg, "").Config
 
-	// Add Claude Code action
-	actions = append(actions, "Enabled Claude Code telemetry (wrapper script)")
+	// Tool config files and wrappers always carry the active profile
+	active, _ := effective.Profile(effective.ActiveProfileName())
+	tools := effective.EnabledTools()
+	actions := enableToolTelemetry(effective, active, false)
 
-	// Configure Codex telemetry config file
-	actions = append(actions, fmt.Sprintf("Enabled Codex telemetry (config at %s)", config.CodexConfigPath()))
-	if err := config.EnableCodexTelemetry(userID, endpoint, false, func(msg string) { fmt.Println(msg) }); err != nil {
-		fmt.Printf("Error enabling Codex telemetry: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Configure Gemini telemetry settings/file
-	actions = append(actions, fmt.Sprintf("Enabled Gemini CLI telemetry (settings at %s)", config.GeminiSettingsPath()))
-	if err := config.EnableGeminiTelemetry(userID, endpoint, false, func(msg string) { fmt.Println(msg) }); err != nil {
-		fmt.Printf("Error enabling Gemini telemetry: %v\n", err)
-		os.Exit(1)
-	}
+	// Generate per-application env vars
+	appEnvs := effective.ToolEnvs(active.UserID, active.Endpoint)
 
 	// Save updated config
-	cfg := config.New(userID, endpoint)
 	if err := cfg.Save(); err != nil {
 		fmt.Printf("Error saving config: %v\n", err)
 		os.Exit(1)
@@ -86,7 +82,7 @@ func runConfigure(cmd *cobra.Command, args []string) {
 
 	// Recreate wrappers
 	installed := validator.GetInstalledTools(tools)
-	if err := wrapper.CreateWrappers(appEnvs, installed); err != nil {
+	if err := wrapper.CreateWrappers(appEnvs, installed, wrapperOptions(effective)); err != nil {
 		fmt.Printf("Error creating wrappers: %v\n", err)
 		os.Exit(1)
 	}
@@ -94,7 +90,7 @@ func runConfigure(cmd *cobra.Command, args []string) {
 	installedTools := wrapper.GetInstalledTools(tools)
 
 	// Auto-install aliases to shell config
-	if err := shell.InstallAliases(installedTools); err != nil {
+	if err := syncAliases(effective, installedTools, true); err != nil {
 		fmt.Printf("Warning: Could not auto-install aliases: %v\n", err)
 		fmt.Println("You'll need to manually add aliases to your shell config.")
 	}
@@ -105,8 +101,7 @@ func runConfigure(cmd *cobra.Command, args []string) {
 
 	// Run success screen
 	successModel := ui.NewSuccessModel(shellConfig, installedTools, aliasCommands, true, actions)
-	p = tea.NewProgram(successModel)
-	if _, err := p.Run(); err != nil {
+	if _, err := runProgram(successModel); err != nil {
 		fmt.Printf("Error running success screen: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/internal/harness"
+	"github.com/jtpck/installer/shell"
+	"github.com/jtpck/installer/wrapper"
+)
+
+const (
+	testUserID  = "12345678-1234-1234-1234-123456789abc"
+	otherUserID = "87654321-4321-4321-4321-cba987654321"
+)
+
+var anyKey = []tea.Msg{tea.KeyMsg{Type: tea.KeyEnter}}
+
+// The files setup edits, as the user had them before
+var originalFiles = map[string]string{
+	".zshrc":                "export EDITOR=vim\n",
+	".codex/config.toml":    "# Codex settings\nmodel = \"o3\"  # default model\n\n[profiles.fast]\nmodel = \"gpt-5-mini\"\n",
+	".gemini/settings.json": "{\n  // UI\n  \"theme\": \"dark\"\n}\n",
+}
+
+// generatedFiles are the files to compare with golden files, by golden name
+var generatedFiles = map[string]string{
+	"zshrc":          ".zshrc",
+	"codex.toml":     ".codex/config.toml",
+	"gemini.json":    ".gemini/settings.json",
+	"gemini.env":     ".gemini/.env",
+	"claude-wrapper": "",
+	"codex-wrapper":  "",
+	"gemini-wrapper": "",
+}
+
+func writeOriginals(t *testing.T, e *harness.Env) {
+	t.Helper()
+	for rel, content := range originalFiles {
+		os.MkdirAll(filepath.Dir(e.Path(rel)), 0755)
+		if err := os.WriteFile(e.Path(rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkGolden compares the generated files with testdata/<prefix>-<name>.golden
+func checkGolden(t *testing.T, e *harness.Env, prefix string) {
+	t.Helper()
+	self, _ := os.Executable()
+	for name, rel := range generatedFiles {
+		path := e.Path(rel)
+		if rel == "" {
+			path = wrapper.WrapperPath(strings.TrimSuffix(name, "-wrapper"))
+		}
+		data, _ := os.ReadFile(path)
+		harness.Golden(t, prefix+"-"+name, e.Normalize(string(data), self, "$JTPCK_BIN"))
+	}
+}
+
+func TestSetupConfigureUninstall(t *testing.T) {
+	e := newEnv(t, "claude", "codex", "gemini")
+	writeOriginals(t, e)
+
+	// Interactive setup: skip the animation, enter the user ID, leave the
+	// success screen
+	screens := harness.NewScreens(t, anyKey, harness.Keys(testUserID+"\n"), anyKey)
+	jtpck(t, screens)
+	if want := []string{"ui.AnimationModel", "ui.InputModel", "ui.SuccessModel"}; strings.Join(screens.Shown, " ") != strings.Join(want, " ") {
+		t.Errorf("screens = %v, want %v", screens.Shown, want)
+	}
+	checkGolden(t, e, "setup")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := cfg.Profile(cfg.ActiveProfileName()); p.UserID != testUserID {
+		t.Errorf("saved user ID = %q", p.UserID)
+	}
+
+	// The wrapper launches the tool through jtpck run with the telemetry
+	// env, once a local collector stands in for the real endpoint
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	run := exec.Command(wrapper.WrapperPath("claude"), "--print", "hello world")
+	run.Env = append(os.Environ(), "JTPCK_TEST_MAIN=1", "JTPCK_ENDPOINT="+collector.URL)
+	if out, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("claude wrapper: %v\n%s", err, out)
+	}
+	call := e.LastCall(t, "claude")
+	if strings.Join(call.Args, "|") != "--print|hello world" {
+		t.Errorf("claude args = %q", call.Args)
+	}
+	if call.Env["CLAUDE_CODE_ENABLE_TELEMETRY"] != "1" || call.Env["OTEL_EXPORTER_OTLP_ENDPOINT"] != collector.URL ||
+		!strings.Contains(call.Env["OTEL_EXPORTER_OTLP_HEADERS"], testUserID) {
+		t.Errorf("claude ran without the telemetry env: %v", call.Env)
+	}
+
+	// Configure replaces the prefilled user ID
+	edit := strings.Repeat("<backspace>", len(testUserID)) + otherUserID + "\n"
+	jtpck(t, harness.NewScreens(t, harness.Keys(edit), anyKey), "configure")
+	checkGolden(t, e, "configure")
+
+	jtpck(t, nil, "uninstall")
+	for rel, original := range originalFiles {
+		if got := e.Read(t, rel); got != original {
+			t.Errorf("%s after uninstall = %q, want the original", rel, got)
+		}
+	}
+	for _, dir := range jtpckDirs() {
+		if _, err := os.Stat(dir); err == nil {
+			t.Errorf("%s survived uninstall", dir)
+		}
+	}
+	if _, err := os.Stat(e.Path(".gemini/.env")); err == nil {
+		t.Error(".gemini/.env survived uninstall")
+	}
+}
+
+func TestUninstallRestoresGeminiTelemetry(t *testing.T) {
+	e := newEnv(t, "gemini")
+	original := "{\n  // sent to Cloud Trace\n  \"telemetry\": {\"enabled\": false, \"target\": \"gcp\"},\n  \"theme\": \"dark\"\n}\n"
+	os.MkdirAll(e.Path(".gemini"), 0755)
+	os.WriteFile(e.Path(".gemini/settings.json"), []byte(original), 0644)
+
+	jtpck(t, nil, "--yes", testUserID)
+	if got := e.Read(t, ".gemini/settings.json"); !strings.Contains(got, `"target": "local"`) {
+		t.Fatalf("setup did not enable Gemini telemetry:\n%s", got)
+	}
+	jtpck(t, nil, "uninstall")
+	if got := e.Read(t, ".gemini/settings.json"); got != original {
+		t.Errorf("settings.json after uninstall:\n%s\nwant\n%s", got, original)
+	}
+}
+
+func TestSetupKeepsConfigItCannotLoad(t *testing.T) {
+	newEnv(t, "claude")
+	t.Setenv("JTPCK_PASSPHRASE", "correct horse")
+	jtpck(t, nil, "--yes", testUserID)
+	jtpck(t, nil, "secrets", "use", "age")
+	saved, _ := os.ReadFile(config.ConfigPath())
+	sealed, _ := os.ReadFile(config.CredentialsPath() + ".age")
+
+	setup := exec.Command(os.Args[0], "--yes", otherUserID)
+	setup.Env = append(os.Environ(), "JTPCK_TEST_MAIN=1", "JTPCK_PASSPHRASE=wrong")
+	if out, err := setup.CombinedOutput(); err == nil || !strings.Contains(string(out), "Error loading config") {
+		t.Errorf("setup with the wrong passphrase: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(config.ConfigPath()); string(data) != string(saved) {
+		t.Errorf("config.json replaced:\n%s", data)
+	}
+	if data, _ := os.ReadFile(config.CredentialsPath() + ".age"); len(sealed) == 0 || string(data) != string(sealed) {
+		t.Error("credentials.age changed")
+	}
+	if _, err := os.Stat(config.CredentialsPath()); !os.IsNotExist(err) {
+		t.Errorf("plaintext credentials written: %v", err)
+	}
+}
+
+func TestUninstallEveryShell(t *testing.T) {
+	e := newEnv(t, "claude")
+	originals := map[string]string{".zshrc": "export EDITOR=vim\n", ".bashrc": "set -o vi\n"}
+	for rc, content := range originals {
+		os.WriteFile(e.Path(rc), []byte(content), 0644)
+	}
+	jtpck(t, nil, "--yes", testUserID)
+	jtpck(t, nil, "config", "set", "shells", "zsh,bash")
+	for rc := range originals {
+		if !strings.Contains(e.Read(t, rc), "alias claude=") {
+			t.Fatalf("no aliases in %s", rc)
+		}
+	}
+	// Without a backup the alias block is taken out
+	os.Remove(shell.BackupPathFor(".bashrc"))
+
+	jtpck(t, nil, "uninstall")
+	for rc, original := range originals {
+		if got := e.Read(t, rc); got != original {
+			t.Errorf("%s after uninstall = %q, want %q", rc, got, original)
+		}
+	}
+}
+
+func TestSetupPrefillsActiveProfile(t *testing.T) {
+	newEnv(t, "claude")
+	jtpck(t, nil, "--yes", testUserID)
+	jtpck(t, nil, "profile", "add", "acme", otherUserID)
+	jtpck(t, nil, "profile", "use", "acme")
+
+	// Answer "Reconfigure?", then accept the prefilled user ID
+	r, w, _ := os.Pipe()
+	w.WriteString("y\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	jtpck(t, harness.NewScreens(t, anyKey, harness.Keys("\n"), anyKey))
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme, _ := cfg.Profile("acme")
+	def, _ := cfg.Profile(config.DefaultProfile)
+	if acme.UserID != otherUserID || def.UserID != testUserID {
+		t.Errorf("after rerunning setup: acme = %q, default = %q", acme.UserID, def.UserID)
+	}
+}
+
+func TestSetupYes(t *testing.T) {
+	e := newEnv(t, "claude")
+
+	jtpck(t, nil, "--yes", testUserID)
+	if _, err := os.Stat(wrapper.WrapperPath("claude")); err != nil {
+		t.Errorf("no claude wrapper: %v", err)
+	}
+	if _, err := os.Stat(wrapper.WrapperPath("codex")); err == nil {
+		t.Error("wrote a wrapper for codex, which is not installed")
+	}
+	if !strings.Contains(e.Read(t, ".zshrc"), "alias claude=") {
+		t.Errorf(".zshrc has no claude alias:\n%s", e.Read(t, ".zshrc"))
+	}
+
+	// A second unattended run keeps the saved user ID
+	jtpck(t, nil, "--yes")
+	cfg, _ := config.Load()
+	if p, _ := cfg.Profile(cfg.ActiveProfileName()); p.UserID != testUserID {
+		t.Errorf("user ID = %q after --yes without one", p.UserID)
+	}
+}
+
+func TestClaudeSettingsMode(t *testing.T) {
+	e := newEnv(t, "claude")
+	os.MkdirAll(e.Path(".claude"), 0700)
+	original := "{\n  \"model\": \"opus\"\n}\n"
+	os.WriteFile(e.Path(".claude/settings.json"), []byte(original), 0600)
+
+	jtpck(t, nil, "--yes", testUserID)
+	jtpck(t, nil, "config", "set", "claude_mode", "settings")
+	if _, err := os.Stat(wrapper.WrapperPath("claude")); err == nil {
+		t.Error("settings mode kept the claude wrapper")
+	}
+	if strings.Contains(e.Read(t, ".zshrc"), "alias claude=") {
+		t.Errorf("settings mode kept the claude alias:\n%s", e.Read(t, ".zshrc"))
+	}
+	settings := e.Read(t, ".claude/settings.json")
+	if !strings.Contains(settings, `"CLAUDE_CODE_ENABLE_TELEMETRY": "1"`) || !strings.Contains(settings, "Bearer "+testUserID) {
+		t.Errorf("settings.json has no telemetry env:\n%s", settings)
+	}
+
+	// Back to the wrapper, settings.json is as it was
+	jtpck(t, nil, "config", "set", "claude_mode", "wrapper")
+	if got := e.Read(t, ".claude/settings.json"); got != original {
+		t.Errorf("settings.json after leaving settings mode = %q", got)
+	}
+	if _, err := os.Stat(wrapper.WrapperPath("claude")); err != nil {
+		t.Errorf("no claude wrapper after leaving settings mode: %v", err)
+	}
+
+	jtpck(t, nil, "config", "set", "claude_mode", "settings")
+	jtpck(t, nil, "uninstall")
+	if got := e.Read(t, ".claude/settings.json"); got != original {
+		t.Errorf("settings.json after uninstall = %q", got)
+	}
+}
+
+func TestSystemManagedSettings(t *testing.T) {
+	newEnv(t, "claude")
+	root := t.TempDir()
+	for _, dir := range []string{"root", "home/ann/.claude"} {
+		os.MkdirAll(filepath.Join(root, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(root, "home/ann/.claude/settings.json"), []byte(`{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0"}}`), 0644)
+
+	jtpck(t, nil, "--root", root, "--home", "/root", "--system", testUserID)
+	managed := config.ClaudeManagedSettingsPath()
+	data, err := os.ReadFile(managed)
+	if err != nil || !strings.Contains(string(data), "Bearer "+testUserID) {
+		t.Fatalf("managed settings = %s, %v", data, err)
+	}
+	if info, _ := os.Stat(managed); info.Mode().Perm() != 0644 {
+		t.Errorf("managed settings mode = %04o, want 0644", info.Mode().Perm())
+	}
+
+	// Without the user ID, it repairs what was written
+	os.WriteFile(managed, []byte(`{"env": {}}`), 0644)
+	jtpck(t, nil, "--root", root, "--home", "/root", "--system")
+	if data, _ := os.ReadFile(managed); !strings.Contains(string(data), "Bearer "+testUserID) {
+		t.Errorf("repaired managed settings = %s", data)
+	}
+	if overrides, _ := config.ClaudeManagedOverrides(map[string]string{"CLAUDE_CODE_ENABLE_TELEMETRY": "1"}, config.ClaudeUserSettingsFiles(), nil); len(overrides) != 1 {
+		t.Errorf("user overrides = %v", overrides)
+	}
+
+	jtpck(t, nil, "--root", root, "--home", "/root", "uninstall", "--system")
+	if _, err := os.Stat(managed); !os.IsNotExist(err) {
+		t.Errorf("managed settings JTPCK created survived uninstall --system: %v", err)
+	}
+	if _, err := os.Stat(config.ClaudeManagedRecordPath()); !os.IsNotExist(err) {
+		t.Errorf("managed record survived uninstall --system: %v", err)
+	}
+}
+
+func TestPausedLaunchDisablesExporters(t *testing.T) {
+	e := newEnv(t, "codex", "gemini")
+	jtpck(t, nil, "--yes", testUserID)
+	jtpck(t, nil, "pause", "0")
+
+	for _, tool := range []string{"codex", "gemini"} {
+		run := exec.Command(wrapper.WrapperPath(tool), "hello")
+		run.Env = os.Environ()
+		if out, err := run.CombinedOutput(); err != nil {
+			t.Fatalf("%s wrapper: %v\n%s", tool, err, out)
+		}
+	}
+	// config.toml still names JTPCK's collector, so both exporters go off
+	codex := e.LastCall(t, "codex")
+	if got := strings.Join(codex.Args, " "); got != `-c otel.exporter="none" -c otel.trace_exporter="none" hello` {
+		t.Errorf("paused codex args = %s", got)
+	}
+	if gemini := e.LastCall(t, "gemini"); gemini.Env["GEMINI_TELEMETRY_ENABLED"] != "false" {
+		t.Errorf("paused gemini env = %v", gemini.Env)
+	}
+}
+
+func TestSetupCancelled(t *testing.T) {
+	e := newEnv(t, "claude")
+
+	jtpck(t, harness.NewScreens(t, anyKey, harness.Keys("<esc>")))
+	if config.Exists() {
+		t.Error("cancelled setup saved a config")
+	}
+	if got := e.Read(t, ".zshrc"); got != "" {
+		t.Errorf("cancelled setup wrote .zshrc:\n%s", got)
+	}
+}
+
+func TestRunOnlySavesUpgradedConfig(t *testing.T) {
+	e := newEnv(t, "claude", "codex")
+	jtpck(t, nil, "--yes", testUserID)
+	os.WriteFile(config.ConfigPath(), []byte(`{"version":2,"endpoint":"`+config.DefaultEndpoint+`"}`), 0600)
+	os.Remove(config.CodexConfigPath())
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	run := exec.Command(wrapper.WrapperPath("claude"), "hello")
+	run.Env = append(os.Environ(), "JTPCK_TEST_MAIN=1", "JTPCK_ENDPOINT="+collector.URL)
+	if out, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("claude wrapper: %v\n%s", err, out)
+	}
+	if e.LastCall(t, "claude").Args[0] != "hello" {
+		t.Error("the tool did not run")
+	}
+	if data, _ := os.ReadFile(config.ConfigPath()); !strings.Contains(string(data), `"version": `+strconv.Itoa(config.CurrentVersion)) {
+		t.Errorf("config.json after jtpck run = %s", data)
+	}
+	// Regenerating the tool configs is left to jtpck migrate
+	if _, err := os.Stat(config.CodexConfigPath()); !os.IsNotExist(err) {
+		t.Errorf("jtpck run rewrote Codex config.toml: %v", err)
+	}
+}
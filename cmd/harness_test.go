@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/internal/harness"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The wrappers setup writes launch the jtpck binary that created them,
+// which in tests is the test binary: run the CLI when it is started that way
+func TestMain(m *testing.M) {
+	if os.Getenv("JTPCK_TEST_MAIN") == "1" {
+		if err := Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newEnv isolates a test in a temporary home with fake tools on PATH
+func newEnv(t *testing.T, tools ...string) *harness.Env {
+	t.Helper()
+	if _, err := os.Stat(config.SystemConfigPath()); err == nil {
+		t.Skipf("%s would change the generated files", config.SystemConfigPath())
+	}
+	e := harness.New(t, tools...)
+	t.Cleanup(func() { shownWarnings = map[string]bool{} })
+	return e
+}
+
+// jtpck runs the CLI in-process with scripted interactive screens
+func jtpck(t *testing.T, screens *harness.Screens, args ...string) {
+	t.Helper()
+	resetFlags(rootCmd)
+	if screens == nil {
+		screens = harness.NewScreens(t)
+	}
+	saved := runProgram
+	runProgram = screens.Run
+	defer func() { runProgram = saved }()
+
+	// cobra falls back to os.Args, the test flags, when args is nil
+	rootCmd.SetArgs(append([]string{}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("jtpck %v: %v", args, err)
+	}
+	screens.Done()
+}
+
+// resetFlags returns every flag to its default, since the commands are
+// package variables that keep the values of the previous run
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
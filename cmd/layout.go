@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
+	"github.com/spf13/cobra"
+)
+
+var layoutDryRun bool
+
+var layoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Show where JTPCK keeps its files",
+	Long: `JTPCK follows the XDG Base Directory spec:
+
+  config  $XDG_CONFIG_HOME/jtpck (~/.config/jtpck)       config.json, credentials, hooks
+  state   $XDG_STATE_HOME/jtpck  (~/.local/state/jtpck)  pause state, health cache, shell backups
+  data    $XDG_DATA_HOME/jtpck   (~/.local/share/jtpck)  wrappers, offline spool
+
+Installs in ~/.jtpck are moved there automatically. Use "jtpck layout revert"
+to move back and stay in ~/.jtpck.`,
+	Args: cobra.NoArgs,
+	Run:  runLayout,
+}
+
+var layoutMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move a ~/.jtpck install into the XDG directories",
+	Args:  cobra.NoArgs,
+	Run:   runLayoutMigrate,
+}
+
+var layoutRevertCmd = &cobra.Command{
+	Use:   "revert",
+	Short: "Move the install back into ~/.jtpck and keep it there",
+	Args:  cobra.NoArgs,
+	Run:   runLayoutRevert,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{layoutMigrateCmd, layoutRevertCmd} {
+		c.Flags().BoolVar(&layoutDryRun, "dry-run", false, "List the moves without making them")
+	}
+	layoutCmd.AddCommand(layoutMigrateCmd, layoutRevertCmd)
+	rootCmd.AddCommand(layoutCmd)
+}
+
+func runLayout(cmd *cobra.Command, args []string) {
+	switch {
+	case !config.UsesLegacyLayout():
+		fmt.Println("Layout: XDG")
+	case config.LegacyLayoutPinned():
+		fmt.Println("Layout: legacy (~/.jtpck, kept by `jtpck layout revert`)")
+	default:
+		fmt.Println("Layout: legacy (~/.jtpck, pending migration)")
+	}
+	fmt.Printf("  Config:  %s\n", config.ConfigDir())
+	fmt.Printf("  State:   %s\n", config.StateDir())
+	fmt.Printf("  Data:    %s\n", config.DataDir())
+	fmt.Printf("  Backups: %s\n", config.BackupDir())
+}
+
+func runLayoutMigrate(cmd *cobra.Command, args []string) {
+	if !config.UsesLegacyLayout() {
+		fmt.Println("Already using the XDG directories.")
+		return
+	}
+	if layoutDryRun || demoMode {
+		printLayoutPlan(config.XDGMigrationPlan())
+		return
+	}
+
+	moves, err := config.MigrateToXDG()
+	reportLayoutMoves(moves)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	relocateWrappers()
+	fmt.Println("✓ Moved to the XDG directories. Open a new shell to pick up the new aliases.")
+}
+
+func runLayoutRevert(cmd *cobra.Command, args []string) {
+	if config.UsesLegacyLayout() {
+		fmt.Println("Already using ~/.jtpck.")
+		return
+	}
+	if layoutDryRun || demoMode {
+		printLayoutPlan(config.XDGRevertPlan())
+		return
+	}
+
+	moves, err := config.RevertXDG()
+	reportLayoutMoves(moves)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	relocateWrappers()
+	fmt.Println("✓ Moved back to ~/.jtpck. Open a new shell to pick up the new aliases.")
+}
+
+func printLayoutPlan(moves []config.LayoutMove, err error) {
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(moves) == 0 {
+		fmt.Println("Nothing to move.")
+		return
+	}
+	for _, move := range moves {
+		fmt.Printf("  would move %s → %s\n", move.From, move.To)
+	}
+}
+
+func reportLayoutMoves(moves []config.LayoutMove) {
+	for _, move := range moves {
+		fmt.Printf("  moved %s → %s\n", move.From, move.To)
+	}
+}
+
+// relocateWrappers regenerates the wrappers, which embed the pause and
+// credentials paths, and points the alias block at their new location
+func relocateWrappers() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: could not load config to regenerate wrappers: %v\n", err)
+		return
+	}
+
+	affects := config.AffectsWrappers
+	if len(shell.ShellConfigsWithAliases()) > 0 {
+		affects |= config.AffectsAliases
+	}
+	if err := refreshInstall(cfg, affects, false, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: %v\n", err)
+	}
+}
+
+// migrateLegacyLayout moves a ~/.jtpck install into the XDG directories the
+// first time any command runs, unless the user pinned it with `layout revert`.
+// `jtpck run` skips it so launching a tool never moves files around.
+func migrateLegacyLayout(cmd *cobra.Command) {
+	if demoMode || cmd == runCmd || cmd.Parent() == layoutCmd || cmd == layoutCmd {
+		return
+	}
+	if !config.UsesLegacyLayout() || config.LegacyLayoutPinned() {
+		return
+	}
+
+	if _, err := config.MigrateToXDG(); err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: could not move ~/.jtpck to the XDG directories: %v\n", err)
+		return
+	}
+	relocateWrappers()
+	fmt.Fprintf(os.Stderr, "jtpck: moved ~/.jtpck to %s, %s and %s (undo with `jtpck layout revert`)\n",
+		config.ConfigDir(), config.StateDir(), config.DataDir())
+}
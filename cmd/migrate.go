@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
+	"github.com/spf13/cobra"
+)
+
+var migrateDryRun bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade an install made by an older jtpck",
+	Long: `Upgrades config.json to the current schema version and regenerates the
+files derived from it: wrappers, the shell alias block, Codex config.toml and
+Gemini settings.json. Installs still in ~/.jtpck are moved to the XDG
+directories first (see "jtpck layout").
+
+Other commands do this automatically when config.json is out of date;
+--dry-run shows what would change.`,
+	Args: cobra.NoArgs,
+	Run:  runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would change without writing anything")
+	rootCmd.AddCommand(migrateCmd)
+
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		applyTargetOrExit()
+		if cmd == migrateCmd {
+			return
+		}
+		migrateLegacyLayout(cmd)
+		autoMigrate(cmd)
+	}
+}
+
+func runMigrate(cmd *cobra.Command, args []string) {
+	dryRun := migrateDryRun || demoMode
+
+	if config.UsesLegacyLayout() && !config.LegacyLayoutPinned() {
+		if dryRun {
+			printLayoutPlan(config.XDGMigrationPlan())
+		} else {
+			moves, err := config.MigrateToXDG()
+			reportLayoutMoves(moves)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if !config.Exists() {
+		fmt.Println("JTPCK is not configured; nothing to migrate.")
+		return
+	}
+	cfg := loadConfigOrExit()
+
+	if cfg.NeedsMigration() {
+		fmt.Printf("config.json: version %d → %d\n", cfg.LoadedVersion(), config.CurrentVersion)
+		for _, m := range config.MigrationsFrom(cfg.LoadedVersion()) {
+			fmt.Printf("  %d → %d: %s\n", m.From, m.From+1, m.Description)
+		}
+	} else {
+		fmt.Printf("config.json: already version %d\n", config.CurrentVersion)
+	}
+
+	report := func(action string) {
+		if dryRun {
+			fmt.Printf("  would %s\n", action)
+		} else {
+			fmt.Printf("  %s\n", action)
+		}
+	}
+	if err := upgradeInstall(cfg, dryRun, report); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if dryRun {
+		fmt.Println("(dry run - no files were modified)")
+	} else {
+		fmt.Println("✓ Migration complete")
+	}
+}
+
+// autoMigrate upgrades an out-of-date install before any command runs,
+// including `jtpck run`, so user IDs left in config.json by older versions
+// are moved to the secret store as soon as possible. `jtpck run` starts
+// every tool, so it only saves config.json; `jtpck migrate` regenerates the
+// files derived from it.
+func autoMigrate(cmd *cobra.Command) {
+	if demoMode || !config.Exists() {
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil || !cfg.NeedsMigration() {
+		return
+	}
+
+	from := cfg.LoadedVersion()
+	if cmd == runCmd {
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "jtpck: could not upgrade config from version %d: %v (try `jtpck migrate`)\n", from, err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "jtpck: upgraded config from version %d to %d; run `jtpck migrate` to regenerate wrappers and tool configs\n", from, config.CurrentVersion)
+		return
+	}
+	if err := upgradeInstall(cfg, false, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: could not upgrade config from version %d: %v (try `jtpck migrate`)\n", from, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "jtpck: upgraded config from version %d to %d\n", from, config.CurrentVersion)
+}
+
+// upgradeInstall saves the config at the current schema version and
+// regenerates everything derived from it. The alias block is only rewritten
+// where one is already installed. report, when set, is told about each step
+// before it runs.
+func upgradeInstall(cfg *config.Config, dryRun bool, report func(string)) error {
+	if report == nil {
+		report = func(string) {}
+	}
+
+	if cfg.NeedsMigration() {
+		report(fmt.Sprintf("save %s as version %d", config.ConfigPath(), config.CurrentVersion))
+		if !dryRun {
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("saving config: %w", err)
+			}
+		}
+	}
+
+	// Older versions left these readable by everyone
+	if !dryRun {
+		for _, path := range []string{config.CodexConfigPath(), config.GeminiSettingsPath(), shell.BackupPath()} {
+			if _, err := os.Stat(path); err == nil {
+				os.Chmod(path, 0600)
+			}
+		}
+	}
+
+	affects := config.AffectsWrappers | config.AffectsCodex | config.AffectsGemini
+	if len(shell.ShellConfigsWithAliases()) > 0 {
+		affects |= config.AffectsAliases
+	}
+	return refreshInstall(cfg, affects, dryRun, report)
+}
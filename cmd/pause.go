@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jtpck/installer/config"
+	"github.com/spf13/cobra"
+)
+
+const defaultPauseDuration = time.Hour
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [duration]",
+	Short: "Temporarily run tools without telemetry",
+	Long: `Pause telemetry so the wrappers launch tools untouched.
+
+Telemetry resumes automatically once the duration (default 1h) has passed.
+Use 0 to pause until you run 'jtpck resume'.
+
+  jtpck pause        # one hour
+  jtpck pause 20m
+  jtpck pause 0      # until resumed`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runPause,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume telemetry after a pause",
+	Args:  cobra.NoArgs,
+	Run:   runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+}
+
+func runPause(cmd *cobra.Command, args []string) {
+	duration := defaultPauseDuration
+	if len(args) > 0 {
+		d, err := time.ParseDuration(args[0])
+		if args[0] == "0" {
+			d, err = 0, nil
+		}
+		if err != nil || d < 0 {
+			fmt.Printf("Error: Invalid duration %q (e.g., 30m, 2h, or 0 for indefinite)\n", args[0])
+			os.Exit(1)
+		}
+		duration = d
+	}
+
+	if demoMode {
+		fmt.Println("⏸ Telemetry paused (DEMO MODE - no files were modified)")
+		return
+	}
+
+	state, err := config.Pause(duration)
+	if err != nil {
+		fmt.Printf("Error pausing telemetry: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("⏸ Telemetry %s\n", describePause(state))
+	fmt.Println("  Run 'jtpck resume' to resume early.")
+
+	// Claude Code reads settings.json directly, without a wrapper to check the pause
+	if cfg, err := config.Load(); err == nil {
+		if resolved, err := resolveConfig(cfg, ""); err == nil && resolved.Config.ToolEnabled("claude") && resolved.Config.ClaudeSettingsMode() {
+			fmt.Fprintf(os.Stderr, "jtpck: warning: claude_mode is settings, so Claude Code keeps sending telemetry from %s while paused\n", config.ClaudeSettingsPath())
+		}
+	}
+}
+
+func runResume(cmd *cobra.Command, args []string) {
+	if demoMode {
+		fmt.Println("▶ Telemetry resumed (DEMO MODE - no files were modified)")
+		return
+	}
+
+	state, err := config.LoadPause()
+	if err != nil {
+		fmt.Printf("Error reading pause state: %v\n", err)
+		os.Exit(1)
+	}
+	if state == nil {
+		fmt.Println("Telemetry is not paused.")
+		return
+	}
+
+	if err := config.Resume(); err != nil {
+		fmt.Printf("Error resuming telemetry: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("▶ Telemetry resumed")
+}
+
+// describePause renders a pause state for humans
+func describePause(state *config.PauseState) string {
+	if state.Indefinite() {
+		return "paused until 'jtpck resume'"
+	}
+	return fmt.Sprintf("paused until %s (%s left)", state.Until.Format("Jan 2 15:04"), state.Remaining())
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileEndpoint string
+	profileDirs     []string
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage JTPCK accounts for different clients or teams",
+	Long: `Profiles hold the user ID (and optionally endpoint) for other JTPCK accounts.
+
+The wrappers pick a profile at launch: the most specific directory mapped
+with --dir wins, otherwise the active profile is used.
+
+  jtpck profile add acme <user_id> --dir ~/clients/acme
+  jtpck profile use acme
+  jtpck profile use acme --dir ~/clients/acme-legacy
+  jtpck profile list
+  jtpck profile rm acme`,
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add NAME USER_ID",
+	Short: "Add or update a profile",
+	Args:  cobra.ExactArgs(2),
+	Run:   runProfileAdd,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Make a profile active, or map it to directories with --dir",
+	Args:  cobra.ExactArgs(1),
+	Run:   runProfileUse,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles and directory mappings",
+	Args:  cobra.NoArgs,
+	Run:   runProfileList,
+}
+
+var profileRmCmd = &cobra.Command{
+	Use:   "rm [NAME]",
+	Short: "Remove a profile, or only directory mappings with --dir",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runProfileRm,
+}
+
+func init() {
+	profileAddCmd.Flags().StringVar(&profileEndpoint, "endpoint", "", "Telemetry endpoint (default: same as the default profile)")
+	profileAddCmd.Flags().StringArrayVar(&profileDirs, "dir", nil, "Directory that should use this profile (repeatable)")
+	profileUseCmd.Flags().StringArrayVar(&profileDirs, "dir", nil, "Map directories to the profile instead of activating it (repeatable)")
+	profileRmCmd.Flags().StringArrayVar(&profileDirs, "dir", nil, "Remove only these directory mappings (repeatable)")
+
+	profileCmd.AddCommand(profileAddCmd, profileUseCmd, profileListCmd, profileRmCmd)
+	rootCmd.AddCommand(profileCmd)
+}
+
+// loadConfigOrExit loads the config, exiting when JTPCK is not set up yet
+func loadConfigOrExit() *config.Config {
+	if !config.Exists() {
+		fmt.Println("JTPCK is not configured. Run 'jtpck' to set it up.")
+		os.Exit(1)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func saveConfigOrExit(cfg *config.Config) {
+	if demoMode {
+		fmt.Println("(DEMO MODE - No files were modified)")
+		return
+	}
+	if err := cfg.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func mapDirsOrExit(cfg *config.Config, name string) {
+	for _, dir := range profileDirs {
+		if err := cfg.MapDirectory(dir, name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func runProfileAdd(cmd *cobra.Command, args []string) {
+	name := strings.TrimSpace(args[0])
+	userID := strings.ToLower(strings.TrimSpace(args[1]))
+	if name == "" || strings.ContainsAny(name, " \t/") {
+		fmt.Printf("Error: Invalid profile name %q\n", name)
+		os.Exit(1)
+	}
+	if !validateUUID(userID) {
+		fmt.Printf("Error: Invalid user ID format. Must be a valid UUID (e.g., 12345678-1234-1234-1234-123456789abc)\n")
+		os.Exit(1)
+	}
+
+	cfg := loadConfigOrExit()
+	existing := cfg.Profiles[name]
+	if name == config.DefaultProfile {
+		existing, _ = cfg.Profile(name)
+	}
+	existing.UserID = userID
+	if profileEndpoint != "" {
+		existing.Endpoint = profileEndpoint
+	}
+	cfg.SetProfile(name, existing)
+	mapDirsOrExit(cfg, name)
+	saveConfigOrExit(cfg)
+
+	fmt.Printf("✓ Saved profile %s\n", name)
+	for _, dir := range profileDirs {
+		fmt.Printf("  %s → %s\n", dir, name)
+	}
+	if name == cfg.ActiveProfileName() {
+		applyActiveProfile(cfg)
+	}
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) {
+	name := args[0]
+	cfg := loadConfigOrExit()
+
+	if len(profileDirs) > 0 {
+		mapDirsOrExit(cfg, name)
+		saveConfigOrExit(cfg)
+		for _, dir := range profileDirs {
+			fmt.Printf("✓ %s → %s\n", dir, name)
+		}
+		return
+	}
+
+	if err := cfg.UseProfile(name); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	saveConfigOrExit(cfg)
+	fmt.Printf("✓ Active profile: %s\n", name)
+	applyActiveProfile(cfg)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) {
+	cfg := resolveConfigOrExit(loadConfigOrExit(), "").Config
+	active := cfg.ActiveProfileName()
+
+	for _, name := range cfg.ProfileNames() {
+		marker := " "
+		if name == active {
+			marker = "*"
+		}
+		p, _ := cfg.Profile(name)
+		fmt.Printf("%s %-12s %s  %s\n", marker, name, maskUserID(p.UserID), p.Endpoint)
+	}
+
+	if len(cfg.Directories) > 0 {
+		dirs := make([]string, 0, len(cfg.Directories))
+		for dir := range cfg.Directories {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+
+		fmt.Println()
+		fmt.Println("Directories:")
+		for _, dir := range dirs {
+			fmt.Printf("  %s → %s\n", dir, cfg.Directories[dir])
+		}
+	}
+}
+
+func runProfileRm(cmd *cobra.Command, args []string) {
+	cfg := loadConfigOrExit()
+
+	if len(profileDirs) > 0 {
+		for _, dir := range profileDirs {
+			if err := cfg.UnmapDirectory(dir); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		saveConfigOrExit(cfg)
+		fmt.Printf("✓ Removed %d directory mapping(s)\n", len(profileDirs))
+		return
+	}
+
+	if len(args) == 0 {
+		fmt.Println("Error: Specify a profile name or --dir")
+		os.Exit(1)
+	}
+
+	wasActive := cfg.ActiveProfileName() == args[0]
+	if err := cfg.RemoveProfile(args[0]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	saveConfigOrExit(cfg)
+	fmt.Printf("✓ Removed profile %s\n", args[0])
+
+	if wasActive {
+		fmt.Printf("  Active profile is now %s\n", cfg.ActiveProfileName())
+		applyActiveProfile(cfg)
+	}
+}
+
+// applyActiveProfile rewrites the tool config files and wrappers, which name
+// the active profile's account for launches outside mapped directories.
+func applyActiveProfile(cfg *config.Config) {
+	if demoMode {
+		return
+	}
+
+	if err := refreshInstall(cfg, config.AffectsWrappers|config.AffectsCodex|config.AffectsGemini, false, nil); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
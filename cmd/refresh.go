@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
+	"github.com/jtpck/installer/validator"
+	"github.com/jtpck/installer/wrapper"
+)
+
+// shownWarnings keeps a command that resolves the config more than once
+// from repeating itself
+var shownWarnings = map[string]bool{}
+
+// resolveConfig layers the system config, JTPCK_* environment variables
+// and --setting flags over cfg, plus the project file found from dir unless
+// dir is empty, and prints what it had to ignore
+func resolveConfig(cfg *config.Config, dir string) (*config.Resolved, error) {
+	resolved, err := cfg.Resolve(config.ResolveOptions{Dir: dir, Flags: settingFlags})
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range resolved.Warnings {
+		if !shownWarnings[warning] {
+			shownWarnings[warning] = true
+			fmt.Fprintf(os.Stderr, "jtpck: warning: %s\n", warning)
+		}
+	}
+	return resolved, nil
+}
+
+func resolveConfigOrExit(cfg *config.Config, dir string) *config.Resolved {
+	resolved, err := resolveConfig(cfg, dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return resolved
+}
+
+// shellConfigs returns the rc files that should hold the alias block
+func shellConfigs(cfg *config.Config) []string {
+	if len(cfg.Shells) == 0 {
+		return []string{shell.DetectShellConfig()}
+	}
+
+	var rcs []string
+	for _, name := range cfg.Shells {
+		if rc, err := shell.ShellConfigFor(name); err == nil {
+			rcs = append(rcs, rc)
+		}
+	}
+	return rcs
+}
+
+// syncAliases writes the alias block to each configured rc file and removes
+// it from any other. fresh re-takes the rc backups, as setup does; otherwise
+// only rc files getting the block for the first time are backed up.
+func syncAliases(cfg *config.Config, tools []string, fresh bool) error {
+	wanted := shellConfigs(cfg)
+	for _, rc := range wanted {
+		var err error
+		if fresh || !shell.AliasesInstalledIn(rc) {
+			err = shell.InstallAliasesIn(rc, tools)
+		} else {
+			err = shell.UpdateAliasesIn(rc, tools)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", rc, err)
+		}
+	}
+
+	for _, rc := range shell.ShellConfigsWithAliases() {
+		if !contains(wanted, rc) {
+			if err := shell.RemoveAliasesFrom(rc); err != nil {
+				return fmt.Errorf("%s: %w", rc, err)
+			}
+		}
+	}
+	return nil
+}
+
+// refreshInstall regenerates the files that depend on the settings named by
+// affects, for the active profile, from the effective config without any
+// project file. Tools that are no longer enabled lose their wrapper and tool
+// config telemetry. report, when set, is told about each step before it
+// runs; dryRun only reports.
+func refreshInstall(user *config.Config, affects config.Affects, dryRun bool, report func(string)) error {
+	if report == nil {
+		report = func(string) {}
+	}
+	resolved, err := resolveConfig(user, "")
+	if err != nil {
+		return err
+	}
+	cfg := resolved.Config
+	active, _ := cfg.Profile(cfg.ActiveProfileName())
+	settings := cfg.TelemetrySettings()
+
+	if affects&config.AffectsCodex != 0 {
+		path := config.CodexConfigPath()
+		if cfg.ToolEnabled("codex") {
+			report("write telemetry to " + path)
+			settings := settings
+			settings.CodexExporters = codexExporters(cfg, active, false)
+			if err := config.EnableCodexTelemetry(active.UserID, active.Endpoint, settings, dryRun, nil); err != nil {
+				return fmt.Errorf("updating Codex config: %w", err)
+			}
+		} else if fileMentions(path, "otel") {
+			report("remove telemetry from " + path)
+			if !dryRun {
+				record, err := config.LoadCodexOtelRecord()
+				if err != nil {
+					return err
+				}
+				if err := config.RemoveCodexTelemetry(path, record); err != nil {
+					return fmt.Errorf("updating Codex config: %w", err)
+				}
+			}
+		}
+	}
+
+	if affects&config.AffectsGemini != 0 {
+		path := config.GeminiSettingsPath()
+		if cfg.ToolEnabled("gemini") {
+			report("write telemetry to " + path)
+			if err := config.EnableGeminiTelemetry(active.UserID, active.Endpoint, settings, dryRun, nil); err != nil {
+				return fmt.Errorf("updating Gemini settings: %w", err)
+			}
+		} else if fileMentions(path, `"telemetry"`) || fileMentions(config.GeminiEnvPath(), "JTPCK") {
+			report("remove telemetry from " + path)
+			if !dryRun {
+				record, err := config.LoadGeminiSettingsRecord()
+				if err != nil {
+					return err
+				}
+				if err := config.RemoveGeminiTelemetry(path, record); err != nil {
+					return fmt.Errorf("updating Gemini settings: %w", err)
+				}
+			}
+		}
+	}
+
+	enabled := cfg.EnabledTools()
+	if affects&config.AffectsWrappers != 0 {
+		envs := cfg.ToolEnvs(active.UserID, active.Endpoint)
+		if err := syncClaudeSettings(cfg, envs["claude"], dryRun, report); err != nil {
+			return err
+		}
+
+		installed := validator.GetInstalledTools(cfg.WrappedTools())
+		if len(installed) > 0 {
+			report("regenerate wrappers for " + strings.Join(installed, ", "))
+			if !dryRun {
+				if err := wrapper.CreateWrappers(envs, installed, wrapperOptions(cfg)); err != nil {
+					return fmt.Errorf("regenerating wrappers: %w", err)
+				}
+			}
+		}
+		if err := removeUnwrapped(cfg, dryRun, report); err != nil {
+			return err
+		}
+	}
+
+	if affects&config.AffectsAliases != 0 {
+		report("update the alias block in ~/" + strings.Join(shellConfigs(cfg), ", ~/"))
+		if !dryRun {
+			if err := syncAliases(cfg, wrapper.GetInstalledTools(enabled), false); err != nil {
+				return fmt.Errorf("updating aliases: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// claudeSettingsModeNote lists what Claude Code loses without a wrapper
+const claudeSettingsModeNote = "  Note: in settings mode Claude Code runs without the jtpck wrapper, so pause, hooks, supervise,\n" +
+	"        failover and per-directory profiles do not apply to it"
+
+// syncClaudeSettings writes the Claude env to Claude Code settings.json in
+// settings mode, and takes back an earlier write otherwise
+func syncClaudeSettings(cfg *config.Config, env map[string]string, dryRun bool, report func(string)) error {
+	if cfg.ToolEnabled("claude") && cfg.ClaudeSettingsMode() {
+		report("write telemetry to " + config.ClaudeSettingsPath())
+		if err := config.EnableClaudeSettings(env, dryRun, nil); err != nil {
+			return fmt.Errorf("updating Claude Code settings: %w", err)
+		}
+		return nil
+	}
+
+	record, err := config.LoadClaudeSettingsRecord()
+	if err != nil || record == nil {
+		return err
+	}
+	report("remove telemetry from " + record.Path)
+	if dryRun {
+		return nil
+	}
+	if err := config.RemoveClaudeSettings(record); err != nil {
+		return fmt.Errorf("updating Claude Code settings: %w", err)
+	}
+	return nil
+}
+
+// removeUnwrapped deletes the wrappers of tools that no longer run through
+// one: those not enabled, and Claude Code in settings mode
+func removeUnwrapped(cfg *config.Config, dryRun bool, report func(string)) error {
+	wrapped := cfg.WrappedTools()
+	for _, tool := range config.AllTools {
+		if contains(wrapped, tool) {
+			continue
+		}
+		if _, err := os.Stat(wrapper.WrapperPath(tool)); err == nil {
+			report("remove the " + tool + " wrapper")
+			if !dryRun {
+				if err := os.Remove(wrapper.WrapperPath(tool)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fileMentions reports whether a file exists and contains s
+func fileMentions(path, s string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), s)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
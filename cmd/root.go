@@ -16,14 +16,19 @@ import (
 )
 
 var (
-	demoMode bool
-	version  = "0.1.0"
+	demoMode     bool
+	supervise    bool
+	settingFlags []string
+	version      = "0.1.0"
 )
 
-const endpoint = "https://JTPCK.com/api/v1/telemetry"
-
 var uuidRegex = regexp.MustCompile(`^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$`)
 
+// runProgram runs an interactive screen; tests replace it to script the keys
+var runProgram = func(model tea.Model, opts ...tea.ProgramOption) (tea.Model, error) {
+	return tea.NewProgram(model, opts...).Run()
+}
+
 func validateUUID(uuid string) bool {
 	return uuidRegex.MatchString(uuid)
 }
@@ -48,11 +53,160 @@ func Execute() error {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&demoMode, "demo", false, "Demo mode (UI preview without file writes)")
+	rootCmd.PersistentFlags().StringArrayVarP(&settingFlags, "setting", "c", nil, "Override a setting for this command (KEY=VALUE, repeatable)")
+	rootCmd.Flags().BoolVar(&supervise, "supervise", false, "Supervise tool sessions and report their start, duration and exit code")
 	rootCmd.Version = version
 }
 
+// updatedConfig applies the user ID to a profile (the active one when empty)
+// in the saved config, keeping the other settings, or starts a new config
+func updatedConfig(cmd *cobra.Command, profileName, userID string) *config.Config {
+	cfg := loadOrNewConfig(userID)
+	if profileName == "" {
+		profileName = cfg.ActiveProfileName()
+	}
+	cfg.SetProfileUserID(profileName, userID)
+
+	if cmd.Flags().Changed("supervise") {
+		cfg.Supervise = &supervise
+	}
+	return cfg
+}
+
+// loadOrNewConfig loads the saved config, or starts one when there is none.
+// A config that cannot be loaded, say for a wrong passphrase, stops the
+// command instead of being replaced.
+func loadOrNewConfig(userID string) *config.Config {
+	if !config.Exists() {
+		return config.New(userID, "")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// wrapperOptions derives wrapper generation options from the effective config
+func wrapperOptions(cfg *config.Config) wrapper.Options {
+	launcher, _ := os.Executable()
+	if config.Targeted() {
+		// This binary may not exist inside the target; prefer the target's own
+		if path, err := config.LookPath("jtpck"); err == nil {
+			launcher = path
+		}
+	}
+	active, _ := cfg.Profile(cfg.ActiveProfileName())
+	return wrapper.Options{
+		Supervise: cfg.Supervised(),
+		Launcher:  launcher,
+		Policy:    cfg.EnvPolicyFor,
+		Token:     active.UserID,
+		Profile:   cfg.ActiveProfileName(),
+	}
+}
+
+// enableToolTelemetry writes the Codex and Gemini config files for the
+// enabled tools and returns the actions to show on the success screen
+func enableToolTelemetry(cfg *config.Config, active config.Profile, demo bool) []string {
+	var actions []string
+	logger := func(msg string) { fmt.Println(msg) }
+
+	// Claude Code gets its env from the wrapper or from settings.json
+	if cfg.ToolEnabled("claude") {
+		fmt.Println("Enabling Claude Code telemetry")
+		if cfg.ClaudeSettingsMode() {
+			fmt.Println(claudeSettingsModeNote)
+			actions = append(actions, fmt.Sprintf("Enabled Claude Code telemetry (settings at %s)", config.ClaudeSettingsPath()))
+		} else {
+			actions = append(actions, "Enabled Claude Code telemetry (wrapper script)")
+		}
+	}
+	env := cfg.ToolEnvs(active.UserID, active.Endpoint)["claude"]
+	if err := syncClaudeSettings(cfg, env, demo, func(string) {}); err != nil {
+		fmt.Printf("Error enabling Claude Code telemetry: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Configure Codex telemetry config file (respects demo mode)
+	if cfg.ToolEnabled("codex") {
+		actions = append(actions, fmt.Sprintf("Enabled Codex telemetry (config at %s)", config.CodexConfigPath()))
+		settings := cfg.TelemetrySettings()
+		settings.CodexExporters = codexExporters(cfg, active, !assumeYes && !demo)
+		if err := config.EnableCodexTelemetry(active.UserID, active.Endpoint, settings, demo, logger); err != nil {
+			fmt.Printf("Error enabling Codex telemetry: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Configure Gemini telemetry settings/file (respects demo mode)
+	if cfg.ToolEnabled("gemini") {
+		actions = append(actions, fmt.Sprintf("Enabled Gemini CLI telemetry (settings at %s)", config.GeminiSettingsPath()))
+		if err := config.EnableGeminiTelemetry(active.UserID, active.Endpoint, cfg.TelemetrySettings(), demo, logger); err != nil {
+			fmt.Printf("Error enabling Gemini telemetry: %v\n", err)
+			os.Exit(1)
+		}
+		cwd, _ := os.Getwd()
+		if overrides, err := config.GeminiOverrides(cwd, active.Endpoint, cfg.TelemetrySettings()); err == nil {
+			for _, o := range overrides {
+				fmt.Fprintf(os.Stderr, "jtpck: warning: Gemini %s; 'jtpck status --fix' removes it\n", o)
+			}
+		}
+	}
+	if !demo {
+		warnPlaintextTokens(cfg)
+	}
+	return actions
+}
+
+// codexExporters decides what happens to Codex exporters that send
+// elsewhere: the codex_exporters setting, else the decision of an earlier
+// run, else the user's answer when prompt is set, else they are kept
+func codexExporters(cfg *config.Config, active config.Profile, prompt bool) string {
+	if cfg.CodexExporters != "" && cfg.CodexExporters != config.CodexExportersAsk {
+		return cfg.CodexExporters
+	}
+	if record, _ := config.LoadCodexOtelRecord(); record != nil && record.Policy != "" {
+		return record.Policy
+	}
+	foreign, err := config.ForeignCodexExporters(active.UserID, active.Endpoint)
+	if err != nil || len(foreign) == 0 {
+		return config.CodexExportersReplace
+	}
+	if !prompt {
+		fmt.Fprintf(os.Stderr, "jtpck: warning: keeping Codex exporters that send elsewhere (%s); set codex_exporters to replace or alongside to change that\n", strings.Join(foreign, ", "))
+		return config.CodexExportersKeep
+	}
+
+	fmt.Println("⚠ Codex already exports telemetry elsewhere:")
+	for _, exporter := range foreign {
+		fmt.Printf("    %s\n", exporter)
+	}
+	fmt.Print("Replace with JTPCK (r), send to both (b) or keep them and skip JTPCK for Codex (k)? [k]: ")
+	var response string
+	fmt.Scanln(&response)
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "r":
+		return config.CodexExportersReplace
+	case "b":
+		return config.CodexExportersAlongside
+	}
+	return config.CodexExportersKeep
+}
+
 func runSetup(cmd *cobra.Command, args []string) {
-	tools := []string{"claude", "codex", "gemini"}
+	if systemMode {
+		runSystemSetup(args)
+		return
+	}
+	if systemVerify {
+		fmt.Println("Error: --verify only applies with --system")
+		os.Exit(1)
+	}
+
+	existing := loadOrNewConfig("")
+	tools := resolveConfigOrExit(existing, "").EnabledTools()
 
 	// Check if user ID provided as argument
 	var userID string
@@ -68,7 +222,7 @@ func runSetup(cmd *cobra.Command, args []string) {
 	// In demo mode, skip validation and config checks
 	if !demoMode {
 		// Check if already configured
-		if config.Exists() && userID == "" {
+		if config.Exists() && userID == "" && !assumeYes {
 			fmt.Println("⚠ Configuration already exists.")
 			fmt.Print("Reconfigure? (y/n): ")
 			var response string
@@ -83,31 +237,39 @@ func runSetup(cmd *cobra.Command, args []string) {
 		missing := validator.GetMissingTools(tools)
 		if len(missing) > 0 {
 			fmt.Printf("⚠ Warning: The following tools are not installed: %v\n", missing)
-			fmt.Println("Wrappers will only be created for installed tools.\n")
+			fmt.Print("Wrappers will only be created for installed tools.\n\n")
 		}
 	}
 
 	// Run animation
-	animModel := ui.NewAnimationModel()
-	p := tea.NewProgram(animModel, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running animation: %v\n", err)
-		os.Exit(1)
+	if !assumeYes {
+		animModel := ui.NewAnimationModel()
+		if _, err := runProgram(animModel, tea.WithAltScreen()); err != nil {
+			fmt.Printf("Error running animation: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Without a prompt, --yes keeps the current user ID
+	if userID == "" && assumeYes {
+		current, _ := existing.Profile(existing.ActiveProfileName())
+		if current.UserID == "" {
+			fmt.Println("Error: --yes needs the user ID as an argument: jtpck --yes <user_id>")
+			os.Exit(1)
+		}
+		userID = current.UserID
 	}
 
 	// Run input screen only if user ID not provided
 	if userID == "" {
 		var currentValue string
-		if !demoMode && config.Exists() {
-			cfg, _ := config.Load()
-			if cfg != nil {
-				currentValue = cfg.UserID
-			}
+		if !demoMode {
+			current, _ := existing.Profile(existing.ActiveProfileName())
+			currentValue = current.UserID
 		}
 
 		inputModel := ui.NewInputModel(currentValue)
-		p = tea.NewProgram(inputModel)
-		finalModel, err := p.Run()
+		finalModel, err := runProgram(inputModel)
 		if err != nil {
 			fmt.Printf("Error running input: %v\n", err)
 			os.Exit(1)
@@ -122,50 +284,38 @@ func runSetup(cmd *cobra.Command, args []string) {
 		userID = inputResult.GetUserID()
 	}
 
-	var actions []string
+	cfg := updatedConfig(cmd, "", userID)
+	effective := resolveConfigOrExit(cfg, "").Config
+	active, _ := effective.Profile(effective.ActiveProfileName())
+	actions := enableToolTelemetry(effective, active, demoMode)
 
 	// Generate per-application env vars
-	appEnvs := config.AppEnvs(userID, endpoint)
-
-	// Add Claude Code action
-	fmt.Println("Enabling Claude Code telemetry")
-	actions = append(actions, "Enabled Claude Code telemetry (wrapper script)")
-
-	// Configure Codex telemetry config file (respects demo mode)
-	actions = append(actions, fmt.Sprintf("Enabled Codex telemetry (config at %s)", config.CodexConfigPath()))
-	if err := config.EnableCodexTelemetry(userID, endpoint, demoMode, func(msg string) { fmt.Println(msg) }); err != nil {
-		fmt.Printf("Error enabling Codex telemetry: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Configure Gemini telemetry settings/file (respects demo mode)
-	actions = append(actions, fmt.Sprintf("Enabled Gemini CLI telemetry (settings at %s)", config.GeminiSettingsPath()))
-	if err := config.EnableGeminiTelemetry(userID, endpoint, demoMode, func(msg string) { fmt.Println(msg) }); err != nil {
-		fmt.Printf("Error enabling Gemini telemetry: %v\n", err)
-		os.Exit(1)
-	}
+	appEnvs := effective.ToolEnvs(active.UserID, active.Endpoint)
 
 	var installedTools []string
 
 	if !demoMode {
 		// Save config
-		cfg := config.New(userID, endpoint)
 		if err := cfg.Save(); err != nil {
 			fmt.Printf("Error saving config: %v\n", err)
 			os.Exit(1)
 		}
 
 		// Create wrappers only for installed tools
-		installed := validator.GetInstalledTools(tools)
-		if err := wrapper.CreateWrappers(appEnvs, installed); err != nil {
+		installed := validator.GetInstalledTools(effective.WrappedTools())
+		if err := wrapper.CreateWrappers(appEnvs, installed, wrapperOptions(effective)); err != nil {
 			fmt.Printf("Error creating wrappers: %v\n", err)
 			os.Exit(1)
 		}
+		if err := removeUnwrapped(effective, false, func(string) {}); err != nil {
+			fmt.Printf("Error removing wrappers: %v\n", err)
+			os.Exit(1)
+		}
 
 		installedTools = wrapper.GetInstalledTools(tools)
 
 		// Auto-install aliases to shell config
-		if err := shell.InstallAliases(installedTools); err != nil {
+		if err := syncAliases(effective, installedTools, true); err != nil {
 			fmt.Printf("Warning: Could not auto-install aliases: %v\n", err)
 			fmt.Println("You'll need to manually add aliases to your shell config.")
 		}
@@ -179,17 +329,27 @@ func runSetup(cmd *cobra.Command, args []string) {
 	aliasCommands := shell.GenerateAliasCommands(installedTools)
 
 	// Run success screen (always show auto-installed UI)
-	successModel := ui.NewSuccessModel(shellConfig, installedTools, aliasCommands, true, actions)
-	p = tea.NewProgram(successModel)
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running success screen: %v\n", err)
-		os.Exit(1)
+	if assumeYes {
+		for _, action := range actions {
+			fmt.Printf("  ✓ %s\n", action)
+		}
+		fmt.Printf("  ✓ Wrappers: %s\n", strings.Join(installedTools, ", "))
+	} else {
+		successModel := ui.NewSuccessModel(shellConfig, installedTools, aliasCommands, true, actions)
+		if _, err := runProgram(successModel); err != nil {
+			fmt.Printf("Error running success screen: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	fmt.Println("\n✓ JTPCK setup complete!")
 	fmt.Println()
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("  🔄 Run now: \033[1;36msource ~/%s\033[0m\n", shellConfig)
-	fmt.Println("  Or restart your terminal")
+	if config.Targeted() {
+		fmt.Printf("  Installed into %s\n", config.HomeDir())
+	} else {
+		fmt.Printf("  🔄 Run now: \033[1;36msource ~/%s\033[0m\n", shellConfig)
+		fmt.Println("  Or restart your terminal")
+	}
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
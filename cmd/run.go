@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/launcher"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runTool      string
+	runSupervise bool
+)
+
+// runCmd is invoked by wrapper scripts, not by users
+var runCmd = &cobra.Command{
+	Use:    "run --tool NAME -- TOOL_PATH [args...]",
+	Short:  "Launch a tool with JTPCK hooks and session reporting",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	Run:    runRun,
+}
+
+func init() {
+	runCmd.Flags().StringVar(&runTool, "tool", "", "Tool name used for hooks and session reports")
+	runCmd.Flags().BoolVar(&runSupervise, "supervise", false, "Report the session once the tool exits")
+	rootCmd.AddCommand(runCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) {
+	opts := launcher.Options{
+		Tool:      runTool,
+		ToolPath:  args[0],
+		Args:      args[1:],
+		Version:   version,
+		Supervise: runSupervise,
+		HooksDir:  config.HooksDir(),
+	}
+
+	// A missing or broken config must never stop the tool from launching
+	user, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: could not load config, launching %s without telemetry: %v\n", runTool, err)
+		os.Exit(launcher.Run(opts))
+	}
+
+	// Project files and JTPCK_* variables apply at launch
+	cwd, _ := os.Getwd()
+	cfg := user
+	if resolved, err := resolveConfig(user, cwd); err == nil {
+		cfg = resolved.Config
+		opts.Supervise = cfg.Supervised()
+	} else {
+		fmt.Fprintf(os.Stderr, "jtpck: ignoring system and project settings: %v\n", err)
+	}
+	profileName, profile := cfg.ProfileFor(cwd)
+	active, _ := cfg.Profile(cfg.ActiveProfileName())
+
+	opts.UserID = profile.UserID
+	opts.HookTimeout = time.Duration(cfg.HookTimeout) * time.Second
+
+	// Codex sessions are labeled, or left without telemetry, by Codex profile.
+	// Exporters setup left pointing elsewhere are kept, or relayed to.
+	var codexArgs []string
+	codexExporters := config.CodexExportersReplace
+	if runTool == "codex" {
+		if record, _ := config.LoadCodexOtelRecord(); record != nil && record.Policy != "" {
+			codexExporters = record.Policy
+		}
+		if codexExporters == config.CodexExportersKeep {
+			os.Exit(launcher.Run(opts))
+		}
+
+		written := config.ReadCodexSettings()
+		codexProfile := config.CodexProfileFromArgs(opts.Args)
+		if codexProfile == "" {
+			codexProfile = written.Profile
+		}
+		if !cfg.CodexProfileEnabled(codexProfile) {
+			if codexExporters == config.CodexExportersAlongside {
+				os.Exit(launcher.Run(opts))
+			}
+			opts.Env = config.AppOfflineEnvs()[runTool]
+			opts.Args = append(config.AppLaunchArgs(runTool, "", ""), opts.Args...)
+			os.Exit(launcher.Run(opts))
+		}
+		if environment := config.CodexEnvironment(cfg.CodexEnvironment, codexProfile); environment != written.Otel.Environment && codexExporters == config.CodexExportersReplace {
+			codexArgs = config.CodexEnvironmentArgs(environment)
+		}
+	}
+
+	endpoint, reachable := launcher.SelectEndpoint(profile.Endpoints(), config.HealthCachePath())
+	if !reachable && cfg.OfflineMode() == config.OfflineSpool {
+		if spool, err := launcher.StartSpool(config.SpoolDir()); err == nil {
+			fmt.Fprintf(os.Stderr, "jtpck: no telemetry endpoint reachable, spooling %s telemetry to %s\n", runTool, config.SpoolDir())
+			opts.Spool = spool
+			endpoint, reachable = spool.URL(), true
+		} else {
+			fmt.Fprintf(os.Stderr, "jtpck: %v\n", err)
+		}
+	}
+
+	if !reachable && codexExporters == config.CodexExportersAlongside {
+		// The user's own collectors still get everything
+		fmt.Fprintf(os.Stderr, "jtpck: no telemetry endpoint reachable, launching %s without JTPCK telemetry\n", runTool)
+		os.Exit(launcher.Run(opts))
+	}
+	if !reachable {
+		fmt.Fprintf(os.Stderr, "jtpck: no telemetry endpoint reachable, launching %s with telemetry disabled\n", runTool)
+		opts.Env = config.AppOfflineEnvs()[runTool]
+		opts.Args = append(config.AppLaunchArgs(runTool, "", ""), opts.Args...)
+		os.Exit(launcher.Run(opts))
+	}
+
+	opts.Endpoint = endpoint
+	if codexExporters == config.CodexExportersAlongside {
+		relay, err := startCodexRelay(profile.UserID, endpoint)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jtpck: %v, launching %s without JTPCK telemetry\n", err, runTool)
+			os.Exit(launcher.Run(opts))
+		}
+		opts.Relay = relay
+		opts.Args = append(config.CodexRelayArgs(relay.URL()), opts.Args...)
+	} else if endpoint != active.Endpoint || profile.UserID != active.UserID {
+		// Tool config files name the active profile's account and endpoint
+		opts.Args = append(config.AppLaunchArgs(runTool, profile.UserID, endpoint), opts.Args...)
+	}
+
+	opts.Args = append(codexArgs, opts.Args...)
+
+	desired := cfg.ToolEnvs(profile.UserID, endpoint)[runTool]
+	env, conflicts := config.MergeEnv(os.LookupEnv, desired, cfg.EnvPolicyFor)
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "jtpck: warning: %s (set env_policy in %s to silence)\n", conflict, config.ConfigPath())
+	}
+	env["JTPCK_PROFILE"] = profileName
+	opts.Env = env
+
+	os.Exit(launcher.Run(opts))
+}
+
+// startCodexRelay relays Codex exports to JTPCK and to the exporters in
+// config.toml
+func startCodexRelay(userID, endpoint string) (*launcher.Relay, error) {
+	logs, traces := config.ReadCodexExporters()
+	routes := map[string][]launcher.RelayTarget{}
+	for path, theirs := range map[string]*config.CodexExporter{"/v1/logs": logs, "/v1/traces": traces} {
+		routes[path] = []launcher.RelayTarget{{
+			URL:     endpoint + path,
+			Headers: map[string]string{"Authorization": "Bearer " + userID},
+		}}
+		if theirs != nil {
+			routes[path] = append(routes[path], launcher.RelayTarget{URL: theirs.Endpoint, Headers: theirs.Headers})
+		}
+	}
+	return launcher.StartRelay(routes)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jtpck/installer/config"
+	"github.com/spf13/cobra"
+)
+
+var scanJSON bool
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [dir]",
+	Short: "Find project settings that disable or redirect telemetry",
+	Long: `Walk a directory (default: the current one) for the project settings of
+Claude Code, Codex and the Gemini CLI and report those that change telemetry:
+.claude/settings.json, .claude/settings.local.json, .codex/config.toml,
+.gemini/settings.json and .env files, and the .jtpck.json settings jtpck run
+applies or ignores.
+
+Exits 1 when a setting disables or redirects telemetry, for CI checks, and
+2 when a file or directory could not be read or parsed; those are reported
+on stderr and the walk goes on. OpenTelemetry variables of a plain .env are
+often the project's own and are only listed as info.
+
+  jtpck scan ~/src
+  jtpck scan --json . | jq '.[] | select(.effect == "disables")'`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runScan,
+}
+
+func init() {
+	scanCmd.Flags().BoolVar(&scanJSON, "json", false, "Print the findings as JSON")
+	rootCmd.AddCommand(scanCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	findings, problems, err := config.ScanProjectTelemetry(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", problem)
+	}
+
+	blocking := 0
+	for _, f := range findings {
+		if f.Blocking() {
+			blocking++
+		}
+	}
+
+	if scanJSON {
+		if findings == nil {
+			findings = []config.ScanFinding{}
+		}
+		data, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printFindings(findings, blocking)
+	}
+	switch {
+	case len(problems) > 0:
+		os.Exit(2)
+	case blocking > 0:
+		os.Exit(1)
+	}
+}
+
+// printFindings lists the findings by file
+func printFindings(findings []config.ScanFinding, blocking int) {
+	if len(findings) == 0 {
+		fmt.Println("✓ No project settings change telemetry")
+		return
+	}
+
+	files := 0
+	for i, f := range findings {
+		if i == 0 || f.Path != findings[i-1].Path {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s (%s)\n", f.Path, f.Tool)
+			files++
+		}
+		fmt.Printf("  %-10s %s = %q\n", f.Effect, f.Key, f.Value)
+	}
+	fmt.Printf("\nTelemetry settings: %s in %s, %d disabling or redirecting it\n", plural(len(findings), "setting"), plural(files, "file"), blocking)
+}
+
+// plural formats a count and a noun that takes an s
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
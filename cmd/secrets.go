@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/jtpck/installer/config"
+	"github.com/spf13/cobra"
+)
+
+var secretsKeyFile string
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Choose how JTPCK stores your user IDs",
+	Long: `User IDs act as bearer tokens. JTPCK can keep them in:
+
+  file            credentials in the config directory, readable only by you (default)
+  age             credentials.age in the config directory, encrypted with a passphrase
+                  or with an age key file kept elsewhere (--key-file)
+  secret-service  the desktop keyring, via secret-tool (Linux)
+
+With a passphrase, set JTPCK_PASSPHRASE or answer the prompt at launch.`,
+}
+
+var secretsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where credentials are stored",
+	Args:  cobra.NoArgs,
+	Run:   runSecretsStatus,
+}
+
+var secretsUseCmd = &cobra.Command{
+	Use:   "use BACKEND",
+	Short: "Move credentials to another backend (file, age, secret-service)",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSecretsUse,
+}
+
+func init() {
+	secretsUseCmd.Flags().StringVar(&secretsKeyFile, "key-file", "", "age identity file (created if missing); omit to use a passphrase")
+	secretsCmd.AddCommand(secretsStatusCmd, secretsUseCmd)
+	rootCmd.AddCommand(secretsCmd)
+
+	config.PassphraseFunc = promptPassphrase
+}
+
+func runSecretsStatus(cmd *cobra.Command, args []string) {
+	cfg := loadConfigOrExit()
+	store, err := cfg.SecretStore()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Backend:  %s\n", store.Name())
+	fmt.Printf("Location: %s\n", store.Location())
+}
+
+func runSecretsUse(cmd *cobra.Command, args []string) {
+	backend, err := config.ParseSecretBackend(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if secretsKeyFile != "" && backend != config.SecretAge {
+		fmt.Println("Error: --key-file only applies to the age backend")
+		os.Exit(1)
+	}
+
+	cfg := loadConfigOrExit()
+	if demoMode {
+		fmt.Println("(DEMO MODE - No files were modified)")
+		return
+	}
+
+	keyFile := secretsKeyFile
+	if keyFile != "" {
+		keyFile, err = filepath.Abs(keyFile)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(keyFile); os.IsNotExist(err) {
+			recipient, err := config.GenerateAgeKeyFile(keyFile)
+			if err != nil {
+				fmt.Printf("Error creating key file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Created age key %s (public key %s)\n", keyFile, recipient)
+		}
+		if strings.HasPrefix(keyFile, config.ConfigDir()+string(filepath.Separator)) {
+			fmt.Printf("⚠ The key file is inside %s; keep it elsewhere so a copy of that directory cannot be decrypted\n", config.ConfigDir())
+		}
+	}
+
+	if err := cfg.MoveSecrets(backend, keyFile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.Save(); err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+
+	store, _ := cfg.SecretStore()
+	fmt.Printf("✓ Credentials stored in %s\n", store.Location())
+
+	// The wrapper fallback can only read the plaintext file
+	applyActiveProfile(cfg)
+	warnPlaintextTokens(cfg)
+}
+
+// warnPlaintextTokens points out the tool files that keep the token in
+// plaintext when the credentials themselves are protected
+func warnPlaintextTokens(cfg *config.Config) {
+	if cfg.SecretBackend == "" || cfg.SecretBackend == config.SecretFile {
+		return
+	}
+	if paths := cfg.PlaintextTokenFiles(); len(paths) > 0 {
+		fmt.Fprintf(os.Stderr, "jtpck: warning: the %s backend does not cover these files, which keep the token in plaintext (0600): %s\n", cfg.SecretBackend, strings.Join(paths, ", "))
+	}
+}
+
+// promptPassphrase asks for the credentials passphrase on the terminal,
+// which works even when stdin/stdout are redirected (e.g. inside a wrapper)
+func promptPassphrase(confirm bool) (string, error) {
+	if p := os.Getenv("JTPCK_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.New("credentials are encrypted; set JTPCK_PASSPHRASE")
+	}
+	defer tty.Close()
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(tty, prompt)
+		b, err := term.ReadPassword(tty.Fd())
+		fmt.Fprintln(tty)
+		return string(b), err
+	}
+
+	if !confirm {
+		return read("JTPCK passphrase: ")
+	}
+
+	p, err := read("New JTPCK passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := read("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if p != again {
+		return "", errors.New("passphrases do not match")
+	}
+	return p, nil
+}
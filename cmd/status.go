@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
+	"github.com/jtpck/installer/wrapper"
+	"github.com/spf13/cobra"
+)
+
+var statusFix bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current JTPCK telemetry setup",
+	Args:  cobra.NoArgs,
+	Run:   runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusFix, "fix", false, "Remove workspace and system Gemini settings that override JTPCK's telemetry here")
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	if !config.Exists() {
+		fmt.Println("JTPCK is not configured. Run 'jtpck' to set it up.")
+		return
+	}
+
+	user, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Show what a tool launched from here would get
+	cwd, _ := os.Getwd()
+	cfg := resolveConfigOrExit(user, cwd).Config
+	tools := cfg.EnabledTools()
+	profileName, profile := cfg.ProfileFor(cwd)
+
+	fmt.Println("JTPCK status")
+	fmt.Printf("  Config:    %s\n", config.ConfigPath())
+	if _, err := os.Stat(config.SystemConfigPath()); err == nil {
+		fmt.Printf("  System:    %s\n", config.SystemConfigPath())
+	}
+	if project := config.FindProjectConfig(cwd); project != "" {
+		fmt.Printf("  Project:   %s\n", project)
+	}
+	fmt.Printf("  Profile:   %s", profileName)
+	if profileName != cfg.ActiveProfileName() {
+		fmt.Printf(" (mapped for this directory; active: %s)", cfg.ActiveProfileName())
+	}
+	fmt.Println()
+	fmt.Printf("  User ID:   %s\n", maskUserID(profile.UserID))
+	fmt.Printf("  Endpoint:  %s\n", profile.Endpoint)
+	if len(profile.Failover) > 0 {
+		fmt.Printf("  Failover:  %s\n", strings.Join(profile.Failover, ", "))
+	}
+	fmt.Printf("  Offline:   %s\n", cfg.OfflineMode())
+
+	state, err := config.LoadPause()
+	switch {
+	case err != nil:
+		fmt.Printf("  Telemetry: unknown (%v)\n", err)
+	case state != nil:
+		fmt.Printf("  Telemetry: %s\n", describePause(state))
+	default:
+		fmt.Println("  Telemetry: active")
+	}
+
+	if cfg.Supervised() {
+		fmt.Println("  Sessions:  supervised (reported as jtpck.session)")
+	}
+
+	installed := wrapper.GetInstalledTools(tools)
+	if len(installed) > 0 {
+		fmt.Printf("  Wrappers:  %s\n", strings.Join(installed, ", "))
+	} else {
+		fmt.Println("  Wrappers:  none")
+	}
+
+	shellConfig := shell.DetectShellConfig()
+	if shell.AliasesInstalled() {
+		fmt.Printf("  Aliases:   installed in ~/%s\n", shellConfig)
+	} else {
+		fmt.Printf("  Aliases:   not found in ~/%s\n", shellConfig)
+	}
+
+	if record, err := config.LoadClaudeManagedRecord(); err == nil && record != nil {
+		reportClaudeManaged(record, config.ClaudeSettingsFiles(cwd), os.LookupEnv)
+	}
+	if cfg.ToolEnabled("claude") && cfg.ClaudeSettingsMode() {
+		reportClaudeSettings()
+	}
+	if cfg.ToolEnabled("gemini") {
+		reportGeminiAuth(user, cwd)
+		reportGeminiOverrides(user, cwd)
+	}
+}
+
+// reportClaudeSettings checks the env variables setup wrote to Claude Code
+// settings.json, which the user or another tool may have changed since
+func reportClaudeSettings() {
+	record, err := config.LoadClaudeSettingsRecord()
+	switch {
+	case err != nil:
+		fmt.Printf("  Claude:    %v\n", err)
+		return
+	case record == nil:
+		fmt.Printf("  Claude:    settings mode, but nothing written to %s\n", tildePath(config.ClaudeSettingsPath()))
+		fmt.Println("             Run 'jtpck --yes' to write it")
+		return
+	}
+
+	drift, err := config.ClaudeSettingsDrift(record)
+	switch {
+	case err != nil:
+		fmt.Printf("  Claude:    could not read %s: %v\n", tildePath(record.Path), err)
+	case len(drift) > 0:
+		fmt.Printf("  Claude:    %s changed since setup: %s\n", tildePath(record.Path), strings.Join(drift, ", "))
+		fmt.Println("             Run 'jtpck --yes' to rewrite it")
+	default:
+		fmt.Printf("  Claude:    settings mode (%d variables in %s)\n", len(record.Env), tildePath(record.Path))
+	}
+	fmt.Println("             No wrapper: pause, hooks, supervise, failover and directory profiles do not apply")
+}
+
+// reportGeminiAuth tells whether Gemini started in dir without the wrapper
+// sends the bearer token, which only the environment or a .env file holds
+func reportGeminiAuth(user *config.Config, dir string) {
+	installed := resolveConfigOrExit(user, "").Config
+	active, _ := installed.Profile(installed.ActiveProfileName())
+	auth, err := config.CheckGeminiAuth(dir, active.UserID)
+	switch {
+	case err != nil:
+		fmt.Printf("  Gemini:    could not read %s: %v\n", tildePath(auth.Source), err)
+	case auth.Authenticated:
+		fmt.Printf("  Gemini:    authenticated without the wrapper (%s)\n", tildePath(auth.Source))
+	case auth.Source == "":
+		fmt.Println("  Gemini:    unauthenticated without the wrapper: no .env file sets OTEL_EXPORTER_OTLP_HEADERS")
+		fmt.Println("             Run 'jtpck --yes' to write ~/.gemini/.env")
+	case auth.Source == config.GeminiEnvPath():
+		fmt.Printf("  Gemini:    unauthenticated without the wrapper: %s has no JTPCK token\n", tildePath(auth.Source))
+		fmt.Println("             Run 'jtpck --yes' to rewrite it")
+	default:
+		fmt.Printf("  Gemini:    unauthenticated without the wrapper: %s is read instead of ~/.gemini/.env\n", tildePath(auth.Source))
+		fmt.Println("             Add OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES from ~/.gemini/.env to it")
+	}
+}
+
+// reportGeminiOverrides lists Gemini settings files in effect in dir that
+// change the telemetry settings JTPCK wrote, and removes them with --fix
+func reportGeminiOverrides(user *config.Config, dir string) {
+	installed := resolveConfigOrExit(user, "").Config
+	active, _ := installed.Profile(installed.ActiveProfileName())
+	overrides, err := config.GeminiOverrides(dir, active.Endpoint, installed.TelemetrySettings())
+	switch {
+	case err != nil:
+		fmt.Printf("  Gemini:    could not read settings: %v\n", err)
+		return
+	case len(overrides) == 0:
+		return
+	}
+
+	if statusFix {
+		if err := config.RemoveGeminiOverrides(overrides); err != nil {
+			fmt.Printf("  Gemini:    could not remove overriding settings: %v\n", err)
+			return
+		}
+		fmt.Printf("  Gemini:    removed %d overriding telemetry settings\n", len(overrides))
+		return
+	}
+	fmt.Println("  Gemini:    telemetry settings overridden here:")
+	for _, o := range overrides {
+		fmt.Printf("               %s\n", o)
+	}
+	fmt.Println("             Run 'jtpck status --fix' to remove them")
+}
+
+// maskUserID hides most of the user ID since it doubles as a bearer token
+func maskUserID(userID string) string {
+	if len(userID) <= 8 {
+		return strings.Repeat("*", len(userID))
+	}
+	return userID[:8] + strings.Repeat("*", len(userID)-8)
+}
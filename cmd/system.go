@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jtpck/installer/config"
+)
+
+var (
+	systemMode   bool
+	systemVerify bool
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&systemMode, "system", false, "Enforce Claude Code telemetry for every user through its managed settings (run as root)")
+	rootCmd.Flags().BoolVar(&systemVerify, "verify", false, "With --system, only check the managed settings and report user overrides")
+}
+
+// runSystemSetup writes, repairs or verifies the Claude Code managed
+// settings. The user ID is the organization's; the endpoint and other
+// settings come from the system config.
+func runSystemSetup(args []string) {
+	exitUnlessRoot("jtpck --system")
+	record, err := config.LoadClaudeManagedRecord()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if systemVerify {
+		if record == nil {
+			fmt.Println("Error: jtpck --system has not been run here")
+			os.Exit(1)
+		}
+		problems := reportClaudeManaged(record, config.ClaudeUserSettingsFiles(), nil)
+		if problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var env map[string]string
+	switch {
+	case len(args) > 0:
+		userID := strings.ToLower(strings.TrimSpace(args[0]))
+		if !validateUUID(userID) {
+			fmt.Printf("Error: Invalid user ID format. Must be a valid UUID (e.g., 12345678-1234-1234-1234-123456789abc)\n")
+			os.Exit(1)
+		}
+		cfg := resolveConfigOrExit(config.New(userID, ""), "").Config
+		active, _ := cfg.Profile(cfg.ActiveProfileName())
+		env = cfg.ToolEnvs(active.UserID, active.Endpoint)["claude"]
+	case record != nil:
+		// Repair what was written before
+		env = record.Env
+	default:
+		fmt.Println("Error: --system needs the organization's user ID: jtpck --system <user_id>")
+		os.Exit(1)
+	}
+
+	if record != nil {
+		problems, err := config.VerifyClaudeManagedSettings(record)
+		if err == nil && len(problems) > 0 {
+			fmt.Println("Repairing Claude Code managed settings:")
+			for _, problem := range problems {
+				fmt.Printf("  %s\n", problem)
+			}
+		}
+	}
+	if demoMode {
+		fmt.Printf("Would write Claude Code telemetry to %s (DEMO MODE)\n", config.ClaudeManagedSettingsPath())
+		return
+	}
+	if err := config.EnableClaudeManagedSettings(env); err != nil {
+		fmt.Printf("Error writing Claude Code managed settings: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Claude Code telemetry enforced for every user in %s\n", config.ClaudeManagedSettingsPath())
+	fmt.Printf("  Endpoint: %s\n", env["OTEL_EXPORTER_OTLP_ENDPOINT"])
+
+	record, _ = config.LoadClaudeManagedRecord()
+	reportClaudeManaged(record, config.ClaudeUserSettingsFiles(), nil)
+}
+
+// runSystemUninstall puts the managed settings back as they were before
+// `jtpck --system` and deletes its record
+func runSystemUninstall() {
+	exitUnlessRoot("jtpck uninstall --system")
+	record, err := config.LoadClaudeManagedRecord()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if record == nil {
+		fmt.Println("jtpck --system has not been run here (skip)")
+		return
+	}
+	fmt.Printf("  Removing JTPCK variables from %s\n", record.Path)
+	if demoMode {
+		fmt.Println("(DEMO MODE - No files were modified)")
+		return
+	}
+	if err := config.RemoveClaudeManagedSettings(record); err != nil {
+		fmt.Printf("Error updating Claude Code managed settings: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Claude Code telemetry is no longer enforced for every user")
+}
+
+// exitUnlessRoot stops a command that writes system files when it is not
+// run as root, unless it provisions another root or only shows what it
+// would do
+func exitUnlessRoot(command string) {
+	if os.Geteuid() != 0 && targetRoot == "" && !demoMode {
+		fmt.Printf("Error: %s writes %s; run it as root (sudo %s)\n", command, config.ClaudeManagedSettingsPath(), command)
+		os.Exit(1)
+	}
+}
+
+// reportClaudeManaged prints what is wrong with the managed settings and
+// the user settings that try to change them, and returns how many problems
+// it found
+func reportClaudeManaged(record *config.ClaudeSettingsRecord, userFiles []string, environ func(string) (string, bool)) int {
+	problems, err := config.VerifyClaudeManagedSettings(record)
+	if err != nil {
+		fmt.Printf("  Managed:   could not read %s: %v\n", record.Path, err)
+		return 1
+	}
+	overrides, err := config.ClaudeManagedOverrides(record.Env, userFiles, environ)
+	if err != nil {
+		fmt.Printf("  Managed:   could not read user settings: %v\n", err)
+	}
+
+	switch {
+	case len(problems) > 0:
+		fmt.Printf("  Managed:   %s needs repair (sudo jtpck --system):\n", record.Path)
+		for _, problem := range problems {
+			fmt.Printf("               %s\n", problem)
+		}
+	default:
+		fmt.Printf("  Managed:   Claude Code telemetry enforced by %s\n", record.Path)
+	}
+	if len(overrides) > 0 {
+		fmt.Println("             User settings that try to override it (the managed values apply):")
+		for _, o := range overrides {
+			fmt.Printf("               %s\n", o)
+		}
+	}
+	return len(problems)
+}
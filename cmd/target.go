@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
+	"github.com/spf13/cobra"
+)
+
+var (
+	targetRoot string
+	targetHome string
+	targetPath string
+	assumeYes  bool
+)
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&targetRoot, "root", "", "Install into a filesystem mounted here (image or chroot); --home is inside it")
+	flags.StringVar(&targetHome, "home", "", "Install into this home directory instead of your own")
+	flags.StringVar(&targetPath, "path", "", "PATH to find claude, codex and gemini on (inside --root)")
+	flags.BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to prompts and skip the interactive screens")
+
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		if !demoMode {
+			chownTarget()
+		}
+	}
+}
+
+// applyTargetOrExit points every path at --root/--home before a command runs
+func applyTargetOrExit() {
+	if err := config.SetTarget(targetRoot, targetHome, targetPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// chownTarget hands what a command wrote in another user's home to them
+func chownTarget() {
+	if !config.Targeted() {
+		return
+	}
+	home := config.HomeDir()
+	paths := []string{
+		config.LegacyDir(), config.ConfigDir(), config.StateDir(), config.DataDir(),
+		filepath.Dir(config.CodexConfigPath()), filepath.Dir(config.GeminiSettingsPath()),
+	}
+	for _, rc := range shell.ShellConfigsWithAliases() {
+		paths = append(paths, filepath.Join(home, rc))
+	}
+	if err := config.ChownToHome(paths...); err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: warning: could not hand %s over to its owner: %v\n", home, err)
+	}
+}
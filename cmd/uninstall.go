@@ -6,100 +6,147 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/shell"
 	"github.com/spf13/cobra"
 )
 
 var uninstallCmd = &cobra.Command{
 	Use:   "uninstall",
 	Short: "Remove JTPCK telemetry configuration",
-	Long:  `Removes all JTPCK configuration files, wrappers, and shell aliases.`,
-	Run:   runUninstall,
+	Long: `Removes all JTPCK configuration files, wrappers, and shell aliases.
+
+With --system (as root), only takes back what 'jtpck --system' wrote to the
+Claude Code managed settings.`,
+	Run: runUninstall,
 }
 
+var uninstallSystem bool
+
 func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallSystem, "system", false, "Remove the Claude Code managed settings written by 'jtpck --system' (run as root)")
 	rootCmd.AddCommand(uninstallCmd)
 }
 
 func runUninstall(cmd *cobra.Command, args []string) {
+	if uninstallSystem {
+		runSystemUninstall()
+		return
+	}
 	if demoMode {
 		fmt.Println("🧹 Cleaning up JTPCK installer artifacts... (DEMO MODE)")
 	} else {
 		fmt.Println("🧹 Cleaning up JTPCK installer artifacts...")
 	}
 
-	home, err := os.UserHomeDir()
+	home := config.HomeDir()
+
+	// 1. Remove JTPCK directories (~/.jtpck and the XDG config, state and data dirs)
+	// The rc file backups may be in the state dir
+	rcFiles := shell.ShellConfigsWithAliases()
+	backups := map[string][]byte{}
+	for _, rc := range rcFiles {
+		if backup, err := os.ReadFile(shell.BackupPathFor(rc)); err == nil {
+			backups[rc] = backup
+		}
+	}
+	// What setup changed in Codex config.toml is recorded in the state dir
+	codexRecord, err := config.LoadCodexOtelRecord()
 	if err != nil {
-		fmt.Printf("Error getting home directory: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("  ⚠️  %v; removing the whole Codex otel table\n", err)
 	}
-
-	// 1. Remove .jtpck directory
-	jtpckDir := filepath.Join(home, ".jtpck")
-	if _, err := os.Stat(jtpckDir); err == nil {
-		fmt.Println("  Removing ~/.jtpck/")
+	claudeRecord, err := config.LoadClaudeSettingsRecord()
+	if err != nil {
+		fmt.Printf("  ⚠️  %v; leaving Claude Code settings alone\n", err)
+	}
+	geminiRecord, err := config.LoadGeminiSettingsRecord()
+	if err != nil {
+		fmt.Printf("  ⚠️  %v; removing every Gemini telemetry setting\n", err)
+	}
+	removed := false
+	for _, dir := range jtpckDirs() {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		removed = true
+		fmt.Printf("  Removing %s/\n", tildePath(dir))
 		if !demoMode {
-			if err := os.RemoveAll(jtpckDir); err != nil {
-				fmt.Printf("  ⚠️  Failed to remove ~/.jtpck/: %v\n", err)
+			if err := os.RemoveAll(dir); err != nil {
+				fmt.Printf("  ⚠️  Failed to remove %s/: %v\n", tildePath(dir), err)
 			}
 		}
-	} else {
+	}
+	if !removed {
 		fmt.Println("  ~/.jtpck/ not found (skip)")
 	}
 
-	// 2. Restore .zshrc from backup or remove JTPCK section
-	zshrcPath := filepath.Join(home, ".zshrc")
-	backupPath := filepath.Join(home, ".zshrc.jtpck-backup")
-
-	if _, err := os.Stat(backupPath); err == nil {
-		fmt.Println("  Restoring ~/.zshrc from backup")
-		if !demoMode {
-			input, err := os.ReadFile(backupPath)
-			if err != nil {
-				fmt.Printf("  ⚠️  Failed to read backup: %v\n", err)
-			} else {
-				if err := os.WriteFile(zshrcPath, input, 0644); err != nil {
-					fmt.Printf("  ⚠️  Failed to restore .zshrc: %v\n", err)
-				} else {
-					os.Remove(backupPath)
-				}
-			}
+	// 2. Restore every shell config with aliases from its backup, or remove
+	// the JTPCK section
+	for _, rc := range rcFiles {
+		backup, ok := backups[rc]
+		if ok {
+			fmt.Printf("  Restoring ~/%s from backup\n", rc)
+		} else {
+			fmt.Printf("  Removing JTPCK aliases from ~/%s\n", rc)
 		}
-	} else if _, err := os.Stat(zshrcPath); err == nil {
-		fmt.Println("  Removing JTPCK aliases from ~/.zshrc")
-		if !demoMode {
-			if err := removeJTPCKSection(zshrcPath); err != nil {
-				fmt.Printf("  ⚠️  Failed to update .zshrc: %v\n", err)
+		if demoMode {
+			continue
+		}
+		if ok {
+			if err := os.WriteFile(filepath.Join(home, rc), backup, 0644); err != nil {
+				fmt.Printf("  ⚠️  Failed to restore %s: %v\n", rc, err)
+			} else {
+				os.Remove(shell.BackupPathFor(rc))
 			}
+		} else if err := shell.RemoveAliasesFrom(rc); err != nil {
+			fmt.Printf("  ⚠️  Failed to update %s: %v\n", rc, err)
 		}
-	} else {
-		fmt.Println("  ~/.zshrc not found (skip)")
+	}
+	if len(rcFiles) == 0 {
+		fmt.Println("  No JTPCK aliases in shell configs (skip)")
 	}
 
-	// 3. Remove [otel] section from ~/.codex/config.toml
-	codexConfigPath := filepath.Join(home, ".codex", "config.toml")
+	// 3. Undo the [otel] edit of Codex config.toml ($CODEX_HOME or ~/.codex)
+	codexConfigPath := config.CodexConfigPath()
 	if _, err := os.Stat(codexConfigPath); err == nil {
-		fmt.Println("  Removing [otel] from ~/.codex/config.toml")
+		fmt.Printf("  Removing [otel] from %s\n", tildePath(codexConfigPath))
 		if !demoMode {
-			if err := removeCodexOtelSection(codexConfigPath); err != nil {
+			if err := config.RemoveCodexTelemetry(codexConfigPath, codexRecord); err != nil {
 				fmt.Printf("  ⚠️  Failed to update Codex config: %v\n", err)
 			}
 		}
 	} else {
-		fmt.Println("  ~/.codex/config.toml not found (skip)")
+		fmt.Printf("  %s not found (skip)\n", tildePath(codexConfigPath))
 	}
 
-	// 4. Remove telemetry section from ~/.gemini/settings.json
-	geminiSettingsPath := filepath.Join(home, ".gemini", "settings.json")
-	if _, err := os.Stat(geminiSettingsPath); err == nil {
-		fmt.Println("  Removing telemetry from ~/.gemini/settings.json")
+	// 4. Put back the env variables setup wrote to Claude Code settings.json
+	if claudeRecord != nil {
+		fmt.Printf("  Removing JTPCK variables from %s\n", tildePath(claudeRecord.Path))
 		if !demoMode {
-			if err := removeGeminiTelemetry(geminiSettingsPath); err != nil {
-				fmt.Printf("  ⚠️  Failed to update Gemini settings: %v\n", err)
+			if err := config.RemoveClaudeSettings(claudeRecord); err != nil {
+				fmt.Printf("  ⚠️  Failed to update Claude Code settings: %v\n", err)
 			}
 		}
+	}
+
+	// 5. Remove telemetry section from ~/.gemini/settings.json and the
+	// variables JTPCK added to ~/.gemini/.env
+	geminiSettingsPath := filepath.Join(home, ".gemini", "settings.json")
+	_, statErr := os.Stat(geminiSettingsPath)
+	geminiEnv := fileMentions(config.GeminiEnvPath(), "JTPCK")
+	if statErr == nil {
+		fmt.Println("  Removing telemetry from ~/.gemini/settings.json")
 	} else {
 		fmt.Println("  ~/.gemini/settings.json not found (skip)")
 	}
+	if geminiEnv {
+		fmt.Println("  Removing JTPCK variables from ~/.gemini/.env")
+	}
+	if (statErr == nil || geminiEnv) && !demoMode {
+		if err := config.RemoveGeminiTelemetry(geminiSettingsPath, geminiRecord); err != nil {
+			fmt.Printf("  ⚠️  Failed to update Gemini settings: %v\n", err)
+		}
+	}
 
 	fmt.Println("✓ Cleanup complete!")
 	fmt.Println("")
@@ -110,106 +157,22 @@ func runUninstall(cmd *cobra.Command, args []string) {
 	}
 }
 
-// removeJTPCKSection removes lines between JTPCK markers from a file
-func removeJTPCKSection(filePath string) error {
-	input, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(input), "\n")
-	var newLines []string
-	skipUntilEnd := false
-
-	for _, line := range lines {
-		if strings.Contains(line, "# JTPCK Telemetry Aliases - START") {
-			skipUntilEnd = true
-			continue
-		}
-		if strings.Contains(line, "# JTPCK Telemetry Aliases - END") {
-			skipUntilEnd = false
-			continue
-		}
-		if !skipUntilEnd {
-			newLines = append(newLines, line)
-		}
-	}
-
-	return os.WriteFile(filePath, []byte(strings.Join(newLines, "\n")), 0644)
-}
-
-// removeCodexOtelSection removes [otel] section from Codex config.toml
-func removeCodexOtelSection(filePath string) error {
-	input, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(input), "\n")
-	var newLines []string
-	inOtelSection := false
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Check if entering [otel] section
-		if trimmed == "[otel]" {
-			inOtelSection = true
-			continue
-		}
-
-		// Check if entering a different section
-		if strings.HasPrefix(trimmed, "[") && trimmed != "[otel]" {
-			inOtelSection = false
+// jtpckDirs returns every directory JTPCK may have written to, in either layout
+func jtpckDirs() []string {
+	dirs := []string{config.LegacyDir()}
+	for _, dir := range []string{config.ConfigDir(), config.StateDir(), config.DataDir()} {
+		if dir != dirs[len(dirs)-1] {
+			dirs = append(dirs, dir)
 		}
-
-		// Skip lines in [otel] section
-		if inOtelSection {
-			continue
-		}
-
-		newLines = append(newLines, line)
 	}
-
-	return os.WriteFile(filePath, []byte(strings.Join(newLines, "\n")), 0644)
+	return dirs
 }
 
-// removeGeminiTelemetry removes telemetry section from Gemini settings.json
-func removeGeminiTelemetry(filePath string) error {
-	input, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	// Simple approach: read line by line and skip telemetry section
-	lines := strings.Split(string(input), "\n")
-	var newLines []string
-	inTelemetry := false
-	braceCount := 0
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-
-		// Check if starting telemetry section
-		if strings.Contains(trimmed, `"telemetry"`) {
-			inTelemetry = true
-			braceCount = 0
-			continue
-		}
-
-		if inTelemetry {
-			// Count braces to find end of telemetry object
-			braceCount += strings.Count(line, "{")
-			braceCount -= strings.Count(line, "}")
-
-			if braceCount <= 0 {
-				inTelemetry = false
-			}
-			continue
-		}
-
-		newLines = append(newLines, line)
+// tildePath shortens a path under $HOME for display
+func tildePath(path string) string {
+	home := config.HomeDir()
+	if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("~", rel)
 	}
-
-	return os.WriteFile(filePath, []byte(strings.Join(newLines, "\n")), 0644)
+	return path
 }
@@ -1,5 +1,63 @@
 package config
 
+import "strconv"
+
+// AllTools lists the tools JTPCK can configure
+var AllTools = []string{"claude", "codex", "gemini"}
+
+// TelemetrySettings are the user-tunable parts of a tool's own telemetry
+// config file
+type TelemetrySettings struct {
+	// LogPrompts includes prompt text in exported events
+	LogPrompts bool
+	// CodexEnvironment labels Codex telemetry; CodexProfilePlaceholder
+	// stands for the profile (empty = DefaultCodexEnvironment)
+	CodexEnvironment string
+	// CodexExporters decides about Codex exporters that send elsewhere:
+	// replace, keep or alongside (anything else keeps them)
+	CodexExporters string
+}
+
+// TelemetrySettings returns the settings written to tool config files
+func (c *Config) TelemetrySettings() TelemetrySettings {
+	if c == nil {
+		return TelemetrySettings{}
+	}
+	return TelemetrySettings{LogPrompts: isTrue(c.LogPrompts), CodexEnvironment: c.CodexEnvironment, CodexExporters: c.CodexExporters}
+}
+
+// CodexProfileEnabled reports whether Codex sessions with a profile get
+// telemetry; CodexDefaultProfile names sessions without one
+func (c *Config) CodexProfileEnabled(profile string) bool {
+	if c == nil || len(c.CodexProfiles) == 0 {
+		return true
+	}
+	for _, name := range c.CodexProfiles {
+		if name == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledTools returns the tools JTPCK configures
+func (c *Config) EnabledTools() []string {
+	if c == nil || len(c.Tools) == 0 {
+		return AllTools
+	}
+	return c.Tools
+}
+
+// ToolEnabled reports whether JTPCK configures a tool
+func (c *Config) ToolEnabled(tool string) bool {
+	for _, t := range c.EnabledTools() {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
 // AppEnvs returns per-application environment variables keyed by tool name.
 func AppEnvs(userID, endpoint string) map[string]map[string]string {
 	return map[string]map[string]string{
@@ -8,3 +66,51 @@ func AppEnvs(userID, endpoint string) map[string]map[string]string {
 		"gemini": GeminiEnv(userID, endpoint),
 	}
 }
+
+// ToolEnvs returns AppEnvs adjusted for the privacy and sampling settings.
+// Codex ignores the OTEL sampler variables, so sampling only reaches
+// Claude Code and the Gemini CLI.
+func (c *Config) ToolEnvs(userID, endpoint string) map[string]map[string]string {
+	envs := AppEnvs(userID, endpoint)
+	if c == nil {
+		return envs
+	}
+
+	logPrompts := isTrue(c.LogPrompts)
+	if logPrompts {
+		envs["claude"]["OTEL_LOG_USER_PROMPTS"] = "1"
+	}
+	if isTrue(c.LogToolDetails) {
+		envs["claude"]["OTEL_LOG_TOOL_DETAILS"] = "1"
+	}
+	envs["codex"]["CODEX_OTEL_LOG_USER_PROMPT"] = strconv.FormatBool(logPrompts)
+	envs["gemini"]["GEMINI_TELEMETRY_LOG_PROMPTS"] = strconv.FormatBool(logPrompts)
+
+	if c.SampleRate != nil {
+		arg := strconv.FormatFloat(*c.SampleRate, 'f', -1, 64)
+		envs["claude"]["OTEL_TRACES_SAMPLER_ARG"] = arg
+		envs["gemini"]["OTEL_TRACES_SAMPLER"] = "parentbased_traceidratio"
+		envs["gemini"]["OTEL_TRACES_SAMPLER_ARG"] = arg
+	}
+	return envs
+}
+
+// AppOfflineEnvs returns per-application environment variables that switch
+// telemetry off, used when no endpoint is reachable.
+func AppOfflineEnvs() map[string]map[string]string {
+	return map[string]map[string]string{
+		"claude": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0"},
+		"codex":  {"CODEX_ENABLE_TELEMETRY": "0"},
+		"gemini": {"GEMINI_TELEMETRY_ENABLED": "false"},
+	}
+}
+
+// AppLaunchArgs returns extra arguments that point a tool at a different
+// account or endpoint than its config file names, or switch its exporters
+// off when endpoint is empty. Only Codex needs them; the others use env.
+func AppLaunchArgs(tool, userID, endpoint string) []string {
+	if tool == "codex" {
+		return CodexLaunchArgs(userID, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// BundleFormat is the bundle layout written by this build
+const BundleFormat = 1
+
+// Bundle carries the settings, profiles and tool selection of an install so
+// it can be recreated elsewhere, e.g. by a new hire
+type Bundle struct {
+	Format     int       `json:"format"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Config is a config.json document without machine-specific keys
+	Config json.RawMessage `json:"config"`
+	// Credentials are only included on request
+	Credentials Credentials      `json:"credentials,omitempty"`
+	Signature   *BundleSignature `json:"signature,omitempty"`
+}
+
+// BundleSignature is an ed25519 signature over the rest of the bundle
+type BundleSignature struct {
+	PublicKey string `json:"public_key"`
+	Value     string `json:"value"`
+}
+
+// machineKeys are config.json keys that only make sense on one machine or
+// belong to the secret store, so bundles leave them out
+var machineKeys = []string{"user_id", "directories", "secret_backend", "secret_key_file", "created_at", "updated_at"}
+
+// Export returns a bundle of the user config. User IDs are only included
+// with includeSecrets.
+func (c *Config) Export(includeSecrets bool) (*Bundle, error) {
+	if c.resolved {
+		return nil, fmt.Errorf("cannot export a resolved config")
+	}
+
+	data, err := json.Marshal(c.withoutCredentials())
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, key := range machineKeys {
+		delete(doc, key)
+	}
+	doc["version"] = CurrentVersion
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Format: BundleFormat, ExportedAt: time.Now().UTC().Truncate(time.Second), Config: data}
+	if includeSecrets {
+		b.Credentials = Credentials{}
+		for name, token := range c.credentials() {
+			if token != "" {
+				b.Credentials[name] = token
+			}
+		}
+	}
+	return b, nil
+}
+
+// ParseBundle decodes a bundle without verifying its signature
+func ParseBundle(data []byte) (*Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parsing bundle: %w", err)
+	}
+	if b.Format != BundleFormat {
+		return nil, fmt.Errorf("unsupported bundle format %d (this jtpck reads %d)", b.Format, BundleFormat)
+	}
+	if len(b.Config) == 0 {
+		return nil, fmt.Errorf("bundle has no config")
+	}
+	return &b, nil
+}
+
+// signedBytes is the canonical form the signature covers
+func (b *Bundle) signedBytes() ([]byte, error) {
+	unsigned := *b
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+// Sign signs the bundle with an ed25519 key
+func (b *Bundle) Sign(key ed25519.PrivateKey) error {
+	data, err := b.signedBytes()
+	if err != nil {
+		return err
+	}
+	b.Signature = &BundleSignature{
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+	return nil
+}
+
+// Verify checks the signature. With a trusted key the bundle must be
+// signed by it; without one any valid signature passes, which only shows
+// the bundle was not altered after signing.
+func (b *Bundle) Verify(trusted ed25519.PublicKey) error {
+	if b.Signature == nil {
+		return fmt.Errorf("bundle is not signed")
+	}
+	key, err := decodePublicKey(b.Signature.PublicKey)
+	if err != nil {
+		return fmt.Errorf("bundle signature: %w", err)
+	}
+	if trusted != nil && !key.Equal(trusted) {
+		return fmt.Errorf("bundle is signed by %s, not the trusted key %s", KeyFingerprint(key), KeyFingerprint(trusted))
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(b.Signature.Value)
+	if err != nil {
+		return fmt.Errorf("bundle signature: %w", err)
+	}
+	data, err := b.signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("bundle signature does not match its contents")
+	}
+	return nil
+}
+
+// Signer returns the fingerprint of the key that signed the bundle
+func (b *Bundle) Signer() string {
+	if b.Signature == nil {
+		return ""
+	}
+	key, err := decodePublicKey(b.Signature.PublicKey)
+	if err != nil {
+		return ""
+	}
+	return KeyFingerprint(key)
+}
+
+// ImportBundle returns the config with the bundle applied, validated. The
+// bundle's settings replace the current ones and its profiles are added or
+// updated; directory mappings, the secret backend and user IDs the bundle
+// does not carry are kept.
+func (c *Config) ImportBundle(b *Bundle) (*Config, error) {
+	var incoming map[string]any
+	if err := json.Unmarshal(b.Config, &incoming); err != nil {
+		return nil, fmt.Errorf("parsing bundle config: %w", err)
+	}
+	if _, _, err := migrateDocument(incoming); err != nil {
+		return nil, fmt.Errorf("bundle config: %w", err)
+	}
+
+	data, err := json.Marshal(c.withoutCredentials())
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	// Settings the bundle leaves out go back to their defaults
+	for key := range doc {
+		if key != "profiles" && key != "active_profile" && !contains(machineKeys, key) {
+			delete(doc, key)
+		}
+	}
+	for key, value := range incoming {
+		if key != "profiles" && !contains(machineKeys, key) {
+			doc[key] = value
+		}
+	}
+	if profiles, ok := incoming["profiles"].(map[string]any); ok {
+		merged, _ := doc["profiles"].(map[string]any)
+		if merged == nil {
+			merged = map[string]any{}
+		}
+		for name, p := range profiles {
+			merged[name] = p
+		}
+		doc["profiles"] = merged
+	}
+
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	updated, err := c.ApplyJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	updated.Created = c.Created
+	updated.applyCredentials(b.Credentials)
+	return updated, nil
+}
+
+// GenerateSigningKey writes a new ed25519 private key to path and its
+// public key to path.pub
+func GenerateSigningKey(path string) (ed25519.PublicKey, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := WritePrivateFile(path, []byte(base64.StdEncoding.EncodeToString(key.Seed())+"\n")); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".pub", []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// ReadSigningKey reads a private key written by GenerateSigningKey
+func ReadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s is not a jtpck signing key", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ReadPublicKey parses a public key given inline or as a .pub file
+func ReadPublicKey(keyOrPath string) (ed25519.PublicKey, error) {
+	if key, err := decodePublicKey(keyOrPath); err == nil {
+		return key, nil
+	}
+	data, err := os.ReadFile(keyOrPath)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a public key nor a readable file", keyOrPath)
+	}
+	key, err := decodePublicKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyOrPath, err)
+	}
+	return key, nil
+}
+
+func decodePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyFingerprint returns a short, printable identifier of a public key
+func KeyFingerprint(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
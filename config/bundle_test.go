@@ -0,0 +1,100 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	lead := New("lead-id", "")
+	lead.Set("log_prompts", "true")
+	lead.Set("tools", "claude,codex")
+	lead.SetProfile("acme", Profile{UserID: "acme-id", Endpoint: "https://acme.example.com"})
+	lead.UseProfile("acme")
+	lead.MapDirectory(t.TempDir(), "acme")
+
+	bundle, err := lead.Export(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(bundle); strings.Contains(string(data), "lead-id") || strings.Contains(string(data), "acme-id") ||
+		strings.Contains(string(data), "directories") {
+		t.Fatalf("bundle leaks user IDs or directories: %s", data)
+	}
+
+	hire := New("", "")
+	hire.Set("sample_rate", "0.5")
+	hire.SetProfile("home", Profile{UserID: "home-id"})
+	imported, err := hire.ImportBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := imported.Get("tools"); got != "claude,codex" {
+		t.Errorf("tools = %q", got)
+	}
+	if got, _ := imported.Get("sample_rate"); got != "1" {
+		t.Errorf("sample_rate = %q; settings missing from the bundle should reset", got)
+	}
+	if imported.ActiveProfileName() != "acme" || imported.Profiles["home"].UserID != "home-id" {
+		t.Errorf("profiles = %v, active = %s", imported.Profiles, imported.ActiveProfileName())
+	}
+	if p, _ := imported.Profile("acme"); p.UserID != "" || p.Endpoint != "https://acme.example.com" {
+		t.Errorf("acme = %+v", p)
+	}
+
+	withSecrets, _ := lead.Export(true)
+	imported, err = hire.ImportBundle(withSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := imported.Profile("acme"); p.UserID != "acme-id" || imported.UserID != "lead-id" {
+		t.Errorf("user IDs not imported: %q %q", p.UserID, imported.UserID)
+	}
+}
+
+func TestBundleSignature(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "team.key")
+	pub, err := GenerateSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ReadSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trusted, err := ReadPublicKey(keyPath + ".pub"); err != nil || !trusted.Equal(pub) {
+		t.Fatalf("ReadPublicKey = %v, %v", trusted, err)
+	}
+
+	bundle, _ := New("abc", "").Export(false)
+	if err := bundle.Verify(nil); err == nil {
+		t.Error("an unsigned bundle verified")
+	}
+	if err := bundle.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the bundle as a recipient reads it
+	data, _ := json.MarshalIndent(bundle, "", "  ")
+	parsed, err := ParseBundle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.Verify(pub); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := parsed.Verify(other); err == nil {
+		t.Error("a bundle verified against the wrong key")
+	}
+
+	tampered, _ := ParseBundle([]byte(strings.Replace(string(data), `"version": 3`, `"version": 3, "log_prompts": true`, 1)))
+	if err := tampered.Verify(nil); err == nil {
+		t.Error("a modified bundle verified")
+	}
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"syscall"
+)
+
+// claudeManagedSettingsPath is the system-wide Claude Code settings file,
+// which takes precedence over every user and project setting
+var claudeManagedSettingsPath = func() string {
+	if runtime.GOOS == "darwin" {
+		return "/Library/Application Support/ClaudeCode/managed-settings.json"
+	}
+	return "/etc/claude-code/managed-settings.json"
+}()
+
+// claudeManagedRecordPath records what `jtpck --system` wrote there
+var claudeManagedRecordPath = "/etc/jtpck/claude-managed.json"
+
+// claudeManagedOwner is the uid the managed settings and their directory
+// must belong to
+var claudeManagedOwner = 0
+
+// ClaudeManagedSettingsPath returns the Claude Code managed settings file
+func ClaudeManagedSettingsPath() string {
+	return OnHost(claudeManagedSettingsPath)
+}
+
+// ClaudeManagedRecordPath returns the record of the managed settings edit
+func ClaudeManagedRecordPath() string {
+	return OnHost(claudeManagedRecordPath)
+}
+
+// writeShared writes a file every user reads and only its owner changes
+func writeShared(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0644)
+}
+
+// LoadClaudeManagedRecord reads what `jtpck --system` wrote, or returns
+// nil when it has not run
+func LoadClaudeManagedRecord() (*ClaudeSettingsRecord, error) {
+	data, err := os.ReadFile(ClaudeManagedRecordPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record ClaudeSettingsRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ClaudeManagedRecordPath(), err)
+	}
+	return &record, nil
+}
+
+// EnableClaudeManagedSettings merges env into the env object of the
+// managed settings file, keeping the administrators' other settings, and
+// makes the file readable by everyone and writable only by its owner. It
+// also repairs a file that was changed since.
+func EnableClaudeManagedSettings(env map[string]string) error {
+	record, err := LoadClaudeManagedRecord()
+	if err != nil {
+		return err
+	}
+	path := ClaudeManagedSettingsPath()
+	if record == nil || record.Path != path {
+		record = newClaudeSettingsRecord(path)
+	}
+
+	for _, dir := range []string{filepath.Dir(path), filepath.Dir(ClaudeManagedRecordPath())} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", dir, err)
+		}
+	}
+	if err := mergeClaudeEnv(record, env, writeShared); err != nil {
+		return err
+	}
+	if err := secureManagedPath(path); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeShared(ClaudeManagedRecordPath(), append(data, '\n'))
+}
+
+// secureManagedPath gives the managed settings and their directory to root
+// and takes write access to the directory from other users, who could
+// otherwise replace the file
+func secureManagedPath(path string) error {
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0022 != 0 {
+		if err := os.Chmod(dir, mode&^0022); err != nil {
+			return fmt.Errorf("securing %s: %w", dir, err)
+		}
+	}
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	for _, p := range []string{dir, path} {
+		if err := os.Chown(p, claudeManagedOwner, claudeManagedOwner); err != nil {
+			return fmt.Errorf("securing %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// RemoveClaudeManagedSettings puts back the env variables `jtpck --system`
+// replaced in the managed settings and deletes its record
+func RemoveClaudeManagedSettings(record *ClaudeSettingsRecord) error {
+	if record == nil {
+		return nil
+	}
+	if err := restoreClaudeEnvFile(record, writeShared); err != nil {
+		return err
+	}
+	if err := os.Remove(ClaudeManagedRecordPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// VerifyClaudeManagedSettings describes what is wrong with the managed
+// settings file JTPCK wrote: a missing file, users who cannot read it or
+// can change it or its directory, and variables changed since
+func VerifyClaudeManagedSettings(record *ClaudeSettingsRecord) ([]string, error) {
+	info, err := os.Stat(record.Path)
+	if os.IsNotExist(err) {
+		return []string{record.Path + " is missing"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if mode := info.Mode().Perm(); mode&0044 != 0044 {
+		problems = append(problems, fmt.Sprintf("%s is not readable by every user (mode %04o)", record.Path, mode))
+	} else if mode&0022 != 0 {
+		problems = append(problems, fmt.Sprintf("%s can be changed by other users (mode %04o)", record.Path, mode))
+	}
+	if uid, ok := fileOwner(info); ok && uid != claudeManagedOwner {
+		problems = append(problems, fmt.Sprintf("%s is owned by uid %d, not root", record.Path, uid))
+	}
+	dir := filepath.Dir(record.Path)
+	if info, err := os.Stat(dir); err == nil {
+		if mode := info.Mode().Perm(); mode&0022 != 0 {
+			problems = append(problems, fmt.Sprintf("%s lets other users replace the file (mode %04o)", dir, mode))
+		}
+		if uid, ok := fileOwner(info); ok && uid != claudeManagedOwner {
+			problems = append(problems, fmt.Sprintf("%s is owned by uid %d, not root", dir, uid))
+		}
+	}
+	drift, err := ClaudeSettingsDrift(record)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range drift {
+		problems = append(problems, fmt.Sprintf("env.%s differs from what jtpck --system wrote", key))
+	}
+	return problems, nil
+}
+
+// fileOwner returns the uid owning a file, where the platform has one
+func fileOwner(info os.FileInfo) (int, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
+
+// ClaudeOverride is an env variable a user sets to something else than the
+// managed settings
+type ClaudeOverride struct {
+	// Source is the settings file, or "environment"
+	Source string
+	Key    string
+	Value  string
+	Want   string
+}
+
+func (o ClaudeOverride) String() string {
+	return fmt.Sprintf("%s = %q in %s (managed: %q)", o.Key, o.Value, o.Source, o.Want)
+}
+
+// ClaudeManagedOverrides returns the variables of env that the settings
+// files at paths, or environ when set, give another value. Claude Code
+// applies the managed value regardless; these are attempts to change it.
+func ClaudeManagedOverrides(env map[string]string, paths []string, environ func(string) (string, bool)) ([]ClaudeOverride, error) {
+	var overrides []ClaudeOverride
+	for _, path := range paths {
+		settings, err := readJSONC(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		set, _ := settings["env"].(map[string]interface{})
+		for _, key := range sortedKeys(env) {
+			if value, ok := set[key]; ok && !reflect.DeepEqual(value, env[key]) {
+				overrides = append(overrides, ClaudeOverride{Source: path, Key: key, Value: fmt.Sprint(value), Want: env[key]})
+			}
+		}
+	}
+	if environ != nil {
+		for _, key := range sortedKeys(env) {
+			if value, ok := environ(key); ok && value != env[key] {
+				overrides = append(overrides, ClaudeOverride{Source: "environment", Key: key, Value: value, Want: env[key]})
+			}
+		}
+	}
+	return overrides, nil
+}
+
+// claudeSettingsNames are the settings files Claude Code reads from a
+// .claude directory
+var claudeSettingsNames = []string{"settings.json", "settings.local.json"}
+
+// ClaudeUserSettingsFiles returns the Claude Code settings files of every
+// home directory on the host, for an administrator checking all users. A
+// user's $CLAUDE_CONFIG_DIR is not known here; `jtpck status` run by them
+// checks it.
+func ClaudeUserSettingsFiles() []string {
+	var paths []string
+	for _, pattern := range []string{"/home/*", "/Users/*", "/root"} {
+		homes, _ := filepath.Glob(OnHost(pattern))
+		for _, home := range homes {
+			paths = append(paths, existingClaudeSettings(filepath.Join(home, ".claude"))...)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ClaudeSettingsFiles returns the Claude Code settings files that apply to
+// the current user in dir: those in $CLAUDE_CONFIG_DIR or ~/.claude, then
+// the project ones in dir and its parents
+func ClaudeSettingsFiles(dir string) []string {
+	user := ClaudeConfigDir()
+	paths := existingClaudeSettings(user)
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return paths
+	}
+	for {
+		if project := filepath.Join(dir, ".claude"); project != user {
+			paths = append(paths, existingClaudeSettings(project)...)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return paths
+		}
+		dir = parent
+	}
+}
+
+// existingClaudeSettings returns the settings files present in a .claude
+// directory
+func existingClaudeSettings(dir string) []string {
+	var paths []string
+	for _, name := range claudeSettingsNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
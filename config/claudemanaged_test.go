@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useManagedPaths points the managed settings and their record at dir
+func useManagedPaths(t *testing.T, dir string) {
+	t.Helper()
+	oldSettings, oldRecord, oldOwner := claudeManagedSettingsPath, claudeManagedRecordPath, claudeManagedOwner
+	claudeManagedSettingsPath = filepath.Join(dir, "claude-code", "managed-settings.json")
+	claudeManagedRecordPath = filepath.Join(dir, "jtpck", "claude-managed.json")
+	claudeManagedOwner = os.Getuid()
+	t.Cleanup(func() {
+		claudeManagedSettingsPath, claudeManagedRecordPath, claudeManagedOwner = oldSettings, oldRecord, oldOwner
+	})
+}
+
+func TestClaudeManagedSettings(t *testing.T) {
+	useTempHome(t)
+	useManagedPaths(t, t.TempDir())
+	os.MkdirAll(filepath.Dir(ClaudeManagedSettingsPath()), 0755)
+	os.WriteFile(ClaudeManagedSettingsPath(), []byte(`{"permissions": {"deny": ["WebFetch"]}}`), 0600)
+
+	env := map[string]string{"CLAUDE_CODE_ENABLE_TELEMETRY": "1", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://org"}
+	if err := EnableClaudeManagedSettings(env); err != nil {
+		t.Fatal(err)
+	}
+	record, err := LoadClaudeManagedRecord()
+	if err != nil || record == nil {
+		t.Fatalf("record = %v, %v", record, err)
+	}
+	if problems, _ := VerifyClaudeManagedSettings(record); len(problems) != 0 {
+		t.Errorf("problems right after writing: %v", problems)
+	}
+	settings, _ := readJSONC(ClaudeManagedSettingsPath())
+	if settings["permissions"] == nil {
+		t.Errorf("lost the other managed settings: %v", settings)
+	}
+
+	// A user turned telemetry off and opened the file and its directory up
+	os.WriteFile(ClaudeManagedSettingsPath(), []byte(`{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://org"}}`), 0666)
+	os.Chmod(ClaudeManagedSettingsPath(), 0666)
+	os.Chmod(filepath.Dir(ClaudeManagedSettingsPath()), 0777)
+	problems, _ := VerifyClaudeManagedSettings(record)
+	if len(problems) != 3 {
+		t.Errorf("problems = %v", problems)
+	}
+	claudeManagedOwner = os.Getuid() + 1
+	if problems, _ := VerifyClaudeManagedSettings(record); len(problems) != 5 {
+		t.Errorf("problems with another owner = %v", problems)
+	}
+	claudeManagedOwner = os.Getuid()
+	if err := EnableClaudeManagedSettings(record.Env); err != nil {
+		t.Fatal(err)
+	}
+	if problems, _ := VerifyClaudeManagedSettings(record); len(problems) != 0 {
+		t.Errorf("problems after repairing: %v", problems)
+	}
+
+	user := ClaudeSettingsPath()
+	os.MkdirAll(filepath.Dir(user), 0700)
+	os.WriteFile(user, []byte(`{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0", "OTEL_SERVICE_NAME": "me"}}`), 0600)
+	environ := func(key string) (string, bool) {
+		if key == "OTEL_EXPORTER_OTLP_ENDPOINT" {
+			return "https://elsewhere", true
+		}
+		return "", false
+	}
+	overrides, err := ClaudeManagedOverrides(record.Env, []string{user, filepath.Join(t.TempDir(), "missing.json")}, environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range overrides {
+		got = append(got, o.Source+":"+o.Key)
+	}
+	if want := []string{user + ":CLAUDE_CODE_ENABLE_TELEMETRY", "environment:OTEL_EXPORTER_OTLP_ENDPOINT"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("overrides = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveClaudeManagedSettings(t *testing.T) {
+	useTempHome(t)
+	useManagedPaths(t, t.TempDir())
+	os.MkdirAll(filepath.Dir(ClaudeManagedSettingsPath()), 0755)
+	original := `{"env": {"OTEL_EXPORTER_OTLP_ENDPOINT": "https://admin"}, "model": "opus"}`
+	os.WriteFile(ClaudeManagedSettingsPath(), []byte(original), 0644)
+
+	if err := EnableClaudeManagedSettings(map[string]string{"CLAUDE_CODE_ENABLE_TELEMETRY": "1", "OTEL_EXPORTER_OTLP_ENDPOINT": "https://org"}); err != nil {
+		t.Fatal(err)
+	}
+	record, _ := LoadClaudeManagedRecord()
+	if err := RemoveClaudeManagedSettings(record); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(ClaudeManagedSettingsPath()); string(got) != original {
+		t.Errorf("managed settings after removing = %s", got)
+	}
+	if record, _ := LoadClaudeManagedRecord(); record != nil {
+		t.Errorf("record left behind: %+v", record)
+	}
+}
+
+func TestClaudeSettingsFiles(t *testing.T) {
+	home := useTempHome(t)
+	configDir := filepath.Join(home, "claude-config")
+	t.Setenv("CLAUDE_CONFIG_DIR", configDir)
+	project := filepath.Join(home, "src", "api")
+	sub := filepath.Join(project, "pkg")
+	for _, path := range []string{
+		filepath.Join(configDir, "settings.json"),
+		filepath.Join(home, ".claude", "settings.json"), // project settings of ~ now
+		filepath.Join(project, ".claude", "settings.json"),
+		filepath.Join(project, ".claude", "settings.local.json"),
+	} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("{}"), 0644)
+	}
+	os.MkdirAll(sub, 0755)
+
+	want := []string{
+		filepath.Join(configDir, "settings.json"),
+		filepath.Join(project, ".claude", "settings.json"),
+		filepath.Join(project, ".claude", "settings.local.json"),
+		filepath.Join(home, ".claude", "settings.json"),
+	}
+	if got := ClaudeSettingsFiles(sub); !reflect.DeepEqual(got, want) {
+		t.Errorf("settings files =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tailscale/hujson"
+)
+
+// Claude modes decide where Claude Code gets the telemetry environment
+const (
+	// ClaudeModeWrapper sets it in the wrapper the shell alias runs
+	ClaudeModeWrapper = "wrapper"
+	// ClaudeModeSettings writes it to the env object of Claude Code's
+	// settings.json, which the IDE extensions read too; there is no wrapper
+	ClaudeModeSettings = "settings"
+)
+
+// ParseClaudeMode validates a Claude mode name
+func ParseClaudeMode(s string) (string, error) {
+	switch s {
+	case ClaudeModeWrapper, ClaudeModeSettings:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid claude_mode %q (want wrapper or settings)", s)
+}
+
+// ClaudeSettingsMode reports whether Claude Code gets its telemetry from
+// settings.json instead of a wrapper
+func (c *Config) ClaudeSettingsMode() bool {
+	return c != nil && c.ClaudeMode == ClaudeModeSettings
+}
+
+// WrappedTools returns the enabled tools that run through a wrapper
+func (c *Config) WrappedTools() []string {
+	var tools []string
+	for _, tool := range c.EnabledTools() {
+		if tool == "claude" && c.ClaudeSettingsMode() {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+// ClaudeConfigDir returns the directory Claude Code keeps its settings in:
+// $CLAUDE_CONFIG_DIR, as Claude Code resolves it, or ~/.claude. Like
+// CODEX_HOME it is ignored when provisioning another home.
+func ClaudeConfigDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" && !Targeted() {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+	return filepath.Join(HomeDir(), ".claude")
+}
+
+// ClaudeSettingsPath returns the path to Claude Code's user settings.json
+func ClaudeSettingsPath() string {
+	return filepath.Join(ClaudeConfigDir(), "settings.json")
+}
+
+// ClaudeSettingsRecord remembers the env variables JTPCK put in Claude
+// Code's settings.json, so status can check them and uninstall undo them
+type ClaudeSettingsRecord struct {
+	// Path is the settings file written
+	Path string `json:"path"`
+	// Env holds the values JTPCK wrote
+	Env map[string]string `json:"env"`
+	// Original holds the values they replaced; a variable missing from it
+	// was not set
+	Original map[string]string `json:"original,omitempty"`
+	// Created is set when JTPCK created the file
+	Created bool `json:"created,omitempty"`
+}
+
+// ClaudeSettingsRecordPath returns the path to the record of the Claude
+// settings edit
+func ClaudeSettingsRecordPath() string {
+	return filepath.Join(StateDir(), "claude-settings.json")
+}
+
+// LoadClaudeSettingsRecord reads the record of the Claude settings edit,
+// or returns nil when JTPCK has not written settings.json
+func LoadClaudeSettingsRecord() (*ClaudeSettingsRecord, error) {
+	data, err := os.ReadFile(ClaudeSettingsRecordPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record ClaudeSettingsRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ClaudeSettingsRecordPath(), err)
+	}
+	return &record, nil
+}
+
+func saveClaudeSettingsRecord(record *ClaudeSettingsRecord) error {
+	if err := os.MkdirAll(StateDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WritePrivateFile(ClaudeSettingsRecordPath(), append(data, '\n'))
+}
+
+// EnableClaudeSettings merges env into the env object of Claude Code's
+// settings.json, keeping the rest of the file and its layout. Variables
+// written before and no longer wanted are put back as they were.
+func EnableClaudeSettings(env map[string]string, demoMode bool, logger func(string)) error {
+	if logger != nil {
+		logger("Writing Claude Code telemetry to " + ClaudeSettingsPath())
+	}
+	if demoMode {
+		return nil
+	}
+
+	record, err := LoadClaudeSettingsRecord()
+	if err != nil {
+		return err
+	}
+	path := ClaudeSettingsPath()
+	if record != nil && record.Path != path {
+		// CLAUDE_CONFIG_DIR changed; the old file goes back as it was
+		if err := RemoveClaudeSettings(record); err != nil {
+			return err
+		}
+		record = nil
+	}
+	if record == nil {
+		record = newClaudeSettingsRecord(path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating Claude settings directory: %w", err)
+	}
+	if err := mergeClaudeEnv(record, env, WritePrivateFile); err != nil {
+		return err
+	}
+	return saveClaudeSettingsRecord(record)
+}
+
+// RemoveClaudeSettings puts back the env variables a record describes and
+// deletes the record. The env object goes when nothing else is left in it.
+func RemoveClaudeSettings(record *ClaudeSettingsRecord) error {
+	if record == nil {
+		return nil
+	}
+	if err := restoreClaudeEnvFile(record, WritePrivateFile); err != nil {
+		return err
+	}
+	if err := os.Remove(ClaudeSettingsRecordPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// newClaudeSettingsRecord starts the record of an edit of path
+func newClaudeSettingsRecord(path string) *ClaudeSettingsRecord {
+	_, err := os.Stat(path)
+	return &ClaudeSettingsRecord{Path: path, Created: os.IsNotExist(err)}
+}
+
+// mergeClaudeEnv sets env in the env object of the record's file and
+// updates the record. Variables written before and no longer wanted are
+// put back as they were.
+func mergeClaudeEnv(record *ClaudeSettingsRecord, env map[string]string, write func(string, []byte) error) error {
+	if record.Original == nil {
+		record.Original = map[string]string{}
+	}
+	err := editJSONFile(record.Path, write, func(root *hujson.Value, settings *hujson.Object) error {
+		envObj, lead := childJSONObject(settings, "env")
+		current := jsonStringMembers(envObj)
+
+		// Undo what is no longer wanted
+		for _, key := range sortedKeys(record.Env) {
+			if _, ok := env[key]; ok {
+				continue
+			}
+			restoreClaudeEnv(root, envObj, key, record.Original, lead)
+		}
+		for _, key := range sortedKeys(env) {
+			if value, ok := current[key]; ok {
+				if _, owned := record.Env[key]; !owned {
+					record.Original[key] = value
+				}
+			}
+			setJSONMember(envObj, key, hujson.String(env[key]), lead)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	record.Env = env
+	for key := range record.Original {
+		if _, ok := env[key]; !ok {
+			delete(record.Original, key)
+		}
+	}
+	return nil
+}
+
+// restoreClaudeEnvFile undoes a recorded edit, deleting the file when
+// JTPCK created it and nothing else was added since
+func restoreClaudeEnvFile(record *ClaudeSettingsRecord, write func(string, []byte) error) error {
+	if _, err := os.Stat(record.Path); err != nil {
+		return nil
+	}
+	err := editJSONFile(record.Path, write, func(root *hujson.Value, settings *hujson.Object) error {
+		if _, ok := jsonObject(root.Find("/env")); !ok {
+			return nil
+		}
+		envObj, lead := childJSONObject(settings, "env")
+		for _, key := range sortedKeys(record.Env) {
+			restoreClaudeEnv(root, envObj, key, record.Original, lead)
+		}
+		if len(envObj.Members) == 0 {
+			return removeJSONMember(root, "", "env")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if settings, err := readJSONC(record.Path); err == nil && len(settings) == 0 && record.Created {
+		return os.Remove(record.Path)
+	}
+	return nil
+}
+
+// restoreClaudeEnv gives an env variable its original value back, or
+// removes it when it had none
+func restoreClaudeEnv(root *hujson.Value, envObj *hujson.Object, key string, original map[string]string, lead hujson.Extra) {
+	if value, ok := original[key]; ok {
+		setJSONMember(envObj, key, hujson.String(value), lead)
+		return
+	}
+	if root.Find("/env/"+jsonPointerEscape(key)) != nil {
+		removeJSONMember(root, "/env", key)
+	}
+}
+
+// ClaudeSettingsDrift returns the variables whose value in settings.json
+// differs from what JTPCK wrote, for `jtpck status`
+func ClaudeSettingsDrift(record *ClaudeSettingsRecord) ([]string, error) {
+	settings, err := readJSONC(record.Path)
+	if os.IsNotExist(err) {
+		settings = map[string]interface{}{}
+	} else if err != nil {
+		return nil, err
+	}
+	env, _ := settings["env"].(map[string]interface{})
+	var drift []string
+	for _, key := range sortedKeys(record.Env) {
+		if value, ok := env[key].(string); !ok || value != record.Env[key] {
+			drift = append(drift, key)
+		}
+	}
+	return drift, nil
+}
+
+// editJSONFile applies edit to a JSON-with-comments file holding an
+// object, creating it when missing, and writes it back unless the result
+// would no longer parse
+func editJSONFile(path string, write func(string, []byte) error, edit func(root *hujson.Value, obj *hujson.Object) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}\n")
+	}
+	root, err := hujson.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	obj, ok := jsonObject(&root)
+	if !ok {
+		return fmt.Errorf("parsing %s: not a JSON object", path)
+	}
+	if err := edit(&root, obj); err != nil {
+		return fmt.Errorf("updating %s: %w", path, err)
+	}
+
+	output := root.Pack()
+	if _, err := hujson.Parse(output); err != nil || (json.Valid(data) && !json.Valid(output)) {
+		return fmt.Errorf("updating %s would leave it invalid, edit it by hand", path)
+	}
+	return write(path, output)
+}
+
+// jsonStringMembers returns the string members of an object
+func jsonStringMembers(obj *hujson.Object) map[string]string {
+	values := map[string]string{}
+	for _, member := range obj.Members {
+		if literal, ok := member.Value.Value.(hujson.Literal); ok && literal.Kind() == '"' {
+			values[member.Name.Value.(hujson.Literal).String()] = literal.String()
+		}
+	}
+	return values
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
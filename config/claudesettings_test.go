@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClaudeSettings(t *testing.T) {
+	useTempHome(t)
+	original := `{
+  // mine
+  "model": "opus",
+  "env": {
+    "OTEL_SERVICE_NAME": "me",
+    "DEBUG": "1"
+  }
+}
+`
+	os.MkdirAll(ClaudeConfigDir(), 0700)
+	os.WriteFile(ClaudeSettingsPath(), []byte(original), 0600)
+
+	env := map[string]string{"OTEL_SERVICE_NAME": "claude-code", "CLAUDE_CODE_ENABLE_TELEMETRY": "1", "OTEL_LOG_USER_PROMPTS": "1"}
+	if err := EnableClaudeSettings(env, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  // mine
+  "model": "opus",
+  "env": {
+    "OTEL_SERVICE_NAME": "claude-code",
+    "DEBUG": "1",
+    "CLAUDE_CODE_ENABLE_TELEMETRY": "1",
+    "OTEL_LOG_USER_PROMPTS": "1"
+  }
+}
+`
+	if got, _ := os.ReadFile(ClaudeSettingsPath()); string(got) != want {
+		t.Errorf("settings =\n%s\nwant\n%s", got, want)
+	}
+
+	// Turning prompts off takes the variable back; the replaced value
+	// stays recorded across runs
+	delete(env, "OTEL_LOG_USER_PROMPTS")
+	if err := EnableClaudeSettings(env, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, err := LoadClaudeSettingsRecord()
+	if err != nil || record == nil {
+		t.Fatalf("record = %v, %v", record, err)
+	}
+	if want := map[string]string{"OTEL_SERVICE_NAME": "me"}; !reflect.DeepEqual(record.Original, want) {
+		t.Errorf("original = %v, want %v", record.Original, want)
+	}
+	if drift, _ := ClaudeSettingsDrift(record); len(drift) != 0 {
+		t.Errorf("drift right after writing: %v", drift)
+	}
+
+	os.WriteFile(ClaudeSettingsPath(), []byte(`{"env": {"OTEL_SERVICE_NAME": "claude-code", "CLAUDE_CODE_ENABLE_TELEMETRY": "0"}}`), 0600)
+	if drift, _ := ClaudeSettingsDrift(record); !reflect.DeepEqual(drift, []string{"CLAUDE_CODE_ENABLE_TELEMETRY"}) {
+		t.Errorf("drift = %v", drift)
+	}
+	os.WriteFile(ClaudeSettingsPath(), []byte(original), 0600)
+	EnableClaudeSettings(env, false, nil)
+
+	if err := RemoveClaudeSettings(record); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(ClaudeSettingsPath()); string(got) != original {
+		t.Errorf("after removing =\n%s\nwant the original", got)
+	}
+	if _, err := os.Stat(ClaudeSettingsRecordPath()); !os.IsNotExist(err) {
+		t.Errorf("record survived: %v", err)
+	}
+}
+
+func TestClaudeSettingsCreated(t *testing.T) {
+	home := useTempHome(t)
+	t.Setenv("CLAUDE_CONFIG_DIR", filepath.Join(home, "claude"))
+
+	if err := EnableClaudeSettings(map[string]string{"CLAUDE_CODE_ENABLE_TELEMETRY": "1"}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(home, "claude", "settings.json")); string(got) != "{\n  \"env\": {\n    \"CLAUDE_CODE_ENABLE_TELEMETRY\": \"1\"\n  }\n}\n" {
+		t.Errorf("settings = %q", got)
+	}
+	record, _ := LoadClaudeSettingsRecord()
+	if err := RemoveClaudeSettings(record); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(ClaudeSettingsPath()); !os.IsNotExist(err) {
+		t.Errorf("settings.json JTPCK created survived: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 )
@@ -20,14 +21,127 @@ func CodexEnv(userID, endpoint string) map[string]string {
 	}
 }
 
+const (
+	// DefaultCodexEnvironment is the otel.environment label unless
+	// codex_environment sets another
+	DefaultCodexEnvironment = "prod"
+	// CodexProfilePlaceholder in codex_environment stands for the Codex
+	// profile a session runs with
+	CodexProfilePlaceholder = "{profile}"
+	// CodexDefaultProfile names running Codex without a profile
+	CodexDefaultProfile = "default"
+)
+
+// CodexHome returns the directory Codex keeps its config in: $CODEX_HOME,
+// as Codex resolves it, or ~/.codex. The variable describes the current
+// user, so it is ignored when provisioning another home.
+func CodexHome() string {
+	if dir := os.Getenv("CODEX_HOME"); dir != "" && !Targeted() {
+		if abs, err := filepath.Abs(dir); err == nil {
+			return abs
+		}
+		return dir
+	}
+	return filepath.Join(HomeDir(), ".codex")
+}
+
 // CodexConfigPath returns the path to Codex config.toml
 func CodexConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".codex", "config.toml")
+	return filepath.Join(CodexHome(), "config.toml")
+}
+
+// CodexSettings is what jtpck reads back from Codex config.toml
+type CodexSettings struct {
+	// Profile is used when the command line selects none
+	Profile string `toml:"profile"`
+	Otel    struct {
+		Environment string `toml:"environment"`
+	} `toml:"otel"`
+}
+
+// ReadCodexSettings reads config.toml; Profile is CodexDefaultProfile
+// when it names none
+func ReadCodexSettings() CodexSettings {
+	data, _ := os.ReadFile(CodexConfigPath())
+	return parseCodexSettings(data)
+}
+
+func parseCodexSettings(data []byte) CodexSettings {
+	var settings CodexSettings
+	toml.Unmarshal(data, &settings)
+	if settings.Profile == "" {
+		settings.Profile = CodexDefaultProfile
+	}
+	return settings
+}
+
+// CodexProfileFromArgs returns the profile a Codex command line selects
+// with -p/--profile, or "" when it selects none
+func CodexProfileFromArgs(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "-p" || arg == "--profile":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(arg, "--profile="):
+			return strings.TrimPrefix(arg, "--profile=")
+		}
+	}
+	return ""
+}
+
+// CodexEnvironment returns the otel.environment label for a Codex profile
+func CodexEnvironment(label, profile string) string {
+	if label == "" {
+		label = DefaultCodexEnvironment
+	}
+	return strings.ReplaceAll(label, CodexProfilePlaceholder, profile)
+}
+
+// CodexEnvironmentArgs returns the `-c` override that labels a session
+// with another environment than config.toml names
+func CodexEnvironmentArgs(environment string) []string {
+	return []string{"-c", fmt.Sprintf("otel.environment=%q", environment)}
+}
+
+// CodexLaunchArgs returns `-c` overrides that send the exporters written by
+// EnableCodexTelemetry to another endpoint or account, or disable them when
+// endpoint is empty.
+func CodexLaunchArgs(userID, endpoint string) []string {
+	if endpoint == "" {
+		return []string{
+			"-c", `otel.exporter="none"`,
+			"-c", `otel.trace_exporter="none"`,
+		}
+	}
+
+	var args []string
+	for _, exporter := range []struct{ key, path string }{
+		{"otel.exporter.otlp-http", "/v1/logs"},
+		{"otel.trace_exporter.otlp-http", "/v1/traces"},
+	} {
+		args = append(args,
+			"-c", fmt.Sprintf("%s.endpoint=%q", exporter.key, endpoint+exporter.path),
+			"-c", fmt.Sprintf("%s.headers.Authorization=%q", exporter.key, "Bearer "+userID),
+		)
+	}
+	return args
+}
+
+// CodexRelayArgs returns `-c` overrides that send both exporters, whatever
+// config.toml configures, to a local relay
+func CodexRelayArgs(relayURL string) []string {
+	return []string{
+		"-c", fmt.Sprintf(`otel.exporter={otlp-http={endpoint=%q,protocol="binary"}}`, relayURL+"/v1/logs"),
+		"-c", fmt.Sprintf(`otel.trace_exporter={otlp-http={endpoint=%q,protocol="binary"}}`, relayURL+"/v1/traces"),
+	}
 }
 
 // EnableCodexTelemetry writes [otel] section to Codex config.toml
-func EnableCodexTelemetry(userID, endpoint string, demoMode bool, logger func(string)) error {
+func EnableCodexTelemetry(userID, endpoint string, settings TelemetrySettings, demoMode bool, logger func(string)) error {
 	if logger != nil {
 		logger("Enabling Codex telemetry")
 	}
@@ -37,59 +151,133 @@ func EnableCodexTelemetry(userID, endpoint string, demoMode bool, logger func(st
 	}
 
 	configPath := CodexConfigPath()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("creating Codex config directory: %w", err)
+	}
 
-	// Read existing config
-	var config map[string]interface{}
+	// Edit the existing file in place, keeping the user's comments and layout
 	data, err := os.ReadFile(configPath)
-	if err == nil && len(data) > 0 {
-		if err := toml.Unmarshal(data, &config); err != nil {
-			return fmt.Errorf("parsing existing Codex config: %w", err)
-		}
-	} else if err != nil && !os.IsNotExist(err) {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("reading Codex config: %w", err)
-	} else {
-		config = make(map[string]interface{})
-	}
-
-	// Codex needs both logs and traces endpoints
-	logsEndpoint := endpoint + "/v1/logs"
-	tracesEndpoint := endpoint + "/v1/traces"
-
-	// Create [otel] section with both log and trace exporters
-	otel := map[string]interface{}{
-		"environment":     "prod",
-		"log_user_prompt": false,
-		"exporter": map[string]interface{}{
-			"otlp-http": map[string]interface{}{
-				"endpoint": logsEndpoint,
-				"protocol": "binary",
-				"headers": map[string]string{
-					"Authorization": fmt.Sprintf("Bearer %s", userID),
-				},
-			},
-		},
-		"trace_exporter": map[string]interface{}{
-			"otlp-http": map[string]interface{}{
-				"endpoint": tracesEndpoint,
-				"protocol": "binary",
-				"headers": map[string]string{
-					"Authorization": fmt.Sprintf("Bearer %s", userID),
-				},
-			},
-		},
-	}
-
-	config["otel"] = otel
-
-	// Write updated config
-	output, err := toml.Marshal(config)
+	}
+	doc, err := parseTOML(data)
 	if err != nil {
-		return fmt.Errorf("encoding Codex config: %w", err)
+		return fmt.Errorf("parsing existing Codex config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, output, 0644); err != nil {
+	// Start from the user's own settings, undoing JTPCK's last edit
+	record, err := LoadCodexOtelRecord()
+	if err != nil {
+		return err
+	}
+	if err := restoreCodexOtel(doc, record); err != nil {
+		return fmt.Errorf("updating Codex config: %w", err)
+	}
+	current := codexOtelTable(doc.Bytes())
+
+	next := &CodexOtelRecord{}
+	if foreign := foreignCodexExporters(current, userID, endpoint); len(foreign) > 0 {
+		switch next.Policy = settings.CodexExporters; next.Policy {
+		case CodexExportersReplace, CodexExportersKeep:
+		case CodexExportersAlongside:
+			for _, key := range []string{"exporter", "trace_exporter"} {
+				if value, ok := current[key]; ok && value != "none" {
+					if _, isHTTP := codexHTTPExporter(value); !isHTTP {
+						return fmt.Errorf("codex_exporters alongside needs otlp-http exporters; otel.%s is %s", key, describeCodexExporter(value))
+					}
+				}
+			}
+		default:
+			next.Policy = CodexExportersKeep
+		}
+	}
+
+	if next.Policy != CodexExportersKeep && next.Policy != CodexExportersAlongside {
+		if next.Original, err = codexOwnedOriginal(current, record, userID, endpoint); err != nil {
+			return fmt.Errorf("recording Codex settings: %w", err)
+		}
+
+		// Codex needs both logs and traces endpoints, each with the token,
+		// and nothing left over from an exporter that was there before
+		doc.Remove([]string{"otel", "exporter"})
+		doc.Remove([]string{"otel", "trace_exporter"})
+		otel := []struct {
+			key   string
+			value interface{}
+		}{
+			{"environment", CodexEnvironment(settings.CodexEnvironment, parseCodexSettings(data).Profile)},
+			{"log_user_prompt", settings.LogPrompts},
+			{"exporter.otlp-http.endpoint", endpoint + "/v1/logs"},
+			{"exporter.otlp-http.protocol", "binary"},
+			{"exporter.otlp-http.headers.Authorization", "Bearer " + userID},
+			{"trace_exporter.otlp-http.endpoint", endpoint + "/v1/traces"},
+			{"trace_exporter.otlp-http.protocol", "binary"},
+			{"trace_exporter.otlp-http.headers.Authorization", "Bearer " + userID},
+		}
+		for _, kv := range otel {
+			if err := doc.Set(append([]string{"otel"}, strings.Split(kv.key, ".")...), kv.value); err != nil {
+				return fmt.Errorf("updating Codex config: %w", err)
+			}
+		}
+	}
+
+	output := doc.Bytes()
+	if err := toml.Unmarshal(output, new(map[string]interface{})); err != nil {
+		return fmt.Errorf("updating Codex config would leave it invalid, edit [otel] in %s by hand: %w", configPath, err)
+	}
+
+	// The exporter headers carry the bearer token
+	if err := WritePrivateFile(configPath, output); err != nil {
 		return fmt.Errorf("writing Codex config: %w", err)
 	}
+	if err := saveCodexOtelRecord(next); err != nil {
+		return fmt.Errorf("recording the Codex config edit: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveCodexTelemetry undoes JTPCK's edit of a Codex config file as
+// recorded, restoring exporters it replaced, or deletes the whole otel
+// table tree for installs without a record. The rest of the file stays as
+// it was. It refuses to write a file that would no longer parse or does
+// not end up as recorded.
+func RemoveCodexTelemetry(configPath string, record *CodexOtelRecord) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s (remove [otel] by hand): %w", configPath, err)
+	}
 
+	if record != nil {
+		if err := restoreCodexOtel(doc, record); err != nil {
+			return err
+		}
+	} else if !doc.Remove([]string{"otel"}) {
+		return nil
+	}
+
+	output := doc.Bytes()
+	var check map[string]interface{}
+	if err := toml.Unmarshal(output, &check); err != nil {
+		return fmt.Errorf("removing [otel] would leave %s invalid: %w", configPath, err)
+	}
+	otel, hasOtel := check["otel"].(map[string]interface{})
+	switch {
+	case record == nil && hasOtel:
+		return fmt.Errorf("could not remove every otel setting from %s", configPath)
+	case record != nil && record.Policy != CodexExportersKeep && record.Policy != CodexExportersAlongside && !sameCodexOtel(otel, record.Original):
+		return fmt.Errorf("could not restore the otel settings of %s", configPath)
+	}
+
+	if err := WritePrivateFile(configPath, output); err != nil {
+		return err
+	}
+	if record != nil {
+		os.Remove(CodexOtelRecordPath())
+	}
 	return nil
 }
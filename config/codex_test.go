@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCodexHome(t *testing.T) {
+	home := useTempHome(t)
+	if got, want := CodexConfigPath(), filepath.Join(home, ".codex", "config.toml"); got != want {
+		t.Errorf("CodexConfigPath() = %q, want %q", got, want)
+	}
+
+	dir := filepath.Join(home, "codex-home")
+	t.Setenv("CODEX_HOME", dir)
+	if got, want := CodexConfigPath(), filepath.Join(dir, "config.toml"); got != want {
+		t.Errorf("with CODEX_HOME, CodexConfigPath() = %q, want %q", got, want)
+	}
+}
+
+func TestCodexProfileFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"-p", "fast", "hello"}, "fast"},
+		{[]string{"exec", "--profile", "deep"}, "deep"},
+		{[]string{"--profile=work"}, "work"},
+		{[]string{"--", "-p", "fast"}, ""},
+		{[]string{"-p"}, ""},
+	}
+	for _, tt := range tests {
+		if got := CodexProfileFromArgs(tt.args); got != tt.want {
+			t.Errorf("CodexProfileFromArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCodexEnvironmentByProfile(t *testing.T) {
+	useTempHome(t)
+	path := CodexConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	WritePrivateFile(path, []byte("profile = \"fast\"\n"))
+
+	settings := TelemetrySettings{CodexEnvironment: "team-{profile}"}
+	if err := EnableCodexTelemetry("abc", "https://collector.example.com", settings, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	written := ReadCodexSettings()
+	if written.Profile != "fast" || written.Otel.Environment != "team-fast" {
+		t.Errorf("profile = %q, environment = %q", written.Profile, written.Otel.Environment)
+	}
+
+	if got := CodexEnvironment("", "fast"); got != DefaultCodexEnvironment {
+		t.Errorf("default label = %q", got)
+	}
+	if got := parseCodexSettings(nil).Profile; got != CodexDefaultProfile {
+		t.Errorf("profile without config = %q", got)
+	}
+
+	cfg := &Config{CodexProfiles: []string{"work", CodexDefaultProfile}}
+	if !cfg.CodexProfileEnabled("work") || !cfg.CodexProfileEnabled(CodexDefaultProfile) || cfg.CodexProfileEnabled("fast") {
+		t.Error("CodexProfileEnabled does not follow codex_profiles")
+	}
+	if !(&Config{}).CodexProfileEnabled("fast") {
+		t.Error("an empty codex_profiles should enable every profile")
+	}
+}
+
+func TestCodexExportersPolicy(t *testing.T) {
+	original := `model = "o3"
+
+[otel]
+environment = "staging" # ours
+exporter = { otlp-http = { endpoint = "https://corp.example.com/v1/logs", protocol = "json", headers = { "x-team" = "ai" } } }
+`
+	tests := []struct {
+		policy     string
+		wantPolicy string
+		changed    bool
+	}{
+		{CodexExportersReplace, CodexExportersReplace, true},
+		{CodexExportersKeep, CodexExportersKeep, false},
+		{CodexExportersAlongside, CodexExportersAlongside, false},
+		{CodexExportersAsk, CodexExportersKeep, false},
+	}
+	for _, tt := range tests {
+		useTempHome(t)
+		path := CodexConfigPath()
+		os.MkdirAll(filepath.Dir(path), 0700)
+		WritePrivateFile(path, []byte(original))
+
+		foreign, err := ForeignCodexExporters("abc", "https://collector.example.com")
+		if err != nil || len(foreign) != 1 || foreign[0] != "otel.exporter (otlp-http → https://corp.example.com/v1/logs)" {
+			t.Fatalf("ForeignCodexExporters() = %q, %v", foreign, err)
+		}
+
+		settings := TelemetrySettings{CodexExporters: tt.policy}
+		if err := EnableCodexTelemetry("abc", "https://collector.example.com", settings, false, nil); err != nil {
+			t.Fatalf("%s: %v", tt.policy, err)
+		}
+		record, _ := LoadCodexOtelRecord()
+		if record == nil || record.Policy != tt.wantPolicy {
+			t.Fatalf("%s: record = %+v", tt.policy, record)
+		}
+		data, _ := os.ReadFile(path)
+		if changed := string(data) != original; changed != tt.changed {
+			t.Errorf("%s: config changed = %v:\n%s", tt.policy, changed, data)
+		}
+
+		// Later runs still see the user's exporter, not JTPCK's
+		if foreign, _ := ForeignCodexExporters("abc", "https://collector.example.com"); len(foreign) != 1 {
+			t.Errorf("%s: after enabling, foreign exporters = %q", tt.policy, foreign)
+		}
+		if err := EnableCodexTelemetry("abc", "https://collector.example.com", settings, false, nil); err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := os.ReadFile(path); string(again) != string(data) {
+			t.Errorf("%s: enabling twice gave\n%s\nthen\n%s", tt.policy, data, again)
+		}
+
+		if err := RemoveCodexTelemetry(path, record); err != nil {
+			t.Fatalf("%s: %v", tt.policy, err)
+		}
+		restored, _ := os.ReadFile(path)
+		if !reflect.DeepEqual(codexOtelTable(restored), codexOtelTable([]byte(original))) {
+			t.Errorf("%s: not restored:\n%s", tt.policy, restored)
+		}
+		if _, err := os.Stat(CodexOtelRecordPath()); !os.IsNotExist(err) {
+			t.Errorf("%s: record left behind", tt.policy)
+		}
+	}
+}
+
+func TestCodexExportersAlongsideNeedsHTTP(t *testing.T) {
+	useTempHome(t)
+	path := CodexConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	WritePrivateFile(path, []byte("[otel]\nexporter = { otlp-grpc = { endpoint = \"https://corp.example.com:4317\" } }\n"))
+
+	settings := TelemetrySettings{CodexExporters: CodexExportersAlongside}
+	if err := EnableCodexTelemetry("abc", "https://collector.example.com", settings, false, nil); err == nil {
+		t.Error("relaying to a gRPC exporter was accepted")
+	}
+}
+
+func TestCodexTelemetryWithoutRecord(t *testing.T) {
+	// Installs from before edits were recorded own the whole otel tree
+	useTempHome(t)
+	path := CodexConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	legacy := "model = \"o3\"\n\n[otel]\nenvironment = 'prod'\n\n[otel.exporter.otlp-http]\nendpoint = 'https://collector.example.com/v1/logs'\n"
+	WritePrivateFile(path, []byte(legacy))
+
+	if err := EnableCodexTelemetry("abc", "https://collector.example.com", TelemetrySettings{}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, _ := LoadCodexOtelRecord()
+	if record == nil || record.Policy != "" || record.Original != "" {
+		t.Fatalf("record = %+v", record)
+	}
+	if err := RemoveCodexTelemetry(path, record); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "model = \"o3\"\n" {
+		t.Errorf("got %q", got)
+	}
+}
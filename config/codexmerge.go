@@ -0,0 +1,297 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Codex exporter policies decide what happens to an exporter in Codex
+// config.toml that sends to another collector than JTPCK
+const (
+	// CodexExportersAsk asks during setup; without a prompt the exporter is kept
+	CodexExportersAsk = "ask"
+	// CodexExportersReplace points the exporters at JTPCK; uninstall restores them
+	CodexExportersReplace = "replace"
+	// CodexExportersKeep leaves the exporters alone, so Codex sends JTPCK nothing
+	CodexExportersKeep = "keep"
+	// CodexExportersAlongside leaves config.toml alone; the wrapper relays
+	// each export to both collectors
+	CodexExportersAlongside = "alongside"
+)
+
+// codexOwnedKeys are the otel keys EnableCodexTelemetry sets
+var codexOwnedKeys = []string{"environment", "log_user_prompt", "exporter", "trace_exporter"}
+
+// ParseCodexExporters validates a Codex exporter policy name
+func ParseCodexExporters(s string) (string, error) {
+	switch s {
+	case CodexExportersAsk, CodexExportersReplace, CodexExportersKeep, CodexExportersAlongside:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid codex_exporters %q (want ask, replace, keep or alongside)", s)
+}
+
+// CodexOtelRecord remembers what EnableCodexTelemetry did to config.toml,
+// so later runs and uninstall can undo exactly that
+type CodexOtelRecord struct {
+	// Policy is the decision about exporters that sent elsewhere; empty
+	// when there were none
+	Policy string `json:"policy,omitempty"`
+	// Original holds the otel keys JTPCK replaced, as TOML; a key missing
+	// from it did not exist
+	Original string `json:"original,omitempty"`
+}
+
+// CodexOtelRecordPath returns the path to the record of the Codex edit
+func CodexOtelRecordPath() string {
+	return filepath.Join(StateDir(), "codex-otel.json")
+}
+
+// LoadCodexOtelRecord reads the record of the last Codex edit, or returns
+// nil when JTPCK has not edited config.toml since recording began
+func LoadCodexOtelRecord() (*CodexOtelRecord, error) {
+	data, err := os.ReadFile(CodexOtelRecordPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record CodexOtelRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", CodexOtelRecordPath(), err)
+	}
+	return &record, nil
+}
+
+func saveCodexOtelRecord(record *CodexOtelRecord) error {
+	if err := os.MkdirAll(StateDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WritePrivateFile(CodexOtelRecordPath(), append(data, '\n'))
+}
+
+// CodexExporter is an OTLP/HTTP exporter configured in config.toml
+type CodexExporter struct {
+	Endpoint string
+	Headers  map[string]string
+}
+
+// ForeignCodexExporters describes the exporters of the user's own Codex
+// config, ignoring JTPCK's edits, that send to another collector than
+// endpoint with another token than userID
+func ForeignCodexExporters(userID, endpoint string) ([]string, error) {
+	data, err := os.ReadFile(CodexConfigPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	record, err := LoadCodexOtelRecord()
+	if err != nil {
+		return nil, err
+	}
+	if err := restoreCodexOtel(doc, record); err != nil {
+		return nil, err
+	}
+	return foreignCodexExporters(codexOtelTable(doc.Bytes()), userID, endpoint), nil
+}
+
+func foreignCodexExporters(otel map[string]interface{}, userID, endpoint string) []string {
+	var foreign []string
+	for _, key := range []string{"exporter", "trace_exporter"} {
+		value, ok := otel[key]
+		if !ok || value == "none" {
+			continue
+		}
+		if jtpckExporter(value, userID, endpoint) {
+			continue
+		}
+		foreign = append(foreign, fmt.Sprintf("otel.%s (%s)", key, describeCodexExporter(value)))
+	}
+	return foreign
+}
+
+// jtpckExporter reports whether an exporter sends to JTPCK
+func jtpckExporter(value interface{}, userID, endpoint string) bool {
+	exporter, isHTTP := codexHTTPExporter(value)
+	return isHTTP && (strings.HasPrefix(exporter.Endpoint, endpoint+"/") || exporter.Headers["Authorization"] == "Bearer "+userID)
+}
+
+// describeCodexExporter names an exporter's kind and where it sends
+func describeCodexExporter(value interface{}) string {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	var parts []string
+	for kind, settings := range table {
+		part := kind
+		if settings, ok := settings.(map[string]interface{}); ok && settings["endpoint"] != nil {
+			part += " → " + fmt.Sprint(settings["endpoint"])
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// codexHTTPExporter returns the settings of an otlp-http exporter value
+func codexHTTPExporter(value interface{}) (CodexExporter, bool) {
+	table, _ := value.(map[string]interface{})
+	settings, ok := table["otlp-http"].(map[string]interface{})
+	if !ok || len(table) != 1 {
+		return CodexExporter{}, false
+	}
+	exporter := CodexExporter{Headers: map[string]string{}}
+	exporter.Endpoint, _ = settings["endpoint"].(string)
+	headers, _ := settings["headers"].(map[string]interface{})
+	for name, value := range headers {
+		exporter.Headers[name] = fmt.Sprint(value)
+	}
+	return exporter, exporter.Endpoint != ""
+}
+
+// ReadCodexExporters returns the otlp-http log and trace exporters in
+// config.toml, nil where there is none
+func ReadCodexExporters() (logs, traces *CodexExporter) {
+	data, _ := os.ReadFile(CodexConfigPath())
+	otel := codexOtelTable(data)
+	if exporter, ok := codexHTTPExporter(otel["exporter"]); ok {
+		logs = &exporter
+	}
+	if exporter, ok := codexHTTPExporter(otel["trace_exporter"]); ok {
+		traces = &exporter
+	}
+	return logs, traces
+}
+
+// codexOtelTable returns the otel table of a config.toml
+func codexOtelTable(data []byte) map[string]interface{} {
+	var doc map[string]interface{}
+	toml.Unmarshal(data, &doc)
+	otel, _ := doc["otel"].(map[string]interface{})
+	return otel
+}
+
+// codexOwnedOriginal returns the otel keys JTPCK is about to replace, as
+// TOML. Exporters sending to JTPCK are its own, and so is the rest when
+// they were written before edits were recorded.
+func codexOwnedOriginal(otel map[string]interface{}, record *CodexOtelRecord, userID, endpoint string) (string, error) {
+	owned := pick(otel, codexOwnedKeys)
+	for _, key := range []string{"exporter", "trace_exporter"} {
+		if value, ok := owned[key]; ok && jtpckExporter(value, userID, endpoint) {
+			if record == nil {
+				return "", nil
+			}
+			delete(owned, key)
+		}
+	}
+	if len(owned) == 0 {
+		return "", nil
+	}
+	out, err := toml.Marshal(map[string]interface{}{"otel": owned})
+	return string(out), err
+}
+
+// restoreCodexOtel undoes a recorded edit: the keys JTPCK set are removed
+// and the values they replaced put back
+func restoreCodexOtel(doc *tomlDoc, record *CodexOtelRecord) error {
+	if record == nil || record.Policy == CodexExportersKeep || record.Policy == CodexExportersAlongside {
+		return nil
+	}
+	var original map[string]interface{}
+	if err := toml.Unmarshal([]byte(record.Original), &original); err != nil {
+		return fmt.Errorf("parsing the recorded Codex settings: %w", err)
+	}
+	was, _ := original["otel"].(map[string]interface{})
+	current := codexOtelTable(doc.Bytes())
+	for _, key := range codexOwnedKeys {
+		path := []string{"otel", key}
+		value, had := was[key]
+		if _, isTable := value.(map[string]interface{}); had && !isTable {
+			// A plain value goes back in place, keeping its comment
+			if _, isTable := current[key].(map[string]interface{}); isTable {
+				doc.Remove(path)
+			}
+			if err := doc.Set(path, value); err != nil {
+				return err
+			}
+			continue
+		}
+		doc.Remove(path)
+		if had {
+			if err := setTOMLTree(doc, path, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	// An otel table left empty was JTPCK's
+	if otel := codexOtelTable(doc.Bytes()); otel != nil && emptyTOMLTree(otel) {
+		doc.Remove([]string{"otel"})
+	}
+	return nil
+}
+
+// setTOMLTree sets every value under a table, in key order
+func setTOMLTree(doc *tomlDoc, path []string, value interface{}) error {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return doc.Set(path, value)
+	}
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := setTOMLTree(doc, append(append([]string{}, path...), key), table[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emptyTOMLTree reports whether a table holds no values, only tables
+func emptyTOMLTree(table map[string]interface{}) bool {
+	for _, value := range table {
+		sub, ok := value.(map[string]interface{})
+		if !ok || !emptyTOMLTree(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameCodexOtel reports whether the otel keys JTPCK owns match a recording
+func sameCodexOtel(otel map[string]interface{}, original string) bool {
+	var doc map[string]interface{}
+	toml.Unmarshal([]byte(original), &doc)
+	want, _ := doc["otel"].(map[string]interface{})
+	return reflect.DeepEqual(pick(otel, codexOwnedKeys), pick(want, codexOwnedKeys))
+}
+
+// pick returns the entries of m with the given keys
+func pick(m map[string]interface{}, keys []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range keys {
+		if value, ok := m[key]; ok {
+			out[key] = value
+		}
+	}
+	return out
+}
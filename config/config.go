@@ -2,29 +2,76 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 )
 
+// DefaultEndpoint is the JTPCK telemetry endpoint
+const DefaultEndpoint = "https://JTPCK.com/api/v1/telemetry"
+
 // Config represents the JTPCK configuration
 type Config struct {
-	UserID   string    `json:"user_id"`
-	Endpoint string    `json:"endpoint"`
-	Created  time.Time `json:"created_at"`
-	Updated  time.Time `json:"updated_at"`
-}
+	// Version is the schema version; Load upgrades older files (see migrate.go)
+	Version int `json:"version"`
+	// UserID is kept in the credentials file; config.json only carries it
+	// for installs that predate the credentials file
+	UserID string `json:"user_id,omitempty"`
+	// Endpoint is empty to use the system or built-in default
+	Endpoint string `json:"endpoint,omitempty"`
+	// Failover endpoints are tried in order when Endpoint is unreachable
+	Failover []string `json:"failover_endpoints,omitempty"`
+	// Offline is what to do when no endpoint is reachable: disable or spool
+	Offline   string `json:"offline,omitempty"`
+	Supervise *bool  `json:"supervise,omitempty"`
+	// HookTimeout bounds each pre/post hook, in seconds (0 = default)
+	HookTimeout int `json:"hook_timeout,omitempty"`
+	// LogPrompts includes prompt text in telemetry (off by default)
+	LogPrompts *bool `json:"log_prompts,omitempty"`
+	// LogToolDetails includes MCP server and tool names (Claude Code)
+	LogToolDetails *bool `json:"log_tool_details,omitempty"`
+	// SampleRate is the fraction of traces kept (unset = all)
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// Tools are the tools JTPCK configures (empty = all supported tools)
+	Tools []string `json:"tools,omitempty"`
+	// CodexEnvironment is the otel.environment label of Codex sessions,
+	// where "{profile}" stands for the Codex profile (empty = "prod")
+	CodexEnvironment string `json:"codex_environment,omitempty"`
+	// CodexProfiles are the Codex profiles whose sessions get telemetry
+	// (empty = all; "default" = no profile)
+	CodexProfiles []string `json:"codex_profiles,omitempty"`
+	// CodexExporters is what setup does with Codex exporters that send
+	// elsewhere: ask, replace, keep or alongside (empty = ask)
+	CodexExporters string `json:"codex_exporters,omitempty"`
+	// ClaudeMode is where Claude Code gets its telemetry environment:
+	// wrapper or settings (empty = wrapper)
+	ClaudeMode string `json:"claude_mode,omitempty"`
+	// Shells get the alias block in their rc file (empty = the detected one)
+	Shells []string `json:"shells,omitempty"`
+	// EnvPolicy maps an environment variable to override, merge or keep
+	EnvPolicy map[string]string `json:"env_policy,omitempty"`
+	// Profiles hold credentials for other JTPCK accounts; the fields
+	// above form the default profile
+	Profiles      map[string]Profile `json:"profiles,omitempty"`
+	ActiveProfile string             `json:"active_profile,omitempty"`
+	// Directories maps a directory (and its subdirectories) to a profile
+	Directories map[string]string `json:"directories,omitempty"`
+	// SecretBackend stores the credentials: file, age or secret-service
+	SecretBackend string `json:"secret_backend,omitempty"`
+	// SecretKeyFile is the age identity unlocking the credentials; without
+	// it the age backend asks for a passphrase
+	SecretKeyFile string    `json:"secret_key_file,omitempty"`
+	Created       time.Time `json:"created_at"`
+	Updated       time.Time `json:"updated_at"`
 
-// ConfigPath returns the path to the config file
-func ConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".jtpck", "config.json")
-}
-
-// ConfigDir returns the path to the .jtpck directory
-func ConfigDir() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".jtpck")
+	// loadedVersion is the schema version config.json had before migrating
+	loadedVersion int
+	// storedCredentials is what the secret store held at load, so saving
+	// unchanged credentials does not re-encrypt (and re-prompt)
+	storedCredentials Credentials
+	// resolved marks the effective config built by Resolve, which mixes in
+	// other layers and must never be saved over config.json
+	resolved bool
 }
 
 // Exists checks if config file exists
@@ -40,40 +87,132 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	cfg, migrated, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := cfg.SecretStore()
+	if err != nil {
 		return nil, err
 	}
+	creds, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	cfg.storedCredentials = creds
+
+	// User IDs a migration took out of config.json only fill gaps
+	cfg.applyCredentials(migrated)
+	cfg.applyCredentials(creds)
+
+	return cfg, nil
+}
+
+// applyCredentials fills in user IDs from the secret store
+func (c *Config) applyCredentials(creds Credentials) {
+	if token, ok := creds[DefaultProfile]; ok {
+		c.UserID = token
+	}
+
+	for name, p := range c.Profiles {
+		if token, ok := creds[name]; ok {
+			p.UserID = token
+			c.Profiles[name] = p
+		}
+	}
+}
+
+// LoadedVersion returns the schema version config.json had on disk
+func (c *Config) LoadedVersion() int {
+	return c.loadedVersion
+}
+
+// NeedsMigration reports whether config.json predates CurrentVersion, so
+// Save would rewrite it and the files generated from it may be stale
+func (c *Config) NeedsMigration() bool {
+	return c.loadedVersion < CurrentVersion
+}
 
-	return &cfg, nil
+// credentials returns the user ID of every profile
+func (c *Config) credentials() Credentials {
+	creds := Credentials{DefaultProfile: c.UserID}
+	for name, p := range c.Profiles {
+		creds[name] = p.UserID
+	}
+	return creds
 }
 
-// Save writes config to disk
+// withoutCredentials returns a copy of the config with user IDs removed
+func (c *Config) withoutCredentials() *Config {
+	stripped := *c
+	stripped.UserID = ""
+	if c.Profiles != nil {
+		stripped.Profiles = make(map[string]Profile, len(c.Profiles))
+		for name, p := range c.Profiles {
+			p.UserID = ""
+			stripped.Profiles[name] = p
+		}
+	}
+	return &stripped
+}
+
+// Save writes config to disk, with user IDs going to the credentials file
 func (c *Config) Save() error {
-	// Ensure directory exists
-	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+	if c.resolved {
+		return fmt.Errorf("cannot save a resolved config; save the user config instead")
+	}
+
+	// Ensure directory exists; it holds credentials, so keep it private
+	if err := os.MkdirAll(ConfigDir(), 0700); err != nil {
+		return err
+	}
+	if err := os.Chmod(ConfigDir(), 0700); err != nil {
 		return err
 	}
 
+	c.Version = CurrentVersion
 	c.Updated = time.Now()
 	if c.Created.IsZero() {
 		c.Created = time.Now()
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	if creds := c.credentials(); c.storedCredentials == nil || !creds.equal(c.storedCredentials) {
+		store, err := c.SecretStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Save(creds); err != nil {
+			return fmt.Errorf("saving credentials: %w", err)
+		}
+		c.storedCredentials = creds
+	}
+
+	data, err := json.MarshalIndent(c.withoutCredentials(), "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(ConfigPath(), data, 0644)
+	if err := WritePrivateFile(ConfigPath(), data); err != nil {
+		return err
+	}
+	c.loadedVersion = CurrentVersion
+	return nil
 }
 
 // New creates a new config instance
 func New(userID, endpoint string) *Config {
 	return &Config{
-		UserID:   userID,
-		Endpoint: endpoint,
-		Created:  time.Now(),
-		Updated:  time.Now(),
+		Version:       CurrentVersion,
+		loadedVersion: CurrentVersion,
+		UserID:        userID,
+		Endpoint:      endpoint,
+		Created:       time.Now(),
+		Updated:       time.Now(),
 	}
 }
+
+// Supervised reports whether wrappers report each session
+func (c *Config) Supervised() bool {
+	return isTrue(c.Supervise)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Credentials maps profile names to user IDs. The user ID is a bearer token,
+// so it is kept in a SecretStore rather than in config.json.
+type Credentials map[string]string
+
+// CredentialsPath returns the path to the plaintext credentials file.
+// It uses a plain name=token format so the wrapper fallback can read it
+// with awk when the jtpck binary is unavailable.
+func CredentialsPath() string {
+	return filepath.Join(ConfigDir(), "credentials")
+}
+
+// parseCredentials reads the name=token format
+func parseCredentials(data []byte) (Credentials, error) {
+	creds := Credentials{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, token, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		creds[strings.TrimSpace(name)] = strings.TrimSpace(token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+// marshal renders the name=token format, skipping empty tokens
+func (c Credentials) marshal() []byte {
+	names := make([]string, 0, len(c))
+	for name, token := range c {
+		if token != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("# JTPCK credentials: user IDs are bearer tokens, keep this file private\n")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", name, c[name]))
+	}
+	return []byte(sb.String())
+}
+
+// equal reports whether two credential sets hold the same non-empty tokens
+func (c Credentials) equal(other Credentials) bool {
+	return bytes.Equal(c.marshal(), other.marshal())
+}
+
+// WritePrivateFile writes data readable only by the owner. Unlike
+// os.WriteFile it also tightens the mode of a file that already exists.
+func WritePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCredentials(t *testing.T) {
+	data := []byte(`# JTPCK credentials
+default=abc
+
+  acme = def
+broken line
+token=with=equals
+empty=
+`)
+	creds, err := parseCredentials(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{DefaultProfile: "abc", "acme": "def", "token": "with=equals", "empty": ""}
+	if !reflect.DeepEqual(creds, want) {
+		t.Errorf("parseCredentials() = %v, want %v", creds, want)
+	}
+}
+
+func TestCredentialsMarshal(t *testing.T) {
+	creds := Credentials{"zeta": "z", DefaultProfile: "abc", "empty": ""}
+	want := "# JTPCK credentials: user IDs are bearer tokens, keep this file private\ndefault=abc\nzeta=z\n"
+	if got := string(creds.marshal()); got != want {
+		t.Errorf("marshal() = %q, want %q", got, want)
+	}
+
+	parsed, _ := parseCredentials(creds.marshal())
+	if !parsed.equal(creds) {
+		t.Errorf("round trip gave %v", parsed)
+	}
+	if parsed.equal(Credentials{DefaultProfile: "abc"}) {
+		t.Error("credentials missing a profile compare equal")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jtpck", "credentials")
+	store := fileStore{path: path}
+
+	if creds, err := store.Load(); err != nil || len(creds) != 0 {
+		t.Errorf("Load() with no file = %v, %v", creds, err)
+	}
+	// An existing file too open is tightened on save
+	os.MkdirAll(filepath.Dir(path), 0700)
+	os.WriteFile(path, nil, 0644)
+	if err := store.Save(Credentials{DefaultProfile: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("credentials mode = %04o, want 0600", info.Mode().Perm())
+	}
+	if creds, err := store.Load(); err != nil || creds[DefaultProfile] != "abc" {
+		t.Errorf("Load() = %v, %v", creds, err)
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(); err != nil {
+		t.Errorf("deleting twice: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvPolicy controls how a JTPCK variable combines with one already set
+// in the user's environment (e.g. by a platform team's own collector setup).
+type EnvPolicy string
+
+const (
+	// PolicyOverride replaces any existing value
+	PolicyOverride EnvPolicy = "override"
+	// PolicyMerge joins comma-separated lists, warning when both sides set
+	// the same entry; for scalar variables ours wins with a warning
+	PolicyMerge EnvPolicy = "merge"
+	// PolicyKeep leaves an existing value untouched
+	PolicyKeep EnvPolicy = "keep"
+)
+
+// listEnvKeys hold comma-separated key=value lists that can be merged
+var listEnvKeys = map[string]bool{
+	"OTEL_EXPORTER_OTLP_HEADERS": true,
+	"OTEL_RESOURCE_ATTRIBUTES":   true,
+}
+
+// defaultEnvPolicies apply when config.json does not set a policy for a key.
+// Anything not listed is overridden, as the wrappers always did. Headers are
+// not merged by default: they hold the credentials of another collector,
+// which would be sent to JTPCK's endpoint.
+var defaultEnvPolicies = map[string]EnvPolicy{
+	"OTEL_RESOURCE_ATTRIBUTES": PolicyMerge,
+}
+
+// ParseEnvPolicy validates a policy name
+func ParseEnvPolicy(s string) (EnvPolicy, error) {
+	switch p := EnvPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyOverride, PolicyMerge, PolicyKeep:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid env policy %q (want override, merge or keep)", s)
+}
+
+// EnvPolicyFor returns the policy for an environment variable
+func (c *Config) EnvPolicyFor(key string) EnvPolicy {
+	if c != nil {
+		if p, err := ParseEnvPolicy(c.EnvPolicy[key]); err == nil {
+			return p
+		}
+	}
+	if p, ok := defaultEnvPolicies[key]; ok {
+		return p
+	}
+	return PolicyOverride
+}
+
+// IsListEnv reports whether a variable holds a mergeable key=value list
+func IsListEnv(key string) bool {
+	return listEnvKeys[key]
+}
+
+// MergeEnv combines the JTPCK environment with the current one according to
+// each key's policy. It returns the values to set and any conflicts found.
+func MergeEnv(lookup func(string) (string, bool), desired map[string]string, policyFor func(string) EnvPolicy) (map[string]string, []string) {
+	merged := make(map[string]string, len(desired))
+	var warnings []string
+
+	for key, value := range desired {
+		existing, ok := lookup(key)
+		if !ok || existing == "" || existing == value {
+			merged[key] = value
+			continue
+		}
+
+		switch policyFor(key) {
+		case PolicyKeep:
+			// Leave the existing value in place
+		case PolicyOverride:
+			merged[key] = value
+		case PolicyMerge:
+			if IsListEnv(key) {
+				list, conflicts := mergeList(existing, value)
+				merged[key] = list
+				for _, name := range conflicts {
+					warnings = append(warnings, fmt.Sprintf("%s: existing %q replaced by JTPCK value", key, name))
+				}
+			} else {
+				merged[key] = value
+				warnings = append(warnings, fmt.Sprintf("%s: existing value %q replaced by %q", key, existing, value))
+			}
+		}
+	}
+
+	return merged, warnings
+}
+
+// mergeList joins two comma-separated key=value lists. Existing entries keep
+// their position; entries with the same key take our value and are reported.
+func mergeList(existing, ours string) (string, []string) {
+	type entry struct{ key, raw string }
+	parse := func(s string) []entry {
+		var entries []entry
+		for _, item := range strings.Split(s, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			key, _, _ := strings.Cut(item, "=")
+			entries = append(entries, entry{strings.TrimSpace(key), item})
+		}
+		return entries
+	}
+
+	result := parse(existing)
+	var conflicts []string
+	for _, e := range parse(ours) {
+		replaced := false
+		for i := range result {
+			if result[i].key == e.key {
+				if result[i].raw != e.raw {
+					conflicts = append(conflicts, e.key)
+				}
+				result[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, e)
+		}
+	}
+
+	items := make([]string, len(result))
+	for i, e := range result {
+		items[i] = e.raw
+	}
+	return strings.Join(items, ","), conflicts
+}
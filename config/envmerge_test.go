@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvPolicyDefaults(t *testing.T) {
+	tests := map[string]EnvPolicy{
+		"OTEL_RESOURCE_ATTRIBUTES":    PolicyMerge,
+		"OTEL_EXPORTER_OTLP_HEADERS":  PolicyOverride,
+		"OTEL_SERVICE_NAME":           PolicyOverride,
+		"OTEL_EXPORTER_OTLP_ENDPOINT": PolicyOverride,
+	}
+	for key, want := range tests {
+		if got := (*Config)(nil).EnvPolicyFor(key); got != want {
+			t.Errorf("default policy for %s = %s, want %s", key, got, want)
+		}
+	}
+
+	cfg := &Config{EnvPolicy: map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "merge", "OTEL_SERVICE_NAME": "bogus"}}
+	if got := cfg.EnvPolicyFor("OTEL_EXPORTER_OTLP_HEADERS"); got != PolicyMerge {
+		t.Errorf("configured policy = %s", got)
+	}
+	if got := cfg.EnvPolicyFor("OTEL_SERVICE_NAME"); got != PolicyOverride {
+		t.Errorf("an invalid policy gave %s, want the default", got)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	existing := map[string]string{
+		"OTEL_RESOURCE_ATTRIBUTES":   "team=infra,user.private_uuid=old",
+		"OTEL_EXPORTER_OTLP_HEADERS": "x-api-key=theirs",
+		"OTEL_SERVICE_NAME":          "mine",
+		"OTEL_METRICS_EXPORTER":      "prometheus",
+		"OTEL_LOGS_EXPORTER":         "otlp",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := existing[key]
+		return value, ok
+	}
+	desired := map[string]string{
+		"OTEL_RESOURCE_ATTRIBUTES":     "user.private_uuid=abc",
+		"OTEL_EXPORTER_OTLP_HEADERS":   "Authorization=Bearer abc",
+		"OTEL_SERVICE_NAME":            "claude-code",
+		"OTEL_METRICS_EXPORTER":        "otlp",
+		"OTEL_LOGS_EXPORTER":           "otlp",
+		"CLAUDE_CODE_ENABLE_TELEMETRY": "1",
+	}
+	policies := map[string]EnvPolicy{"OTEL_SERVICE_NAME": PolicyMerge, "OTEL_METRICS_EXPORTER": PolicyKeep}
+	policyFor := func(key string) EnvPolicy {
+		if p, ok := policies[key]; ok {
+			return p
+		}
+		return (*Config)(nil).EnvPolicyFor(key)
+	}
+
+	merged, warnings := MergeEnv(lookup, desired, policyFor)
+	want := map[string]string{
+		"OTEL_RESOURCE_ATTRIBUTES":     "team=infra,user.private_uuid=abc",
+		"OTEL_EXPORTER_OTLP_HEADERS":   "Authorization=Bearer abc",
+		"OTEL_SERVICE_NAME":            "claude-code",
+		"OTEL_LOGS_EXPORTER":           "otlp",
+		"CLAUDE_CODE_ENABLE_TELEMETRY": "1",
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("merged = %v\nwant %v", merged, want)
+	}
+	// One for the replaced resource attribute, one for the scalar merge
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestMergeList(t *testing.T) {
+	tests := []struct {
+		existing, ours, want string
+		conflicts            []string
+	}{
+		{"a=1, b=2", "c=3", "a=1,b=2,c=3", nil},
+		{"a=1,b=2", "b=3,a=1", "a=1,b=3", []string{"b"}},
+		{",a=1,,", "a=1", "a=1", nil},
+		{"", "a=1", "a=1", nil},
+	}
+	for _, tt := range tests {
+		got, conflicts := mergeList(tt.existing, tt.ours)
+		if got != tt.want || !reflect.DeepEqual(conflicts, tt.conflicts) {
+			t.Errorf("mergeList(%q, %q) = %q, %v; want %q, %v", tt.existing, tt.ours, got, conflicts, tt.want, tt.conflicts)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/tailscale/hujson"
 )
 
+// geminiTelemetryKeys are the settings EnableGeminiTelemetry writes under
+// "telemetry"
+var geminiTelemetryKeys = []string{"enabled", "target", "otlpEndpoint", "otlpProtocol", "useCollector", "logPrompts"}
+
 // GeminiEnv defines the telemetry environment variables for the Gemini CLI.
 func GeminiEnv(userID, endpoint string) map[string]string {
 	return map[string]string{
@@ -22,7 +30,7 @@ func GeminiEnv(userID, endpoint string) map[string]string {
 
 // GeminiSettingsDir returns the path to the .gemini directory.
 func GeminiSettingsDir() string {
-	home, _ := os.UserHomeDir()
+	home := HomeDir()
 	return filepath.Join(home, ".gemini")
 }
 
@@ -32,7 +40,7 @@ func GeminiSettingsPath() string {
 }
 
 // EnableGeminiTelemetry writes telemetry settings for the Gemini CLI.
-func EnableGeminiTelemetry(userID, endpoint string, demoMode bool, logger func(string)) error {
+func EnableGeminiTelemetry(userID, endpoint string, telemetrySettings TelemetrySettings, demoMode bool, logger func(string)) error {
 	if logger != nil {
 		logger("Enabling Gemini CLI telemetry")
 	}
@@ -41,44 +49,357 @@ func EnableGeminiTelemetry(userID, endpoint string, demoMode bool, logger func(s
 		return nil
 	}
 
-	if err := os.MkdirAll(GeminiSettingsDir(), 0755); err != nil {
+	if err := os.MkdirAll(GeminiSettingsDir(), 0700); err != nil {
 		return fmt.Errorf("creating Gemini settings directory: %w", err)
 	}
 
-	settings := map[string]interface{}{}
-
+	// Edit the file in place, keeping the user's comments and layout
 	data, err := os.ReadFile(GeminiSettingsPath())
-	if err == nil && len(data) > 0 {
-		if err := json.Unmarshal(data, &settings); err != nil {
-			return fmt.Errorf("parsing existing Gemini settings: %w", err)
-		}
-	} else if err != nil && !os.IsNotExist(err) {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("reading Gemini settings: %w", err)
 	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}\n")
+	}
+	root, err := hujson.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing existing Gemini settings: %w", err)
+	}
+	settings, ok := jsonObject(&root)
+	if !ok {
+		return fmt.Errorf("parsing existing Gemini settings: %s is not a JSON object", GeminiSettingsPath())
+	}
 
-	var telemetry map[string]interface{}
-	if existing, ok := settings["telemetry"].(map[string]interface{}); ok {
-		telemetry = existing
-	} else {
-		telemetry = map[string]interface{}{}
+	// Remember the user's own values the first time, so uninstall can put
+	// them back
+	record, err := LoadGeminiSettingsRecord()
+	if err != nil {
+		return err
+	}
+	if record == nil || record.Path != GeminiSettingsPath() {
+		record = &GeminiSettingsRecord{Path: GeminiSettingsPath(), Original: geminiOwnedOriginal(&root, endpoint)}
 	}
 
-	telemetry["enabled"] = true
-	telemetry["target"] = "local"
-	telemetry["otlpEndpoint"] = endpoint
-	telemetry["otlpProtocol"] = "http"
-	telemetry["useCollector"] = true
+	telemetry, childLead := childJSONObject(settings, "telemetry")
+	for _, kv := range geminiTelemetryValues(endpoint, telemetrySettings) {
+		setJSONMember(telemetry, kv.key, kv.value, childLead)
+	}
 
-	settings["telemetry"] = telemetry
+	output := root.Pack()
+	if _, err := hujson.Parse(output); err != nil || (json.Valid(data) && !json.Valid(output)) {
+		return fmt.Errorf("updating Gemini settings would leave %s invalid, edit \"telemetry\" by hand", GeminiSettingsPath())
+	}
+	if err := WritePrivateFile(GeminiSettingsPath(), output); err != nil {
+		return fmt.Errorf("writing Gemini settings: %w", err)
+	}
+	if err := saveGeminiSettingsRecord(record); err != nil {
+		return fmt.Errorf("recording Gemini settings: %w", err)
+	}
+
+	// settings.json has no place for the exporter's headers, so Gemini
+	// started without the wrapper reads them from ~/.gemini/.env
+	if err := writeGeminiEnv(GeminiEnvPath(), userID); err != nil {
+		return fmt.Errorf("writing %s: %w", GeminiEnvPath(), err)
+	}
 
-	output, err := json.MarshalIndent(settings, "", "  ")
+	return nil
+}
+
+// geminiTelemetryValues are the telemetry settings JTPCK writes, in
+// geminiTelemetryKeys order
+func geminiTelemetryValues(endpoint string, settings TelemetrySettings) []struct {
+	key   string
+	value hujson.Literal
+} {
+	return []struct {
+		key   string
+		value hujson.Literal
+	}{
+		{"enabled", hujson.Bool(true)},
+		{"target", hujson.String("local")},
+		{"otlpEndpoint", hujson.String(endpoint)},
+		{"otlpProtocol", hujson.String("http")},
+		{"useCollector", hujson.Bool(true)},
+		{"logPrompts", hujson.Bool(settings.LogPrompts)},
+	}
+}
+
+// geminiOwnedOriginal returns the user's telemetry settings JTPCK is about
+// to replace in a file it has no record for. A file already exporting to a
+// JTPCK endpoint was written by JTPCK before edits were recorded, so none
+// of its values are the user's.
+func geminiOwnedOriginal(root *hujson.Value, endpoint string) map[string]json.RawMessage {
+	original := map[string]json.RawMessage{}
+	for _, key := range geminiTelemetryKeys {
+		v := root.Find("/telemetry/" + jsonPointerEscape(key))
+		if v == nil {
+			continue
+		}
+		value := v.Clone()
+		value.Standardize()
+		value.Minimize()
+		original[key] = value.Pack()
+	}
+	if jtpckGeminiEndpoint(original["otlpEndpoint"], endpoint) {
+		return map[string]json.RawMessage{}
+	}
+	return original
+}
+
+// jtpckGeminiEndpoint reports whether an otlpEndpoint value sends to endpoint
+// or to the default JTPCK endpoint
+func jtpckGeminiEndpoint(raw []byte, endpoint string) bool {
+	var value string
+	if json.Unmarshal(raw, &value) != nil {
+		return false
+	}
+	value = strings.TrimRight(value, "/")
+	return value == strings.TrimRight(endpoint, "/") || value == DefaultEndpoint
+}
+
+// GeminiSettingsRecord remembers the telemetry settings JTPCK replaced in
+// the Gemini settings file, so uninstall can put them back
+type GeminiSettingsRecord struct {
+	// Path is the settings file written
+	Path string `json:"path"`
+	// Original holds the replaced values as JSON; a key missing from it
+	// was not set
+	Original map[string]json.RawMessage `json:"original,omitempty"`
+}
+
+// GeminiSettingsRecordPath returns the path to the record of the Gemini
+// settings edit
+func GeminiSettingsRecordPath() string {
+	return filepath.Join(StateDir(), "gemini-settings.json")
+}
+
+// LoadGeminiSettingsRecord reads the record of the Gemini settings edit, or
+// returns nil when JTPCK has not edited settings.json since recording began
+func LoadGeminiSettingsRecord() (*GeminiSettingsRecord, error) {
+	data, err := os.ReadFile(GeminiSettingsRecordPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("encoding Gemini settings: %w", err)
+		return nil, err
+	}
+	var record GeminiSettingsRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", GeminiSettingsRecordPath(), err)
 	}
+	return &record, nil
+}
 
-	if err := os.WriteFile(GeminiSettingsPath(), output, 0644); err != nil {
-		return fmt.Errorf("writing Gemini settings: %w", err)
+func saveGeminiSettingsRecord(record *GeminiSettingsRecord) error {
+	if err := os.MkdirAll(StateDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
 	}
+	return WritePrivateFile(GeminiSettingsRecordPath(), append(data, '\n'))
+}
 
+// RemoveGeminiTelemetry puts back the telemetry settings JTPCK replaced in
+// a Gemini settings file and deletes the ones it added, and the
+// "telemetry" object when nothing else is left in it. The rest of the file
+// keeps its order, indentation and comments. It refuses to write a file
+// that would no longer parse. The JTPCK block of the .env file next to it
+// goes too. record is what LoadGeminiSettingsRecord returned; without one
+// every telemetry key JTPCK writes is deleted.
+func RemoveGeminiTelemetry(settingsPath string, record *GeminiSettingsRecord) error {
+	envPath := filepath.Join(filepath.Dir(settingsPath), ".env")
+	if err := removeGeminiEnv(envPath); err != nil {
+		return fmt.Errorf("updating %s: %w", envPath, err)
+	}
+	var original map[string]json.RawMessage
+	if record != nil && record.Path == settingsPath {
+		original = record.Original
+	}
+	if _, err := os.Stat(settingsPath); err == nil {
+		if err := removeGeminiTelemetryKeys(settingsPath, geminiTelemetryKeys, original, WritePrivateFile); err != nil {
+			return err
+		}
+	}
+	if record != nil && record.Path == settingsPath {
+		if err := os.Remove(GeminiSettingsRecordPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
+
+// removeGeminiTelemetryKeys deletes keys from the "telemetry" object of a
+// settings file, or gives them their original value, and deletes the
+// object when it is left empty
+func removeGeminiTelemetryKeys(settingsPath string, keys []string, original map[string]json.RawMessage, write func(string, []byte) error) error {
+	data, err := os.ReadFile(settingsPath)
+	if err != nil {
+		return err
+	}
+	root, err := hujson.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s (remove \"telemetry\" by hand): %w", settingsPath, err)
+	}
+	telemetry, ok := jsonObject(root.Find("/telemetry"))
+	if !ok {
+		return nil
+	}
+
+	settings, _ := jsonObject(&root)
+	_, lead := childJSONObject(settings, "telemetry")
+	var removed int
+	for _, key := range keys {
+		if raw, ok := original[key]; ok {
+			value, err := hujson.Parse(raw)
+			if err != nil {
+				return fmt.Errorf("restoring telemetry.%s: %w", key, err)
+			}
+			setJSONMember(telemetry, key, value.Value, lead)
+			removed++
+			continue
+		}
+		if root.Find("/telemetry/"+jsonPointerEscape(key)) == nil {
+			continue
+		}
+		if err := removeJSONMember(&root, "/telemetry", key); err != nil {
+			return fmt.Errorf("updating %s: %w", settingsPath, err)
+		}
+		removed++
+	}
+	if len(telemetry.Members) == 0 {
+		if err := removeJSONMember(&root, "", "telemetry"); err != nil {
+			return fmt.Errorf("updating %s: %w", settingsPath, err)
+		}
+	} else if removed == 0 {
+		return nil
+	}
+
+	output := root.Pack()
+	if _, err := hujson.Parse(output); err != nil || (json.Valid(data) && !json.Valid(output)) {
+		return fmt.Errorf("removing telemetry would leave %s invalid, edit it by hand", settingsPath)
+	}
+	return write(settingsPath, output)
+}
+
+// childJSONObject returns the object member name of parent, adding an
+// empty one laid out like its neighbours when there is none, and the lead
+// for the child's own members
+func childJSONObject(parent *hujson.Object, name string) (*hujson.Object, hujson.Extra) {
+	lead, unit := hujson.Extra("\n  "), "  "
+	if len(parent.Members) > 0 {
+		lead = memberLead(parent.Members[len(parent.Members)-1].Name.BeforeExtra)
+		unit = strings.TrimLeft(string(lead), "\n")
+	} else if !bytes.Contains(parent.AfterExtra, []byte("\n")) {
+		parent.AfterExtra = hujson.Extra("\n")
+	}
+	var child *hujson.Object
+	for i, member := range parent.Members {
+		if member.Name.Value.(hujson.Literal).String() == name {
+			child, _ = jsonObject(&parent.Members[i].Value)
+		}
+	}
+	if child == nil {
+		child = &hujson.Object{AfterExtra: lead}
+		if !bytes.Contains(lead, []byte("\n")) {
+			child.AfterExtra = nil
+		}
+		setJSONMember(parent, name, child, lead)
+	}
+	if bytes.Contains(lead, []byte("\n")) {
+		lead = append(append(hujson.Extra{}, lead...), unit...)
+	}
+	return child, lead
+}
+
+// jsonObject returns the object a value holds
+func jsonObject(v *hujson.Value) (*hujson.Object, bool) {
+	if v == nil {
+		return nil, false
+	}
+	obj, ok := v.Value.(*hujson.Object)
+	return obj, ok
+}
+
+// removeJSONMember deletes a member from the object at parent (a JSON
+// pointer), keeping the closing brace where it was
+func removeJSONMember(root *hujson.Value, parent, name string) error {
+	obj, ok := jsonObject(root.Find(parent))
+	if !ok {
+		return fmt.Errorf("%s is not an object", parent)
+	}
+	closing := obj.AfterExtra
+	var first hujson.Extra
+	if len(obj.Members) > 1 && obj.Members[0].Name.Value.(hujson.Literal).String() == name {
+		first = memberLead(obj.Members[0].Name.BeforeExtra)
+	}
+
+	patch, err := json.Marshal([]map[string]string{{"op": "remove", "path": parent + "/" + jsonPointerEscape(name)}})
+	if err != nil {
+		return err
+	}
+	if err := root.Patch(patch); err != nil {
+		return err
+	}
+
+	// Removing the last member moves its leading space and comments before
+	// the closing brace; indent the brace as before
+	if !bytes.Equal(obj.AfterExtra, closing) {
+		i, j := bytes.LastIndexByte(obj.AfterExtra, '\n'), bytes.LastIndexByte(closing, '\n')
+		if i >= 0 && j >= 0 {
+			obj.AfterExtra = append(obj.AfterExtra[:i:i], closing[j:]...)
+		}
+	}
+	// The new first member goes where the removed one was
+	if first != nil && len(bytes.TrimSpace(obj.Members[0].Name.BeforeExtra)) == 0 {
+		obj.Members[0].Name.BeforeExtra = first
+	}
+	return nil
+}
+
+// setJSONMember gives the member name of obj a value: in place when it
+// exists, otherwise as a new last member laid out like the one before it.
+// lead is what goes before the name when obj has no members yet.
+func setJSONMember(obj *hujson.Object, name string, value hujson.ValueTrimmed, lead hujson.Extra) {
+	for i, member := range obj.Members {
+		if member.Name.Value.(hujson.Literal).String() == name {
+			obj.Members[i].Value.Value = value
+			return
+		}
+	}
+
+	sep, trailing := hujson.Extra(" "), hujson.Extra(nil)
+	if len(lead) == 0 {
+		sep = nil
+	}
+	if n := len(obj.Members); n > 0 {
+		last := &obj.Members[n-1]
+		lead = memberLead(last.Name.BeforeExtra)
+		if extra := bytes.TrimSpace(last.Value.BeforeExtra); len(extra) == 0 {
+			sep = append(hujson.Extra{}, last.Value.BeforeExtra...)
+		}
+		// A trailing comma stays after the last member
+		trailing, last.Value.AfterExtra = last.Value.AfterExtra, nil
+	}
+	obj.Members = append(obj.Members, hujson.ObjectMember{
+		Name:  hujson.Value{BeforeExtra: append(hujson.Extra{}, lead...), Value: hujson.String(name)},
+		Value: hujson.Value{BeforeExtra: sep, Value: value, AfterExtra: trailing},
+	})
+}
+
+// memberLead returns the whitespace before a member's name without the
+// comments describing it
+func memberLead(extra hujson.Extra) hujson.Extra {
+	if i := bytes.LastIndexByte(extra, '\n'); i >= 0 {
+		return append(hujson.Extra{'\n'}, extra[i+1:]...)
+	}
+	if len(bytes.TrimSpace(extra)) > 0 {
+		return nil
+	}
+	return append(hujson.Extra{}, extra...)
+}
+
+func jsonPointerEscape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,335 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemoveGeminiTelemetry(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{
+			name: "whole object",
+			input: `{
+  // UI
+  "theme": "dark",
+  "telemetry": {
+    "enabled": true,
+    "target": "local",
+    "otlpEndpoint": "https://x",
+    "otlpProtocol": "http",
+    "useCollector": true,
+    "logPrompts": false
+  }
+}
+`,
+			want: `{
+  // UI
+  "theme": "dark"
+}
+`,
+		},
+		{
+			name: "user settings stay",
+			input: `{
+  "telemetry": {
+    "enabled": true,
+    "outfile": "/tmp/gemini.log"
+  },
+  "theme": "dark"
+}
+`,
+			want: `{
+  "telemetry": {
+    "outfile": "/tmp/gemini.log"
+  },
+  "theme": "dark"
+}
+`,
+		},
+		{
+			name:  "single line",
+			input: `{"note":"telemetry","telemetry":{"enabled":true},"hooks":{"telemetry":1}}`,
+			want:  `{"note":"telemetry","hooks":{"telemetry":1}}`,
+		},
+		{
+			name:  "nothing to remove",
+			input: "{\n  \"telemetry\": {\"outfile\": \"x\"}\n}\n",
+			want:  "{\n  \"telemetry\": {\"outfile\": \"x\"}\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		os.WriteFile(path, []byte(tt.input), 0600)
+		if err := RemoveGeminiTelemetry(path, nil); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got, _ := os.ReadFile(path); string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGeminiTelemetryRoundTrip(t *testing.T) {
+	useTempHome(t)
+	os.MkdirAll(GeminiSettingsDir(), 0700)
+	input := `{"telemetry": {"enabled": true, "target": "gcp", "logPrompts": false}}`
+	os.WriteFile(GeminiSettingsPath(), []byte(input), 0600)
+
+	// Enabling twice keeps the user's values, not JTPCK's
+	for i := 0; i < 2; i++ {
+		if err := EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{LogPrompts: true}, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if settings, _ := readJSONC(GeminiSettingsPath()); settings["telemetry"].(map[string]interface{})["target"] != "local" {
+		t.Errorf("enabled settings = %v", settings)
+	}
+	record, err := LoadGeminiSettingsRecord()
+	if err != nil || record == nil {
+		t.Fatalf("record = %+v, %v", record, err)
+	}
+	if err := RemoveGeminiTelemetry(GeminiSettingsPath(), record); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(GeminiSettingsPath()); string(got) != input {
+		t.Errorf("after uninstall:\n%s\nwant\n%s", got, input)
+	}
+	if record, _ := LoadGeminiSettingsRecord(); record != nil {
+		t.Errorf("record left behind: %+v", record)
+	}
+}
+
+func TestGeminiTelemetryAfterUnrecordedInstall(t *testing.T) {
+	useTempHome(t)
+	os.MkdirAll(GeminiSettingsDir(), 0700)
+	// settings.json as setup wrote it before edits were recorded
+	baseline := `{
+  "theme": "dark",
+  "telemetry": {
+    "enabled": true,
+    "target": "local",
+    "otlpEndpoint": "` + DefaultEndpoint + `",
+    "otlpProtocol": "http",
+    "useCollector": true,
+    "logPrompts": false
+  }
+}
+`
+	os.WriteFile(GeminiSettingsPath(), []byte(baseline), 0600)
+
+	// The upgrade refreshes it for another endpoint
+	if err := EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{LogPrompts: true}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, _ := LoadGeminiSettingsRecord()
+	if record == nil || len(record.Original) != 0 {
+		t.Fatalf("JTPCK's own settings recorded as the user's: %+v", record)
+	}
+	if err := RemoveGeminiTelemetry(GeminiSettingsPath(), record); err != nil {
+		t.Fatal(err)
+	}
+	if settings, _ := readJSONC(GeminiSettingsPath()); settings["telemetry"] != nil || settings["theme"] != "dark" {
+		t.Errorf("after uninstall: %v", settings)
+	}
+}
+
+func TestRemoveGeminiTelemetryInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	input := `{"telemetry": {"enabled": true,}`
+	os.WriteFile(path, []byte(input), 0600)
+	if err := RemoveGeminiTelemetry(path, nil); err == nil {
+		t.Error("accepted a file that does not parse")
+	}
+	if got, _ := os.ReadFile(path); string(got) != input {
+		t.Errorf("rewrote a file it could not parse: %s", got)
+	}
+}
+
+func TestEnableGeminiTelemetryKeepsLayout(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{
+			name:  "no file",
+			input: "",
+			want: `{
+  "telemetry": {
+    "enabled": true,
+    "target": "local",
+    "otlpEndpoint": "https://x",
+    "otlpProtocol": "http",
+    "useCollector": true,
+    "logPrompts": false
+  }
+}
+`,
+		},
+		{
+			name: "comments and trailing commas",
+			input: `{
+	// UI
+	"theme": "dark",
+	"telemetry": {
+		"outfile": "/tmp/gemini.log", // mine
+		"enabled": false,
+	},
+}
+`,
+			want: `{
+	// UI
+	"theme": "dark",
+	"telemetry": {
+		"outfile": "/tmp/gemini.log", // mine
+		"enabled": true,
+		"target": "local",
+		"otlpEndpoint": "https://x",
+		"otlpProtocol": "http",
+		"useCollector": true,
+		"logPrompts": false,
+	},
+}
+`,
+		},
+		{
+			name:  "single line",
+			input: `{"theme":"dark"}`,
+			want:  `{"theme":"dark","telemetry":{"enabled":true,"target":"local","otlpEndpoint":"https://x","otlpProtocol":"http","useCollector":true,"logPrompts":false}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		useTempHome(t)
+		os.MkdirAll(GeminiSettingsDir(), 0700)
+		if tt.input != "" {
+			os.WriteFile(GeminiSettingsPath(), []byte(tt.input), 0600)
+		}
+		if err := EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{}, false, nil); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got, _ := os.ReadFile(GeminiSettingsPath()); string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGeminiOverrides(t *testing.T) {
+	useTempHome(t)
+	system := filepath.Join(t.TempDir(), "settings.json")
+	old := geminiSystemSettingsPath
+	geminiSystemSettingsPath = system
+	t.Cleanup(func() { geminiSystemSettingsPath = old })
+	t.Setenv("GEMINI_CLI_SYSTEM_SETTINGS_PATH", "")
+
+	if err := EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	project := t.TempDir()
+	workspace := filepath.Join(project, ".gemini", "settings.json")
+	os.MkdirAll(filepath.Dir(workspace), 0755)
+	os.WriteFile(workspace, []byte("{\n  // quiet here\n  \"telemetry\": {\"enabled\": false, \"outfile\": \"x\"},\n}\n"), 0644)
+	os.WriteFile(system, []byte(`{"telemetry": {"otlpEndpoint": "https://corp", "target": "local"}}`), 0644)
+
+	effective, err := EffectiveGeminiTelemetry(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := effective["outfile"]; got.Value != "x" || got.Layer.Name != GeminiWorkspace {
+		t.Errorf("outfile = %+v", got)
+	}
+
+	overrides, err := GeminiOverrides(project, "https://x", TelemetrySettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range overrides {
+		got = append(got, o.Layer.Name+":"+o.Key)
+	}
+	if want := []string{"workspace:enabled", "system:otlpEndpoint"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("overrides = %v, want %v", got, want)
+	}
+	if overrides, _ := GeminiOverrides("", "https://x", TelemetrySettings{}); len(overrides) != 1 {
+		t.Errorf("without a workspace, overrides = %v", overrides)
+	}
+
+	if err := RemoveGeminiOverrides(overrides); err != nil {
+		t.Fatal(err)
+	}
+	if overrides, _ := GeminiOverrides(project, "https://x", TelemetrySettings{}); len(overrides) != 0 {
+		t.Errorf("after removing, overrides = %v", overrides)
+	}
+	if data, _ := os.ReadFile(workspace); string(data) != "{\n  // quiet here\n  \"telemetry\": {\"outfile\": \"x\"},\n}\n" {
+		t.Errorf("workspace settings = %q", data)
+	}
+}
+
+func TestGeminiEnvBlock(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "")
+	os.Unsetenv("OTEL_EXPORTER_OTLP_HEADERS")
+
+	os.MkdirAll(GeminiSettingsDir(), 0700)
+	original := "GEMINI_API_KEY=key"
+	os.WriteFile(GeminiEnvPath(), []byte(original), 0600)
+	for i := 0; i < 2; i++ {
+		if err := EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{}, false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(GeminiEnvPath())
+	if strings.Count(string(data), geminiEnvStart) != 1 || !strings.HasPrefix(string(data), original+"\n") {
+		t.Errorf(".env = %q", data)
+	}
+
+	project := t.TempDir()
+	if auth, err := CheckGeminiAuth(project, "abc"); err != nil || !auth.Authenticated || auth.Source != GeminiEnvPath() {
+		t.Errorf("auth = %+v, %v", auth, err)
+	}
+	// A project .env comes first and has no token
+	os.WriteFile(filepath.Join(project, ".env"), []byte("export OTEL_EXPORTER_OTLP_HEADERS='x-api-key=1'\n"), 0600)
+	sub := filepath.Join(project, "src")
+	os.MkdirAll(sub, 0755)
+	if auth, _ := CheckGeminiAuth(sub, "abc"); auth.Authenticated || auth.Source != filepath.Join(project, ".env") {
+		t.Errorf("shadowed auth = %+v", auth)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20abc")
+	if auth, _ := CheckGeminiAuth(sub, "abc"); !auth.Authenticated || auth.Source != "environment" {
+		t.Errorf("environment auth = %+v", auth)
+	}
+
+	record, _ := LoadGeminiSettingsRecord()
+	if err := RemoveGeminiTelemetry(GeminiSettingsPath(), record); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(GeminiEnvPath()); string(data) != original+"\n" {
+		t.Errorf(".env after removing = %q", data)
+	}
+	os.WriteFile(GeminiEnvPath(), nil, 0600)
+	EnableGeminiTelemetry("abc", "https://x", TelemetrySettings{}, false, nil)
+	record, _ = LoadGeminiSettingsRecord()
+	RemoveGeminiTelemetry(GeminiSettingsPath(), record)
+	if _, err := os.Stat(GeminiEnvPath()); !os.IsNotExist(err) {
+		t.Errorf(".env with only JTPCK's block survived: %v", err)
+	}
+}
+
+func TestPlaintextTokenFiles(t *testing.T) {
+	useTempHome(t)
+	cfg := New("abc", "")
+	cfg.Tools = []string{"claude", "gemini"}
+	if got := cfg.PlaintextTokenFiles(); !reflect.DeepEqual(got, []string{GeminiEnvPath()}) {
+		t.Errorf("plaintext token files = %v", got)
+	}
+	cfg.ClaudeMode = ClaudeModeSettings
+	if got := cfg.PlaintextTokenFiles(); !reflect.DeepEqual(got, []string{ClaudeSettingsPath(), GeminiEnvPath()}) {
+		t.Errorf("plaintext token files in settings mode = %v", got)
+	}
+}
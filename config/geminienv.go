@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The Gemini CLI loads the first .env file it finds; JTPCK's variables
+// are a marked block in ~/.gemini/.env
+const (
+	geminiEnvStart = "# JTPCK Telemetry - START"
+	geminiEnvEnd   = "# JTPCK Telemetry - END"
+)
+
+// GeminiEnvPath returns ~/.gemini/.env, which the Gemini CLI loads when no
+// .env file comes first on the way up from where it starts
+func GeminiEnvPath() string {
+	return filepath.Join(GeminiSettingsDir(), ".env")
+}
+
+// geminiAuthEnv returns the variables settings.json cannot hold: the
+// exporter's bearer header and the account attribute
+func geminiAuthEnv(userID string) map[string]string {
+	env := GeminiEnv(userID, "")
+	return map[string]string{
+		"OTEL_EXPORTER_OTLP_HEADERS": env["OTEL_EXPORTER_OTLP_HEADERS"],
+		"OTEL_RESOURCE_ATTRIBUTES":   env["OTEL_RESOURCE_ATTRIBUTES"],
+	}
+}
+
+// writeGeminiEnv replaces the JTPCK block of a .env file, keeping the
+// rest of the file
+func writeGeminiEnv(path, userID string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := stripGeminiEnvBlock(string(data))
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	env := geminiAuthEnv(userID)
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(content)
+	sb.WriteString(geminiEnvStart + "\n")
+	sb.WriteString("# Authenticates Gemini CLI telemetry when it is launched without the jtpck wrapper\n")
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%s=%q\n", key, env[key])
+	}
+	sb.WriteString(geminiEnvEnd + "\n")
+
+	// The block carries the bearer token
+	return WritePrivateFile(path, []byte(sb.String()))
+}
+
+// removeGeminiEnv deletes the JTPCK block from a .env file, and the file
+// when nothing else is left
+func removeGeminiEnv(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content := stripGeminiEnvBlock(string(data))
+	if content == string(data) {
+		return nil
+	}
+	if strings.TrimSpace(content) == "" {
+		return os.Remove(path)
+	}
+	return WritePrivateFile(path, []byte(content))
+}
+
+// stripGeminiEnvBlock removes the JTPCK block from .env content
+func stripGeminiEnvBlock(content string) string {
+	start := strings.Index(content, geminiEnvStart+"\n")
+	if start < 0 {
+		return content
+	}
+	end := strings.Index(content[start:], geminiEnvEnd+"\n")
+	if end < 0 {
+		return content[:start]
+	}
+	return content[:start] + content[start+end+len(geminiEnvEnd)+1:]
+}
+
+// FindGeminiEnvFile returns the .env file the Gemini CLI loads when started
+// in dir: .gemini/.env or .env in dir or the nearest parent that has one,
+// then ~/.gemini/.env and ~/.env. It returns "" when there is none.
+func FindGeminiEnvFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
+	for {
+		for _, path := range []string{filepath.Join(dir, ".gemini", ".env"), filepath.Join(dir, ".env")} {
+			if exists(path) {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, path := range []string{GeminiEnvPath(), filepath.Join(HomeDir(), ".env")} {
+		if exists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// readDotEnv returns the variables a .env file sets; later lines win
+func readDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		env[strings.TrimSpace(key)] = value
+	}
+	return env, scanner.Err()
+}
+
+// GeminiAuth describes how Gemini started in a directory without the
+// wrapper authenticates its telemetry
+type GeminiAuth struct {
+	// Source is the .env file or "environment" the header comes from, ""
+	// when there is none
+	Source string
+	// Authenticated is set when the header carries the user's token
+	Authenticated bool
+}
+
+// CheckGeminiAuth works out whether Gemini started in dir, outside the
+// wrapper, sends userID's token. Variables already in the environment win
+// over the .env file, as the Gemini CLI loads it.
+func CheckGeminiAuth(dir, userID string) (GeminiAuth, error) {
+	headers, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_HEADERS")
+	auth := GeminiAuth{Source: "environment"}
+	if !ok {
+		auth.Source = FindGeminiEnvFile(dir)
+		if auth.Source == "" {
+			return auth, nil
+		}
+		env, err := readDotEnv(auth.Source)
+		if err != nil {
+			return auth, err
+		}
+		headers = env["OTEL_EXPORTER_OTLP_HEADERS"]
+	}
+
+	for _, entry := range strings.Split(headers, ",") {
+		name, value, _ := strings.Cut(entry, "=")
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Authorization") && value == "Bearer "+userID {
+			auth.Authenticated = true
+		}
+	}
+	return auth, nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/tailscale/hujson"
+)
+
+// geminiSystemSettingsPath is where administrators put Gemini CLI settings
+// that override everyone's
+var geminiSystemSettingsPath = "/etc/gemini-cli/settings.json"
+
+// Gemini settings layers, in the order the Gemini CLI applies them
+const (
+	GeminiUser      = "user"
+	GeminiWorkspace = "workspace"
+	GeminiSystem    = "system"
+)
+
+// GeminiSystemSettingsPath returns the system settings file:
+// $GEMINI_CLI_SYSTEM_SETTINGS_PATH, as the Gemini CLI reads it, or
+// /etc/gemini-cli/settings.json
+func GeminiSystemSettingsPath() string {
+	if path := os.Getenv("GEMINI_CLI_SYSTEM_SETTINGS_PATH"); path != "" && !Targeted() {
+		return path
+	}
+	return OnHost(geminiSystemSettingsPath)
+}
+
+// GeminiWorkspaceSettingsPath returns the workspace settings file the
+// Gemini CLI reads when started in dir, or "" when it reads none there
+func GeminiWorkspaceSettingsPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, ".gemini", "settings.json")
+	if path == GeminiSettingsPath() {
+		// Started in the home directory, the user file is not read twice
+		return ""
+	}
+	return path
+}
+
+// GeminiLayer is one Gemini settings file
+type GeminiLayer struct {
+	Name string
+	Path string
+	// Telemetry is the file's "telemetry" object, nil when it has none
+	Telemetry map[string]interface{}
+}
+
+// GeminiLayers reads the Gemini settings files that apply in dir, in the
+// order the Gemini CLI applies them: user, workspace, then system, each
+// overriding the telemetry keys it sets. Missing files are left out; dir ""
+// skips the workspace.
+func GeminiLayers(dir string) ([]GeminiLayer, error) {
+	layers := []GeminiLayer{{Name: GeminiUser, Path: GeminiSettingsPath()}}
+	if dir != "" {
+		if path := GeminiWorkspaceSettingsPath(dir); path != "" {
+			layers = append(layers, GeminiLayer{Name: GeminiWorkspace, Path: path})
+		}
+	}
+	layers = append(layers, GeminiLayer{Name: GeminiSystem, Path: GeminiSystemSettingsPath()})
+
+	var found []GeminiLayer
+	for _, layer := range layers {
+		settings, err := readJSONC(layer.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		layer.Telemetry, _ = settings["telemetry"].(map[string]interface{})
+		found = append(found, layer)
+	}
+	return found, nil
+}
+
+// readJSONC reads a JSON file that may have comments and trailing commas
+func readJSONC(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if len(data) == 0 {
+		return settings, nil
+	}
+	standard, err := hujson.Standardize(data)
+	if err == nil {
+		err = json.Unmarshal(standard, &settings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// GeminiSetting is the effective value of a telemetry setting and the
+// file it comes from
+type GeminiSetting struct {
+	Key   string
+	Value interface{}
+	Layer GeminiLayer
+}
+
+// EffectiveGeminiTelemetry merges the "telemetry" objects of the settings
+// files that apply in dir, as the Gemini CLI does, by key
+func EffectiveGeminiTelemetry(dir string) (map[string]GeminiSetting, error) {
+	layers, err := GeminiLayers(dir)
+	if err != nil {
+		return nil, err
+	}
+	effective := map[string]GeminiSetting{}
+	for _, layer := range layers {
+		for key, value := range layer.Telemetry {
+			effective[key] = GeminiSetting{Key: key, Value: value, Layer: layer}
+		}
+	}
+	return effective, nil
+}
+
+// GeminiOverride is a telemetry setting JTPCK writes that another settings
+// file changes
+type GeminiOverride struct {
+	GeminiSetting
+	// Want is the value JTPCK writes to the user settings
+	Want interface{}
+}
+
+func (o GeminiOverride) String() string {
+	got, _ := json.Marshal(o.Value)
+	want, _ := json.Marshal(o.Want)
+	return fmt.Sprintf("telemetry.%s = %s in %s settings %s (JTPCK: %s)", o.Key, got, o.Layer.Name, o.Layer.Path, want)
+}
+
+// GeminiOverrides returns the telemetry settings JTPCK writes that a
+// workspace or system file in effect in dir sets to something else
+func GeminiOverrides(dir, endpoint string, settings TelemetrySettings) ([]GeminiOverride, error) {
+	effective, err := EffectiveGeminiTelemetry(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []GeminiOverride
+	for _, kv := range geminiTelemetryValues(endpoint, settings) {
+		setting, ok := effective[kv.key]
+		if !ok || setting.Layer.Name == GeminiUser {
+			continue
+		}
+		o := GeminiOverride{GeminiSetting: setting}
+		json.Unmarshal(kv.value, &o.Want)
+		if !reflect.DeepEqual(o.Value, o.Want) {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides, nil
+}
+
+// RemoveGeminiOverrides deletes overriding telemetry settings from the
+// files that set them, so JTPCK's user settings apply
+func RemoveGeminiOverrides(overrides []GeminiOverride) error {
+	keys := map[string][]string{}
+	var paths []string
+	for _, o := range overrides {
+		if keys[o.Layer.Path] == nil {
+			paths = append(paths, o.Layer.Path)
+		}
+		keys[o.Layer.Path] = append(keys[o.Layer.Path], o.Key)
+	}
+	// Other users read these files; an existing file keeps its mode
+	write := func(path string, data []byte) error { return os.WriteFile(path, data, 0644) }
+	for _, path := range paths {
+		if err := removeGeminiTelemetryKeys(path, keys[path], nil, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
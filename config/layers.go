@@ -0,0 +1,347 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layer names a source of settings. Later layers override earlier ones:
+// default < system < user < project < env < flag.
+type Layer string
+
+const (
+	LayerDefault Layer = "default"
+	LayerSystem  Layer = "system"
+	LayerUser    Layer = "user"
+	LayerProject Layer = "project"
+	LayerEnv     Layer = "env"
+	LayerFlag    Layer = "flag"
+)
+
+// ProjectConfigName is the project settings file, looked up from the
+// working directory towards the root
+const ProjectConfigName = ".jtpck.json"
+
+// envSettingPrefix starts the environment variable of each setting, e.g.
+// JTPCK_LOG_PROMPTS or JTPCK_ENV_POLICY_OTEL_SERVICE_NAME
+const envSettingPrefix = "JTPCK_"
+
+// systemConfigPath is where administrators put defaults and locks
+var systemConfigPath = "/etc/jtpck/config.json"
+
+// SystemConfigPath returns the path of the system-wide settings file
+func SystemConfigPath() string {
+	return OnHost(systemConfigPath)
+}
+
+// Origin is where the effective value of a setting came from
+type Origin struct {
+	Layer Layer
+	// Source is the file, environment variable or flag that set the value
+	Source string
+	// Locked values were pinned by the system config
+	Locked bool
+}
+
+func (o Origin) String() string {
+	s := string(o.Layer)
+	if o.Source != "" {
+		s += ":" + o.Source
+	}
+	if o.Locked {
+		s += " (locked)"
+	}
+	return s
+}
+
+// ResolveOptions selects the layers above the user config
+type ResolveOptions struct {
+	// Dir is where to look for a project file; empty skips project files,
+	// as for settings baked into wrappers and tool config files
+	Dir string
+	// Flags are KEY=VALUE settings given on the command line
+	Flags []string
+}
+
+// Resolved is the effective config along with the origin of every setting
+type Resolved struct {
+	// Config holds the effective values; Save refuses it
+	*Config
+	// Warnings describe values that were ignored
+	Warnings []string
+
+	origins map[string]Origin
+}
+
+// Origin returns where the effective value of a setting came from
+func (r *Resolved) Origin(key string) Origin {
+	if o, ok := r.origins[key]; ok {
+		return o
+	}
+	return Origin{Layer: LayerDefault}
+}
+
+// Locked reports whether the system config pins a setting
+func (r *Resolved) Locked(key string) bool {
+	return r.Origin(key).Locked
+}
+
+// Resolve layers the system config, project file, JTPCK_* environment
+// variables and command-line flags around the user's config.json and
+// returns the effective config. Profiles, directories and the secret
+// backend only come from config.json. Invalid values in files and the
+// environment are skipped with a warning; invalid flags are an error.
+func (c *Config) Resolve(opts ResolveOptions) (*Resolved, error) {
+	eff := *c
+	eff.resolved = true
+	eff.Profiles = maps.Clone(c.Profiles)
+	eff.Directories = maps.Clone(c.Directories)
+	eff.EnvPolicy = nil
+	for _, s := range settings {
+		s.unset(&eff)
+	}
+	r := &Resolved{Config: &eff, origins: map[string]Origin{}}
+
+	system, err := readLayerFile(SystemConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("reading system config: %w", err)
+	}
+	if system != nil {
+		r.Warnings = append(r.Warnings, system.warnings...)
+		r.apply(Origin{Layer: LayerSystem, Source: SystemConfigPath()}, system.values)
+		for _, key := range system.locked {
+			if _, err := LookupSetting(key); err != nil {
+				r.warn("%s: cannot lock %s: %v", SystemConfigPath(), key, err)
+				continue
+			}
+			o := r.Origin(key)
+			if o.Layer == LayerDefault {
+				o.Source = SystemConfigPath()
+			}
+			o.Locked = true
+			r.origins[key] = o
+		}
+	}
+
+	user := map[string]string{}
+	for _, key := range c.SettingKeys() {
+		if s, err := LookupSetting(key); err == nil && s.get(c) != "" {
+			user[key] = s.get(c)
+		}
+	}
+	r.apply(Origin{Layer: LayerUser, Source: ConfigPath()}, user)
+
+	if opts.Dir != "" {
+		if path := FindProjectConfig(opts.Dir); path != "" {
+			project, err := readLayerFile(path)
+			if err != nil {
+				r.warn("ignoring %s: %v", path, err)
+			} else {
+				r.Warnings = append(r.Warnings, project.warnings...)
+				if len(project.locked) > 0 {
+					r.warn("%s: locked is only honored in %s", path, SystemConfigPath())
+				}
+				r.apply(Origin{Layer: LayerProject, Source: path}, project.values)
+			}
+		}
+	}
+
+	r.apply(Origin{Layer: LayerEnv}, envSettings())
+
+	for _, flag := range opts.Flags {
+		key, value, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid setting %q (want KEY=VALUE)", flag)
+		}
+		key = strings.TrimSpace(key)
+		if err := r.set(Origin{Layer: LayerFlag, Source: "--setting " + key}, key, value); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// apply sets each value from one layer, warning about the ones it skips
+func (r *Resolved) apply(origin Origin, values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		o := origin
+		if o.Layer == LayerEnv {
+			o.Source = envSettingName(key)
+		}
+		if err := r.set(o, key, values[key]); err != nil {
+			r.warn("%s: %v", o.Source, err)
+		}
+	}
+}
+
+// set stores one layer's value for a key unless the key is locked or the
+// layer may not set it
+func (r *Resolved) set(origin Origin, key, value string) error {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+	if origin.Layer == LayerProject && !s.Project {
+		return s.checkProject(value)
+	}
+	if current := r.Origin(key); current.Locked {
+		return fmt.Errorf("%s is locked by %s", key, current.Source)
+	}
+
+	value, err = s.normalize(value)
+	if err != nil {
+		return err
+	}
+	if origin.Layer == LayerProject {
+		if err := s.checkProject(value); err != nil {
+			return err
+		}
+	}
+	if err := s.set(r.Config, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	r.origins[key] = origin
+	return nil
+}
+
+func (r *Resolved) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// FindProjectConfig returns the nearest project settings file in dir or
+// one of its parents, or "" when there is none
+func FindProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// envSettingName returns the environment variable that overrides a setting
+func envSettingName(key string) string {
+	if name, ok := strings.CutPrefix(key, envPolicyPrefix); ok {
+		return envSettingPrefix + "ENV_POLICY_" + name
+	}
+	return envSettingPrefix + strings.ToUpper(key)
+}
+
+// envSettings returns the settings given as JTPCK_* environment variables
+func envSettings() map[string]string {
+	values := map[string]string{}
+	for _, s := range settings {
+		if value := os.Getenv(envSettingName(s.Key)); value != "" {
+			values[s.Key] = value
+		}
+	}
+
+	policyPrefix := envSettingPrefix + "ENV_POLICY_"
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if v, ok := strings.CutPrefix(name, policyPrefix); ok && v != "" && value != "" {
+			values[envPolicyPrefix+v] = value
+		}
+	}
+	return values
+}
+
+// layerFile is a system or project settings file: config.json keys plus,
+// in the system file, a list of locked keys
+type layerFile struct {
+	values   map[string]string
+	locked   []string
+	warnings []string
+}
+
+// readLayerFile parses a settings file, returning nil when it is missing
+func readLayerFile(path string) (*layerFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	f := &layerFile{values: map[string]string{}}
+	for key, raw := range doc {
+		switch key {
+		case "locked":
+			locked, ok := layerValue(raw)
+			if !ok {
+				return nil, fmt.Errorf("locked must be a list of setting keys")
+			}
+			f.locked = splitList(locked)
+		case "env_policy":
+			policies, ok := raw.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("env_policy must be an object")
+			}
+			for name, v := range policies {
+				f.add(path, envPolicyPrefix+name, v)
+			}
+		default:
+			f.add(path, key, raw)
+		}
+	}
+	return f, nil
+}
+
+func (f *layerFile) add(path, key string, raw any) {
+	value, ok := layerValue(raw)
+	if !ok {
+		f.warnings = append(f.warnings, fmt.Sprintf("%s: %s has an unsupported value", path, key))
+		return
+	}
+	f.values[key] = value
+}
+
+// layerValue converts a JSON value to the string form settings use
+func layerValue(raw any) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", false
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), true
+	}
+	return "", false
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useSystemConfig points SystemConfigPath at a temporary file with data
+func useSystemConfig(t *testing.T, data string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := systemConfigPath
+	systemConfigPath = path
+	t.Cleanup(func() { systemConfigPath = old })
+}
+
+func resolve(t *testing.T, cfg *Config, opts ResolveOptions) *Resolved {
+	t.Helper()
+	r, err := cfg.Resolve(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	useTempHome(t)
+	useSystemConfig(t, `{"endpoint":"https://corp.example.com","sample_rate":0.5,"log_prompts":true,"hook_timeout":20}`)
+
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ProjectConfigName), []byte(`{"supervise":true,"offline":"spool"}`), 0644)
+	sub := filepath.Join(project, "src", "pkg")
+	os.MkdirAll(sub, 0755)
+
+	t.Setenv("JTPCK_OFFLINE", "disable")
+	t.Setenv("JTPCK_ENV_POLICY_OTEL_SERVICE_NAME", "keep")
+
+	cfg := New("abc", "")
+	cfg.Set("log_prompts", "false")
+	cfg.Set("hook_timeout", "30")
+
+	r := resolve(t, cfg, ResolveOptions{Dir: sub, Flags: []string{"hook_timeout=40"}})
+
+	tests := []struct {
+		key, value string
+		layer      Layer
+	}{
+		{"endpoint", "https://corp.example.com", LayerSystem},
+		{"log_prompts", "false", LayerUser},
+		{"sample_rate", "0.5", LayerSystem},
+		{"supervise", "true", LayerProject},
+		{"offline", "disable", LayerEnv},
+		{"env_policy.OTEL_SERVICE_NAME", "keep", LayerEnv},
+		{"hook_timeout", "40", LayerFlag},
+		{"log_tool_details", "false", LayerDefault},
+	}
+	for _, tt := range tests {
+		if got, _ := r.Get(tt.key); got != tt.value {
+			t.Errorf("%s = %q, want %q", tt.key, got, tt.value)
+		}
+		if got := r.Origin(tt.key).Layer; got != tt.layer {
+			t.Errorf("%s comes from %s, want %s", tt.key, got, tt.layer)
+		}
+	}
+
+	if p, _ := r.Profile(DefaultProfile); p.Endpoint != "https://corp.example.com" {
+		t.Errorf("default profile endpoint = %q", p.Endpoint)
+	}
+	if got, _ := cfg.Get("supervise"); got != "false" {
+		t.Errorf("Resolve changed the user config: supervise = %q", got)
+	}
+	if err := r.Save(); err == nil {
+		t.Error("a resolved config was saved")
+	}
+}
+
+func TestResolveLocks(t *testing.T) {
+	useTempHome(t)
+	useSystemConfig(t, `{"endpoint":"https://corp.example.com","locked":["endpoint","log_prompts"]}`)
+	t.Setenv("JTPCK_LOG_PROMPTS", "true")
+
+	cfg := New("abc", "https://mine.example.com")
+	r := resolve(t, cfg, ResolveOptions{})
+
+	if r.Endpoint != "https://corp.example.com" || !r.Locked("endpoint") {
+		t.Errorf("endpoint = %q, locked = %v", r.Endpoint, r.Locked("endpoint"))
+	}
+	if got, _ := r.Get("log_prompts"); got != "false" || !r.Locked("log_prompts") {
+		t.Errorf("log_prompts = %q, locked = %v; a lock without a value pins the default", got, r.Locked("log_prompts"))
+	}
+	if len(r.Warnings) != 2 {
+		t.Errorf("warnings = %q, want one for config.json and one for JTPCK_LOG_PROMPTS", r.Warnings)
+	}
+
+	if _, err := cfg.Resolve(ResolveOptions{Flags: []string{"endpoint=https://x.example.com"}}); err == nil {
+		t.Error("a flag overrode a locked setting")
+	}
+}
+
+func TestResolveProjectRestrictions(t *testing.T) {
+	useTempHome(t)
+	useSystemConfig(t, `{}`)
+
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, ProjectConfigName),
+		[]byte(`{"endpoint":"https://evil.example.com","tools":["claude"],"log_prompts":true,"log_tool_details":false,
+			"sample_rate":0,"codex_profiles":["none"],"env_policy":{"OTEL_EXPORTER_OTLP_ENDPOINT":"keep"}}`), 0644)
+
+	cfg := New("abc", "")
+	cfg.Set("log_tool_details", "true")
+
+	r := resolve(t, cfg, ResolveOptions{Dir: project})
+	if p, _ := r.Profile(DefaultProfile); p.Endpoint != DefaultEndpoint {
+		t.Errorf("a project file changed the endpoint to %q", p.Endpoint)
+	}
+	if len(r.EnabledTools()) != len(AllTools) {
+		t.Errorf("a project file changed tools to %v", r.EnabledTools())
+	}
+	// Project files may send less, not more, and cannot switch telemetry off
+	if got, _ := r.Get("log_tool_details"); got != "false" {
+		t.Errorf("log_tool_details = %q, want the project value", got)
+	}
+	for _, key := range []string{"log_prompts", "sample_rate", "codex_profiles", "env_policy.OTEL_EXPORTER_OTLP_ENDPOINT"} {
+		if o := r.Origin(key); o.Layer == LayerProject {
+			t.Errorf("a project file set %s", key)
+		}
+	}
+	if len(r.Warnings) != 6 {
+		t.Errorf("warnings = %q", r.Warnings)
+	}
+
+	if r := resolve(t, New("abc", ""), ResolveOptions{}); r.Origin("log_tool_details").Layer != LayerDefault {
+		t.Error("the project file applied without a directory")
+	}
+}
+
+func TestResolveRejectsBadInput(t *testing.T) {
+	useTempHome(t)
+	useSystemConfig(t, `{"endpoint":`)
+	if _, err := New("abc", "").Resolve(ResolveOptions{}); err == nil {
+		t.Error("a malformed system config was accepted")
+	}
+
+	useSystemConfig(t, `{"offline":"queue","colour":"blue"}`)
+	r := resolve(t, New("abc", ""), ResolveOptions{})
+	if len(r.Warnings) != 2 || !strings.Contains(strings.Join(r.Warnings, "\n"), "colour") {
+		t.Errorf("warnings = %q", r.Warnings)
+	}
+
+	for _, flag := range []string{"sample_rate", "sample_rate=2", "nope=1"} {
+		if _, err := New("abc", "").Resolve(ResolveOptions{Flags: []string{flag}}); err == nil {
+			t.Errorf("flag %q accepted", flag)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the config.json schema version written by this build.
+// Files without a version field predate versioning and are version 1.
+const CurrentVersion = 3
+
+// Migration upgrades a config.json document from version From to From+1.
+// Apply edits the raw document, so a migration never depends on the current
+// Config struct, and moves any user IDs it finds into creds.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]any, creds Credentials) error
+}
+
+// migrations must stay ordered by From, one per version
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "move user IDs from config.json to the secret store",
+		Apply:       migrateCredentialsOut,
+	},
+	{
+		From:        2,
+		Description: "stop pinning the built-in endpoint so a system default applies",
+		Apply:       dropDefaultEndpoint,
+	},
+}
+
+// MigrationsFrom returns the migrations that upgrade a version to CurrentVersion
+func MigrationsFrom(version int) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if m.From >= version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// documentVersion returns the schema version of a raw config.json document
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["version"]
+	if !ok {
+		return 1, nil
+	}
+	v, ok := raw.(float64)
+	if !ok || v < 1 || v != float64(int(v)) {
+		return 0, fmt.Errorf("invalid config version %v", raw)
+	}
+	return int(v), nil
+}
+
+// migrateDocument upgrades a raw config.json document in place and returns
+// the version it started at along with any user IDs the migrations removed
+func migrateDocument(doc map[string]any) (int, Credentials, error) {
+	version, err := documentVersion(doc)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version > CurrentVersion {
+		return 0, nil, fmt.Errorf("config is version %d but this jtpck only understands up to %d; upgrade jtpck", version, CurrentVersion)
+	}
+
+	creds := Credentials{}
+	for _, m := range MigrationsFrom(version) {
+		if err := m.Apply(doc, creds); err != nil {
+			return 0, nil, fmt.Errorf("migrating config from version %d: %w", m.From, err)
+		}
+		doc["version"] = m.From + 1
+	}
+	return version, creds, nil
+}
+
+// parseConfig decodes config.json, upgrading older schemas first
+func parseConfig(data []byte) (*Config, Credentials, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	version, creds, err := migrateDocument(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(migrated, &cfg); err != nil {
+		return nil, nil, err
+	}
+	cfg.loadedVersion = version
+	return &cfg, creds, nil
+}
+
+// migrateCredentialsOut (1 → 2) removes the default user_id and each
+// profile's user_id; Save writes them to the secret store
+func migrateCredentialsOut(doc map[string]any, creds Credentials) error {
+	if userID, ok := doc["user_id"].(string); ok && userID != "" {
+		creds[DefaultProfile] = userID
+	}
+	delete(doc, "user_id")
+
+	profiles, _ := doc["profiles"].(map[string]any)
+	for name, raw := range profiles {
+		profile, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("profile %q is not an object", name)
+		}
+		if userID, ok := profile["user_id"].(string); ok && userID != "" {
+			creds[name] = userID
+		}
+		delete(profile, "user_id")
+	}
+	return nil
+}
+
+// dropDefaultEndpoint (2 → 3) removes an endpoint equal to DefaultEndpoint.
+// Setup used to write it unconditionally, which hid the endpoint set in the
+// system config.
+func dropDefaultEndpoint(doc map[string]any, creds Credentials) error {
+	if doc["endpoint"] == DefaultEndpoint {
+		delete(doc, "endpoint")
+	}
+	return nil
+}
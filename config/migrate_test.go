@@ -0,0 +1,202 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useTempHome points every JTPCK path at a fresh directory
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("CODEX_HOME", "")
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	return home
+}
+
+func decode(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMigrationsAreContiguous(t *testing.T) {
+	for i, m := range migrations {
+		if m.From != i+1 {
+			t.Errorf("migrations[%d].From = %d, want %d", i, m.From, i+1)
+		}
+	}
+	if last := migrations[len(migrations)-1]; last.From+1 != CurrentVersion {
+		t.Errorf("last migration ends at %d, CurrentVersion is %d", last.From+1, CurrentVersion)
+	}
+}
+
+func TestMigrateVersion1(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		want      string
+		wantCreds Credentials
+	}{
+		{
+			name:      "unversioned with user_id",
+			in:        `{"user_id":"abc","endpoint":"https://e"}`,
+			want:      `{"version":3,"endpoint":"https://e"}`,
+			wantCreds: Credentials{DefaultProfile: "abc"},
+		},
+		{
+			name:      "profiles",
+			in:        `{"version":1,"user_id":"abc","profiles":{"acme":{"user_id":"def","endpoint":"https://acme"}}}`,
+			want:      `{"version":3,"profiles":{"acme":{"endpoint":"https://acme"}}}`,
+			wantCreds: Credentials{DefaultProfile: "abc", "acme": "def"},
+		},
+		{
+			name:      "credentials already moved",
+			in:        `{"endpoint":"https://e","profiles":{"acme":{}}}`,
+			want:      `{"version":3,"endpoint":"https://e","profiles":{"acme":{}}}`,
+			wantCreds: Credentials{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decode(t, tt.in)
+			version, creds, err := migrateDocument(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != 1 {
+				t.Errorf("version = %d, want 1", version)
+			}
+			got, _ := json.Marshal(doc)
+			if !reflect.DeepEqual(decode(t, string(got)), decode(t, tt.want)) {
+				t.Errorf("doc = %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(creds, tt.wantCreds) {
+				t.Errorf("creds = %v, want %v", creds, tt.wantCreds)
+			}
+		})
+	}
+}
+
+func TestMigrateVersion2DropsDefaultEndpoint(t *testing.T) {
+	doc := decode(t, `{"version":2,"endpoint":"`+DefaultEndpoint+`","profiles":{"acme":{"endpoint":"`+DefaultEndpoint+`"}}}`)
+	if _, _, err := migrateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["endpoint"]; ok {
+		t.Error("the built-in endpoint is still pinned")
+	}
+	if profile := doc["profiles"].(map[string]any)["acme"].(map[string]any); profile["endpoint"] != DefaultEndpoint {
+		t.Error("a profile endpoint was dropped")
+	}
+}
+
+func TestMigrateVersion1RejectsBadProfile(t *testing.T) {
+	if _, _, err := migrateDocument(decode(t, `{"profiles":{"acme":"oops"}}`)); err == nil {
+		t.Fatal("expected an error for a non-object profile")
+	}
+}
+
+func TestMigrateCurrentVersionIsUnchanged(t *testing.T) {
+	in := `{"version":3,"endpoint":"https://e","offline":"spool"}`
+	doc := decode(t, in)
+	version, creds, err := migrateDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != CurrentVersion || len(creds) != 0 {
+		t.Errorf("version = %d, creds = %v", version, creds)
+	}
+	if !reflect.DeepEqual(doc, decode(t, in)) {
+		t.Errorf("doc changed: %v", doc)
+	}
+}
+
+func TestMigrateRejectsUnknownVersions(t *testing.T) {
+	for _, in := range []string{`{"version":99}`, `{"version":0}`, `{"version":"2"}`, `{"version":1.5}`} {
+		if _, _, err := migrateDocument(decode(t, in)); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+}
+
+func TestLoadUpgradesVersion1(t *testing.T) {
+	useTempHome(t)
+	writeConfig(t, `{"user_id":"abc","endpoint":"https://e","profiles":{"acme":{"user_id":"def"}}}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.NeedsMigration() || cfg.LoadedVersion() != 1 {
+		t.Fatalf("NeedsMigration = %v, LoadedVersion = %d", cfg.NeedsMigration(), cfg.LoadedVersion())
+	}
+	if cfg.UserID != "abc" || cfg.Profiles["acme"].UserID != "def" {
+		t.Fatalf("user IDs not carried over: %q %q", cfg.UserID, cfg.Profiles["acme"].UserID)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(ConfigPath())
+	if strings.Contains(string(data), "abc") || strings.Contains(string(data), "def") {
+		t.Errorf("config.json still holds user IDs:\n%s", data)
+	}
+	if doc := decode(t, string(data)); doc["version"] != float64(CurrentVersion) {
+		t.Errorf("saved version = %v", doc["version"])
+	}
+	data, _ = os.ReadFile(CredentialsPath())
+	creds, err := parseCredentials(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Credentials{DefaultProfile: "abc", "acme": "def"}); !reflect.DeepEqual(creds, want) {
+		t.Errorf("credentials = %v, want %v", creds, want)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NeedsMigration() || cfg.UserID != "abc" {
+		t.Errorf("after save: NeedsMigration = %v, UserID = %q", cfg.NeedsMigration(), cfg.UserID)
+	}
+}
+
+func TestLoadPrefersStoredCredentials(t *testing.T) {
+	useTempHome(t)
+	writeConfig(t, `{"user_id":"stale","endpoint":"https://e"}`)
+	if err := WritePrivateFile(CredentialsPath(), []byte("default=fresh\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UserID != "fresh" {
+		t.Errorf("UserID = %q, want the stored one", cfg.UserID)
+	}
+}
+
+func writeConfig(t *testing.T, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(ConfigPath()), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ConfigPath(), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Offline modes decide what the launcher does when no endpoint is reachable
+const (
+	// OfflineDisable launches tools with their exporters switched off
+	OfflineDisable = "disable"
+	// OfflineSpool points exporters at a local receiver that writes to SpoolDir
+	OfflineSpool = "spool"
+)
+
+// HealthCachePath returns the path to the cached endpoint health checks
+func HealthCachePath() string {
+	return filepath.Join(StateDir(), "health.json")
+}
+
+// SpoolDir returns the directory holding telemetry recorded while offline
+func SpoolDir() string {
+	return filepath.Join(DataDir(), "spool")
+}
+
+// OfflineMode returns the configured offline mode, defaulting to disable
+func (c *Config) OfflineMode() string {
+	if c.Offline == OfflineSpool {
+		return OfflineSpool
+	}
+	return OfflineDisable
+}
+
+// ParseOfflineMode validates an offline mode name
+func ParseOfflineMode(s string) (string, error) {
+	switch s {
+	case OfflineDisable, OfflineSpool:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid offline mode %q (want disable or spool)", s)
+}
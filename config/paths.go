@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// JTPCK follows the XDG Base Directory spec: config.json, credentials and
+// hooks live in ConfigDir, runtime state (pause, health cache, shell rc
+// backups) in StateDir, and wrappers and the offline spool in DataDir.
+// Installs made before XDG support keep everything in ~/.jtpck until
+// MigrateToXDG moves them.
+
+// legacyPinFile marks a ~/.jtpck install that RevertXDG moved back, so it
+// is not migrated again automatically
+const legacyPinFile = ".legacy-layout"
+
+// LegacyDir returns the single directory used before XDG support
+func LegacyDir() string {
+	home := HomeDir()
+	return filepath.Join(home, ".jtpck")
+}
+
+// UsesLegacyLayout reports whether the install still lives in ~/.jtpck
+func UsesLegacyLayout() bool {
+	_, err := os.Stat(filepath.Join(LegacyDir(), "config.json"))
+	return err == nil
+}
+
+// LegacyLayoutPinned reports whether the user chose to stay in ~/.jtpck
+func LegacyLayoutPinned() bool {
+	_, err := os.Stat(filepath.Join(LegacyDir(), legacyPinFile))
+	return err == nil
+}
+
+// xdgDir returns the jtpck subdirectory of an XDG base directory. Unset or
+// relative values fall back to the default under $HOME, as the spec requires.
+// The variables describe the current user, so a target home ignores them.
+func xdgDir(env string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) && !Targeted() {
+		return filepath.Join(dir, "jtpck")
+	}
+	home := HomeDir()
+	return filepath.Join(append(append([]string{home}, fallback...), "jtpck")...)
+}
+
+func xdgConfigDir() string { return xdgDir("XDG_CONFIG_HOME", ".config") }
+func xdgStateDir() string  { return xdgDir("XDG_STATE_HOME", ".local", "state") }
+func xdgDataDir() string   { return xdgDir("XDG_DATA_HOME", ".local", "share") }
+
+// ConfigDir returns the directory holding config.json, credentials and hooks
+func ConfigDir() string {
+	if UsesLegacyLayout() {
+		return LegacyDir()
+	}
+	return xdgConfigDir()
+}
+
+// StateDir returns the directory holding pause state, the endpoint health
+// cache and shell config backups
+func StateDir() string {
+	if UsesLegacyLayout() {
+		return LegacyDir()
+	}
+	return xdgStateDir()
+}
+
+// DataDir returns the directory holding wrapper scripts and the offline spool
+func DataDir() string {
+	if UsesLegacyLayout() {
+		return LegacyDir()
+	}
+	return xdgDataDir()
+}
+
+// BackupDir returns where shell config backups are kept. Legacy installs
+// keep them next to the shell config in $HOME.
+func BackupDir() string {
+	if UsesLegacyLayout() {
+		home := HomeDir()
+		return home
+	}
+	return filepath.Join(xdgStateDir(), "backups")
+}
+
+// ConfigPath returns the path to the config file
+func ConfigPath() string {
+	return filepath.Join(ConfigDir(), "config.json")
+}
+
+// HooksDir returns the directory holding pre-<tool>.d and post-<tool>.d hooks
+func HooksDir() string {
+	return filepath.Join(ConfigDir(), "hooks")
+}
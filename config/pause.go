@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PauseState describes a temporary suspension of telemetry.
+// A zero Until means the pause lasts until `jtpck resume`.
+type PauseState struct {
+	Until time.Time
+}
+
+// PausePath returns the path to the pause state file.
+// The wrapper scripts read it at launch, so it holds a single Unix
+// timestamp (or 0 for an indefinite pause) that bash can compare directly.
+func PausePath() string {
+	return filepath.Join(StateDir(), "paused")
+}
+
+// Indefinite reports whether the pause has no expiry.
+func (p *PauseState) Indefinite() bool {
+	return p.Until.IsZero()
+}
+
+// Remaining returns how long the pause has left to run.
+func (p *PauseState) Remaining() time.Duration {
+	if p.Indefinite() {
+		return 0
+	}
+	return time.Until(p.Until).Round(time.Second)
+}
+
+// Pause suspends telemetry for the given duration. A zero duration pauses
+// until Resume is called.
+func Pause(d time.Duration) (*PauseState, error) {
+	if err := os.MkdirAll(StateDir(), 0700); err != nil {
+		return nil, err
+	}
+
+	state := &PauseState{}
+	stamp := "0"
+	if d > 0 {
+		state.Until = time.Now().Add(d)
+		stamp = strconv.FormatInt(state.Until.Unix(), 10)
+	}
+
+	if err := WritePrivateFile(PausePath(), []byte(stamp+"\n")); err != nil {
+		return nil, fmt.Errorf("writing pause state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Resume removes any pause so the wrappers export telemetry again.
+func Resume() error {
+	if err := os.Remove(PausePath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing pause state: %w", err)
+	}
+	return nil
+}
+
+// LoadPause returns the active pause, or nil when telemetry is not paused.
+// An expired or unreadable pause file is removed, mirroring the wrapper.
+func LoadPause() (*PauseState, error) {
+	data, err := os.ReadFile(PausePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pause state: %w", err)
+	}
+
+	stamp, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return nil, Resume()
+	}
+
+	if stamp == 0 {
+		return &PauseState{}, nil
+	}
+
+	until := time.Unix(stamp, 0)
+	if !time.Now().Before(until) {
+		return nil, Resume()
+	}
+
+	return &PauseState{Until: until}, nil
+}
+
+// PausedLaunch returns the env and arguments a tool is launched with while
+// paused. Codex and the Gemini CLI also read JTPCK's collector from their
+// config files, so their exporters are switched off as when offline; Codex
+// exporters kept pointing elsewhere are left alone.
+func PausedLaunch(tool string) (map[string]string, []string) {
+	env := AppOfflineEnvs()[tool]
+	if tool == "codex" {
+		if record, _ := LoadCodexOtelRecord(); record != nil && (record.Policy == CodexExportersKeep || record.Policy == CodexExportersAlongside) {
+			return env, nil
+		}
+	}
+	return env, AppLaunchArgs(tool, "", "")
+}
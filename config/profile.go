@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile names the credentials stored at the top level of config.json
+const DefaultProfile = "default"
+
+// Profile holds the credentials for one JTPCK account
+type Profile struct {
+	UserID   string   `json:"user_id,omitempty"`
+	Endpoint string   `json:"endpoint,omitempty"`
+	Failover []string `json:"failover_endpoints,omitempty"`
+}
+
+// Endpoints returns the primary endpoint followed by the failover endpoints
+func (p Profile) Endpoints() []string {
+	endpoints := []string{p.Endpoint}
+	for _, endpoint := range p.Failover {
+		if endpoint != "" && endpoint != p.Endpoint {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// Profile returns a named profile. Endpoints a profile leaves empty are
+// inherited from the default profile.
+func (c *Config) Profile(name string) (Profile, bool) {
+	base := Profile{UserID: c.UserID, Endpoint: c.Endpoint, Failover: c.Failover}
+	if base.Endpoint == "" {
+		base.Endpoint = DefaultEndpoint
+	}
+	if name == "" || name == DefaultProfile {
+		return base, true
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, false
+	}
+	if p.Endpoint == "" {
+		p.Endpoint = base.Endpoint
+		if len(p.Failover) == 0 {
+			p.Failover = base.Failover
+		}
+	}
+	return p, true
+}
+
+// SetProfile creates or replaces a named profile
+func (c *Config) SetProfile(name string, p Profile) {
+	if name == "" || name == DefaultProfile {
+		c.UserID = p.UserID
+		c.Endpoint = p.Endpoint
+		if p.Failover != nil {
+			c.Failover = p.Failover
+		}
+		return
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = p
+}
+
+// SetProfileUserID updates a profile's user ID, creating the profile if needed
+func (c *Config) SetProfileUserID(name, userID string) {
+	if name == "" || name == DefaultProfile {
+		c.UserID = userID
+		return
+	}
+
+	p := c.Profiles[name]
+	p.UserID = userID
+	c.SetProfile(name, p)
+}
+
+// RemoveProfile deletes a named profile along with its directory mappings.
+// Removing the active profile switches back to the default one.
+func (c *Config) RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile cannot be removed", DefaultProfile)
+	}
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	delete(c.Profiles, name)
+	for dir, profile := range c.Directories {
+		if profile == name {
+			delete(c.Directories, dir)
+		}
+	}
+	if c.ActiveProfile == name {
+		c.ActiveProfile = ""
+	}
+	return nil
+}
+
+// ActiveProfileName returns the profile used outside mapped directories
+func (c *Config) ActiveProfileName() string {
+	if c.ActiveProfile == "" {
+		return DefaultProfile
+	}
+	return c.ActiveProfile
+}
+
+// UseProfile makes a profile active
+func (c *Config) UseProfile(name string) error {
+	if _, ok := c.Profile(name); !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	if name == DefaultProfile {
+		name = ""
+	}
+	c.ActiveProfile = name
+	return nil
+}
+
+// ProfileNames returns all profile names, default first
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// MapDirectory selects a profile for a directory and everything beneath it
+func (c *Config) MapDirectory(dir, name string) error {
+	if _, ok := c.Profile(name); !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	dir, err := normalizeDir(dir)
+	if err != nil {
+		return err
+	}
+	if c.Directories == nil {
+		c.Directories = map[string]string{}
+	}
+	c.Directories[dir] = name
+	return nil
+}
+
+// UnmapDirectory removes a directory mapping
+func (c *Config) UnmapDirectory(dir string) error {
+	dir, err := normalizeDir(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.Directories[dir]; !ok {
+		return fmt.Errorf("no profile is mapped to %s", dir)
+	}
+	delete(c.Directories, dir)
+	return nil
+}
+
+// ProfileFor picks the profile for a working directory: the most specific
+// mapped directory wins, otherwise the active profile. Mappings to profiles
+// that no longer exist are ignored.
+func (c *Config) ProfileFor(dir string) (string, Profile) {
+	if dir, err := normalizeDir(dir); err == nil {
+		best := ""
+		for mapped, name := range c.Directories {
+			if _, ok := c.Profile(name); !ok {
+				continue
+			}
+			if (dir == mapped || strings.HasPrefix(dir, mapped+string(filepath.Separator))) && len(mapped) > len(best) {
+				best = mapped
+			}
+		}
+		if best != "" {
+			name := c.Directories[best]
+			p, _ := c.Profile(name)
+			return name, p
+		}
+	}
+
+	name := c.ActiveProfileName()
+	if p, ok := c.Profile(name); ok {
+		return name, p
+	}
+	p, _ := c.Profile(DefaultProfile)
+	return DefaultProfile, p
+}
+
+// normalizeDir makes a directory absolute, expanding ~ and resolving symlinks
+// so mappings match however the directory is reached.
+func normalizeDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		dir = filepath.Join(InTarget(HomeDir()), strings.TrimPrefix(dir, "~"))
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return abs, nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileFor(t *testing.T) {
+	home := useTempHome(t)
+	work := filepath.Join(home, "src", "work")
+	client := filepath.Join(work, "client")
+	for _, dir := range []string{filepath.Join(client, "app"), filepath.Join(home, "src", "workshop")} {
+		os.MkdirAll(dir, 0755)
+	}
+	os.Symlink(client, filepath.Join(home, "client-link"))
+
+	c := &Config{UserID: "default-id", Profiles: map[string]Profile{
+		"work":   {UserID: "work-id"},
+		"client": {UserID: "client-id", Endpoint: "https://client.example"},
+	}}
+	for dir, name := range map[string]string{"~/src/work": "work", client: "client"} {
+		if err := c.MapDirectory(dir, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A mapping left behind by a profile edited out of config.json
+	c.Directories[filepath.Join(home, "src")] = "gone"
+
+	tests := []struct {
+		dir, want string
+	}{
+		{work, "work"},
+		{filepath.Join(client, "app"), "client"},
+		{filepath.Join(home, "client-link"), "client"},
+		{filepath.Join(home, "src", "workshop"), DefaultProfile},
+		{home, DefaultProfile},
+	}
+	for _, tt := range tests {
+		if got, _ := c.ProfileFor(tt.dir); got != tt.want {
+			t.Errorf("ProfileFor(%s) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+
+	if err := c.UseProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if got, p := c.ProfileFor(home); got != "work" || p.UserID != "work-id" {
+		t.Errorf("outside mappings ProfileFor() = %q, %+v, want the active profile", got, p)
+	}
+	if err := c.MapDirectory(home, "missing"); err == nil {
+		t.Error("mapped a directory to a missing profile")
+	}
+}
+
+func TestProfileInheritsEndpoints(t *testing.T) {
+	c := &Config{
+		UserID: "default-id", Endpoint: "https://primary.example",
+		Failover: []string{"https://primary.example", "", "https://backup.example"},
+		Profiles: map[string]Profile{
+			"work":   {UserID: "work-id"},
+			"client": {UserID: "client-id", Endpoint: "https://client.example"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{DefaultProfile, []string{"https://primary.example", "https://backup.example"}},
+		{"work", []string{"https://primary.example", "https://backup.example"}},
+		{"client", []string{"https://client.example"}},
+	}
+	for _, tt := range tests {
+		p, ok := c.Profile(tt.name)
+		if !ok {
+			t.Fatalf("profile %q not found", tt.name)
+		}
+		if got := p.Endpoints(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s endpoints = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if p, _ := (&Config{}).Profile(DefaultProfile); p.Endpoint != DefaultEndpoint {
+		t.Errorf("default endpoint = %q", p.Endpoint)
+	}
+}
+
+func TestRemoveProfileDropsMappings(t *testing.T) {
+	dir := t.TempDir()
+	c := &Config{Profiles: map[string]Profile{"work": {UserID: "work-id"}}}
+	c.MapDirectory(dir, "work")
+	c.UseProfile("work")
+
+	if err := c.RemoveProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Directories) != 0 || c.ActiveProfileName() != DefaultProfile {
+		t.Errorf("after removal: directories %v, active %q", c.Directories, c.ActiveProfileName())
+	}
+	if err := c.RemoveProfile(DefaultProfile); err == nil {
+		t.Error("removed the default profile")
+	}
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// What a project setting does to the telemetry JTPCK sets up
+const (
+	// EffectDisables switches telemetry, or one of its signals, off
+	EffectDisables = "disables"
+	// EffectRedirects sends telemetry somewhere else, or with other
+	// credentials
+	EffectRedirects = "redirects"
+	// EffectChanges alters what is sent
+	EffectChanges = "changes"
+	// EffectIgnored is a JTPCK project setting that jtpck run refuses
+	EffectIgnored = "ignored"
+	// EffectInfo is an OpenTelemetry variable of a plain .env file, which
+	// the Gemini CLI loads but is usually the project's own setup
+	EffectInfo = "info"
+)
+
+// ScanFinding is a project-level setting that changes a tool's telemetry
+type ScanFinding struct {
+	Tool string `json:"tool"`
+	// Path is the settings file, relative to the scanned directory
+	Path   string `json:"path"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// Blocking reports whether a finding takes telemetry away from JTPCK
+func (f ScanFinding) Blocking() bool {
+	return f.Effect == EffectDisables || f.Effect == EffectRedirects
+}
+
+// scanSkipDirs are not searched for project settings
+var scanSkipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// ScanProjectTelemetry walks dir for the project settings of Claude Code
+// (.claude/settings.json and settings.local.json), Codex (.codex/config.toml),
+// the Gemini CLI (.gemini/settings.json, .gemini/.env and .env) and JTPCK
+// (.jtpck.json) and returns the settings in them that change telemetry.
+// The user's own settings files are not project settings and are left out.
+// Files and directories that cannot be read or parsed do not stop the
+// walk; they are returned as problems. err is only set when dir itself
+// cannot be walked.
+func ScanProjectTelemetry(dir string) (findings []ScanFinding, problems []error, err error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := map[string]bool{
+		ClaudeSettingsPath(): true, filepath.Join(ClaudeConfigDir(), "settings.local.json"): true,
+		CodexConfigPath(): true, GeminiSettingsPath(): true, GeminiEnvPath(): true,
+		filepath.Join(HomeDir(), ".env"): true,
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			problems = append(problems, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if scanSkipDirs[d.Name()] && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if user[path] {
+			return nil
+		}
+
+		var found []ScanFinding
+		switch rel := filepath.Base(filepath.Dir(path)) + "/" + d.Name(); {
+		case rel == ".claude/settings.json" || rel == ".claude/settings.local.json":
+			found, err = scanClaudeSettings(path)
+		case rel == ".codex/config.toml":
+			found, err = scanCodexConfig(path)
+		case rel == ".gemini/settings.json":
+			found, err = scanGeminiSettings(path)
+		case rel == ".gemini/.env":
+			found, err = scanDotEnv(path, false)
+		case d.Name() == ".env":
+			found, err = scanDotEnv(path, true)
+		case d.Name() == ProjectConfigName:
+			found, err = scanProjectConfig(path)
+		default:
+			return nil
+		}
+		if err != nil {
+			problems = append(problems, err)
+			return nil
+		}
+		for _, f := range found {
+			f.Path, _ = filepath.Rel(root, path)
+			findings = append(findings, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Key < findings[j].Key
+	})
+	return findings, problems, nil
+}
+
+func scanClaudeSettings(path string) ([]ScanFinding, error) {
+	settings, err := readJSONC(path)
+	if err != nil {
+		return nil, err
+	}
+	env, _ := settings["env"].(map[string]interface{})
+	var found []ScanFinding
+	for key, value := range env {
+		if effect, ok := telemetryEnvEffect(key, fmt.Sprint(value)); ok {
+			found = append(found, ScanFinding{Tool: "claude", Key: "env." + key, Value: redactEnv(key, fmt.Sprint(value)), Effect: effect})
+		}
+	}
+	return found, nil
+}
+
+func scanCodexConfig(path string) ([]ScanFinding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseTOML(data); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	var found []ScanFinding
+	for key, value := range codexOtelTable(data) {
+		f := ScanFinding{Tool: "codex", Key: "otel." + key, Value: fmt.Sprint(value), Effect: EffectChanges}
+		switch key {
+		case "exporter", "trace_exporter":
+			f.Value = describeCodexExporter(value)
+			f.Effect = EffectRedirects
+			if value == "none" {
+				f.Effect = EffectDisables
+			}
+		}
+		found = append(found, f)
+	}
+	return found, nil
+}
+
+func scanGeminiSettings(path string) ([]ScanFinding, error) {
+	settings, err := readJSONC(path)
+	if err != nil {
+		return nil, err
+	}
+	telemetry, _ := settings["telemetry"].(map[string]interface{})
+	var found []ScanFinding
+	for key, value := range telemetry {
+		f := ScanFinding{Tool: "gemini", Key: "telemetry." + key, Value: fmt.Sprint(value), Effect: EffectChanges}
+		switch key {
+		case "enabled":
+			if value == true {
+				continue
+			}
+			f.Effect = EffectDisables
+		case "target", "otlpEndpoint", "otlpProtocol", "outfile", "useCollector":
+			f.Effect = EffectRedirects
+		}
+		found = append(found, f)
+	}
+	return found, nil
+}
+
+// scanDotEnv reports the telemetry variables of a .env file, which the
+// Gemini CLI loads from the project before ~/.gemini/.env. In a plain .env
+// only the GEMINI_ variables are surely meant for it; the OpenTelemetry ones
+// are reported as information.
+func scanDotEnv(path string, plain bool) ([]ScanFinding, error) {
+	env, err := readDotEnv(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var found []ScanFinding
+	for key, value := range env {
+		if effect, ok := telemetryEnvEffect(key, value); ok {
+			if plain && !strings.HasPrefix(key, "GEMINI_") {
+				effect = EffectInfo
+			}
+			found = append(found, ScanFinding{Tool: "gemini", Key: key, Value: redactEnv(key, value), Effect: effect})
+		}
+	}
+	return found, nil
+}
+
+// scanProjectConfig reports every setting of a JTPCK project file; those it
+// may not set are ignored by jtpck run
+func scanProjectConfig(path string) ([]ScanFinding, error) {
+	project, err := readLayerFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	var found []ScanFinding
+	for key, value := range project.values {
+		f := ScanFinding{Tool: "jtpck", Key: key, Value: value, Effect: EffectChanges}
+		s, err := LookupSetting(key)
+		if err == nil {
+			value, err = s.normalize(value)
+		}
+		if err == nil {
+			err = s.checkProject(value)
+		}
+		if err != nil {
+			f.Effect = EffectIgnored
+		}
+		found = append(found, f)
+	}
+	return found, nil
+}
+
+// telemetryEnvEffect classifies a telemetry environment variable; ok is
+// false for variables that do not concern telemetry and for values that
+// only turn it on
+func telemetryEnvEffect(key, value string) (effect string, ok bool) {
+	on := value == "1" || strings.EqualFold(value, "true")
+	switch {
+	case key == "CLAUDE_CODE_ENABLE_TELEMETRY" || key == "GEMINI_TELEMETRY_ENABLED" || key == "CODEX_ENABLE_TELEMETRY":
+		return EffectDisables, !on
+	case key == "OTEL_SDK_DISABLED":
+		return EffectDisables, on
+	case strings.HasPrefix(key, "OTEL_") && strings.HasSuffix(key, "_EXPORTER"):
+		if value == "none" {
+			return EffectDisables, true
+		}
+		return EffectRedirects, true
+	case strings.HasPrefix(key, "OTEL_EXPORTER_OTLP_"), strings.HasPrefix(key, "GEMINI_TELEMETRY_OTLP_"),
+		key == "GEMINI_TELEMETRY_TARGET", key == "GEMINI_TELEMETRY_OUTFILE", key == "GEMINI_TELEMETRY_USE_COLLECTOR":
+		return EffectRedirects, true
+	case strings.HasPrefix(key, "OTEL_"), strings.HasPrefix(key, "GEMINI_TELEMETRY_"):
+		return EffectChanges, true
+	}
+	return "", false
+}
+
+// redactEnv hides header values, which hold credentials
+func redactEnv(key, value string) string {
+	if strings.HasSuffix(key, "_HEADERS") && value != "" {
+		return "(redacted)"
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanProjectTelemetry(t *testing.T) {
+	home := useTempHome(t)
+	files := map[string]string{
+		"api/.claude/settings.json":       `{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0", "DEBUG": "1"}}`,
+		"api/.claude/settings.local.json": `{"env": {"OTEL_EXPORTER_OTLP_ENDPOINT": "https://other", "OTEL_EXPORTER_OTLP_HEADERS": "x-key=secret"}}`,
+		"web/.codex/config.toml":          "[otel]\nexporter = \"none\"\nenvironment = \"dev\"\n",
+		"web/.gemini/settings.json":       "{\n  // local only\n  \"telemetry\": {\"enabled\": true, \"outfile\": \"/tmp/t.log\"},\n}\n",
+		"web/.env":                        "GEMINI_TELEMETRY_ENABLED=false\nOTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318\nPORT=3000\n",
+		"web/.gemini/.env":                "OTEL_SDK_DISABLED=true\n",
+		"broken/.claude/settings.json":    `{"env": `,
+		"web/node_modules/x/.env":         "OTEL_SDK_DISABLED=true\n",
+		"web/.jtpck.json":                 `{"log_prompts": true, "supervise": true, "env_policy": {"OTEL_EXPORTER_OTLP_ENDPOINT": "keep"}}`,
+		"clean/.claude/settings.json":     `{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "1"}}`,
+		// The user's own settings are not project settings
+		".claude/settings.json": `{"env": {"CLAUDE_CODE_ENABLE_TELEMETRY": "0"}}`,
+	}
+	for rel, content := range files {
+		path := filepath.Join(home, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	findings, problems, err := ScanProjectTelemetry(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Error(), "broken") {
+		t.Errorf("problems = %v, want the broken settings file", problems)
+	}
+	want := []ScanFinding{
+		{"claude", "api/.claude/settings.json", "env.CLAUDE_CODE_ENABLE_TELEMETRY", "0", EffectDisables},
+		{"claude", "api/.claude/settings.local.json", "env.OTEL_EXPORTER_OTLP_ENDPOINT", "https://other", EffectRedirects},
+		{"claude", "api/.claude/settings.local.json", "env.OTEL_EXPORTER_OTLP_HEADERS", "(redacted)", EffectRedirects},
+		{"codex", "web/.codex/config.toml", "otel.environment", "dev", EffectChanges},
+		{"codex", "web/.codex/config.toml", "otel.exporter", "none", EffectDisables},
+		{"gemini", "web/.env", "GEMINI_TELEMETRY_ENABLED", "false", EffectDisables},
+		{"gemini", "web/.env", "OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318", EffectInfo},
+		{"gemini", "web/.gemini/.env", "OTEL_SDK_DISABLED", "true", EffectDisables},
+		{"gemini", "web/.gemini/settings.json", "telemetry.outfile", "/tmp/t.log", EffectRedirects},
+		{"jtpck", "web/.jtpck.json", "env_policy.OTEL_EXPORTER_OTLP_ENDPOINT", "keep", EffectIgnored},
+		{"jtpck", "web/.jtpck.json", "log_prompts", "true", EffectIgnored},
+		{"jtpck", "web/.jtpck.json", "supervise", "true", EffectChanges},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings =\n%v\nwant\n%v", findings, want)
+	}
+
+	if findings, _, _ := ScanProjectTelemetry(filepath.Join(home, "clean")); len(findings) != 0 {
+		t.Errorf("clean project findings = %v", findings)
+	}
+}
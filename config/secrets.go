@@ -0,0 +1,366 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+)
+
+// Secret backends for storing credentials
+const (
+	// SecretFile keeps credentials in a 0600 plaintext file (the default)
+	SecretFile = "file"
+	// SecretAge encrypts credentials with age, unlocked by a passphrase
+	// (scrypt) or by an identity file kept outside ~/.jtpck
+	SecretAge = "age"
+	// SecretService stores credentials in the desktop keyring via secret-tool
+	SecretService = "secret-service"
+)
+
+// SecretStore persists profile credentials
+type SecretStore interface {
+	// Name returns the backend name
+	Name() string
+	// Location describes where the credentials are kept
+	Location() string
+	// Load returns the stored credentials; nothing stored is not an error
+	Load() (Credentials, error)
+	Save(Credentials) error
+	// Delete removes the stored credentials
+	Delete() error
+}
+
+// PassphraseFunc obtains the passphrase for age-encrypted credentials.
+// confirm is set when a new passphrase is being chosen. The default reads
+// JTPCK_PASSPHRASE; the CLI swaps in a terminal prompt.
+var PassphraseFunc = func(confirm bool) (string, error) {
+	if p := os.Getenv("JTPCK_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	return "", errors.New("credentials are encrypted; set JTPCK_PASSPHRASE")
+}
+
+// cachedPassphrase avoids asking twice in one process (load, then save)
+var cachedPassphrase string
+
+func passphrase(confirm bool) (string, error) {
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+	p, err := PassphraseFunc(confirm)
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", errors.New("empty passphrase")
+	}
+	cachedPassphrase = p
+	return p, nil
+}
+
+// ParseSecretBackend validates a backend name
+func ParseSecretBackend(s string) (string, error) {
+	switch s {
+	case SecretFile, SecretAge, SecretService:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid secret backend %q (want %s, %s or %s)", s, SecretFile, SecretAge, SecretService)
+}
+
+// NewSecretStore returns the store for a backend. keyFile only applies to
+// the age backend; without it a passphrase is used.
+func NewSecretStore(backend, keyFile string) (SecretStore, error) {
+	switch backend {
+	case "", SecretFile:
+		return fileStore{path: CredentialsPath()}, nil
+	case SecretAge:
+		return ageStore{path: CredentialsPath() + ".age", keyFile: keyFile}, nil
+	case SecretService:
+		return secretToolStore{}, nil
+	}
+	return nil, fmt.Errorf("unknown secret backend %q", backend)
+}
+
+// SecretStore returns the store configured for this install
+func (c *Config) SecretStore() (SecretStore, error) {
+	return NewSecretStore(c.SecretBackend, c.SecretKeyFile)
+}
+
+// PlaintextTokenFiles returns the tool files that hold the bearer token in
+// plaintext whatever the secret backend, because the tools read it from
+// nowhere else
+func (c *Config) PlaintextTokenFiles() []string {
+	var paths []string
+	if c.ToolEnabled("claude") && c.ClaudeSettingsMode() {
+		paths = append(paths, ClaudeSettingsPath())
+	}
+	if c.ToolEnabled("codex") {
+		paths = append(paths, CodexConfigPath())
+	}
+	if c.ToolEnabled("gemini") {
+		paths = append(paths, GeminiEnvPath())
+	}
+	return paths
+}
+
+// MoveSecrets switches the credentials to another backend, removing them
+// from the old one. The caller saves the config afterwards.
+func (c *Config) MoveSecrets(backend, keyFile string) error {
+	oldStore, err := c.SecretStore()
+	if err != nil {
+		return err
+	}
+	newStore, err := NewSecretStore(backend, keyFile)
+	if err != nil {
+		return err
+	}
+
+	creds := c.credentials()
+	if err := newStore.Save(creds); err != nil {
+		return fmt.Errorf("saving credentials to %s: %w", newStore.Name(), err)
+	}
+
+	c.SecretBackend = backend
+	c.SecretKeyFile = keyFile
+	c.storedCredentials = creds
+
+	if oldStore.Name() != newStore.Name() {
+		if err := oldStore.Delete(); err != nil {
+			return fmt.Errorf("removing credentials from %s: %w", oldStore.Name(), err)
+		}
+	}
+	return nil
+}
+
+// fileStore keeps credentials in the plaintext credentials file
+type fileStore struct {
+	path string
+}
+
+func (s fileStore) Name() string     { return SecretFile }
+func (s fileStore) Location() string { return s.path }
+
+func (s fileStore) Load() (Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Credentials{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	return parseCredentials(data)
+}
+
+func (s fileStore) Save(creds Credentials) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return WritePrivateFile(s.path, creds.marshal())
+}
+
+func (s fileStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ageStore keeps credentials in an age-encrypted file
+type ageStore struct {
+	path    string
+	keyFile string
+}
+
+func (s ageStore) Name() string { return SecretAge }
+
+func (s ageStore) Location() string {
+	if s.keyFile != "" {
+		return fmt.Sprintf("%s (key: %s)", s.path, s.keyFile)
+	}
+	return fmt.Sprintf("%s (passphrase)", s.path)
+}
+
+func (s ageStore) Load() (Credentials, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Credentials{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading encrypted credentials: %w", err)
+	}
+
+	identity, err := s.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting credentials: %w", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting credentials: %w", err)
+	}
+	return parseCredentials(plain)
+}
+
+func (s ageStore) Save(creds Credentials) error {
+	recipient, err := s.recipient()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return fmt.Errorf("encrypting credentials: %w", err)
+	}
+	if _, err := w.Write(creds.marshal()); err != nil {
+		return fmt.Errorf("encrypting credentials: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("encrypting credentials: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return WritePrivateFile(s.path, buf.Bytes())
+}
+
+func (s ageStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s ageStore) identity() (age.Identity, error) {
+	if s.keyFile == "" {
+		p, err := passphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		return age.NewScryptIdentity(p)
+	}
+	return loadAgeIdentity(s.keyFile)
+}
+
+func (s ageStore) recipient() (age.Recipient, error) {
+	if s.keyFile == "" {
+		p, err := passphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		return age.NewScryptRecipient(p)
+	}
+
+	identity, err := loadAgeIdentity(s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return identity.Recipient(), nil
+}
+
+func loadAgeIdentity(path string) (*age.X25519Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing key file %s: %w", path, err)
+	}
+	for _, identity := range identities {
+		if x, ok := identity.(*age.X25519Identity); ok {
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("key file %s has no X25519 identity", path)
+}
+
+// GenerateAgeKeyFile creates a new age identity at path, in the same format
+// as age-keygen, and returns its public key.
+func GenerateAgeKeyFile(path string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", err
+	}
+
+	content := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), identity.Recipient(), identity)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := WritePrivateFile(path, []byte(content)); err != nil {
+		return "", err
+	}
+	return identity.Recipient().String(), nil
+}
+
+// secretToolStore keeps credentials in the Secret Service keyring
+// (GNOME Keyring, KWallet) through libsecret's secret-tool
+type secretToolStore struct{}
+
+var secretToolAttrs = []string{"service", "jtpck", "account", "credentials"}
+
+func (s secretToolStore) Name() string     { return SecretService }
+func (s secretToolStore) Location() string { return "Secret Service keyring (service=jtpck)" }
+
+func (s secretToolStore) Load() (Credentials, error) {
+	out, err := s.run(nil, append([]string{"lookup"}, secretToolAttrs...)...)
+	if err != nil {
+		// secret-tool exits 1 silently when nothing is stored
+		if _, notFound := err.(*exec.ExitError); notFound && len(out) == 0 {
+			return Credentials{}, nil
+		}
+		return nil, err
+	}
+	return parseCredentials(out)
+}
+
+func (s secretToolStore) Save(creds Credentials) error {
+	args := append([]string{"store", "--label=JTPCK credentials"}, secretToolAttrs...)
+	_, err := s.run(creds.marshal(), args...)
+	return err
+}
+
+func (s secretToolStore) Delete() error {
+	_, err := s.run(nil, append([]string{"clear"}, secretToolAttrs...)...)
+	return err
+}
+
+func (s secretToolStore) run(stdin []byte, args ...string) ([]byte, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, errors.New("secret-tool not found (install libsecret-tools)")
+	}
+
+	cmd := exec.Command(path, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return out, fmt.Errorf("secret-tool %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// usePassphrase answers passphrase prompts with p for one test
+func usePassphrase(t *testing.T, p string) {
+	t.Helper()
+	saved := PassphraseFunc
+	PassphraseFunc = func(bool) (string, error) { return p, nil }
+	cachedPassphrase = ""
+	t.Cleanup(func() {
+		PassphraseFunc = saved
+		cachedPassphrase = ""
+	})
+}
+
+func TestAgeStorePassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.age")
+	creds := Credentials{DefaultProfile: "abc", "acme": "def"}
+	usePassphrase(t, "correct horse")
+
+	store := ageStore{path: path}
+	if err := store.Save(creds); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "abc") {
+		t.Error("the encrypted file holds a token in plaintext")
+	}
+	if got, err := store.Load(); err != nil || !reflect.DeepEqual(got, creds) {
+		t.Errorf("Load() = %v, %v", got, err)
+	}
+
+	usePassphrase(t, "wrong")
+	if _, err := store.Load(); err == nil {
+		t.Error("decrypted with the wrong passphrase")
+	}
+}
+
+func TestAgeStoreKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys", "jtpck.key")
+	public, err := GenerateAgeKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(public, "age1") {
+		t.Errorf("public key = %q", public)
+	}
+	if info, _ := os.Stat(keyFile); info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %04o", info.Mode().Perm())
+	}
+	if _, err := GenerateAgeKeyFile(keyFile); err == nil {
+		t.Error("overwrote an existing key file")
+	}
+
+	// No passphrase is needed with a key file
+	usePassphrase(t, "")
+	store := ageStore{path: filepath.Join(dir, "credentials.age"), keyFile: keyFile}
+	if creds, err := store.Load(); err != nil || len(creds) != 0 {
+		t.Errorf("Load() with nothing stored = %v, %v", creds, err)
+	}
+	if err := store.Save(Credentials{DefaultProfile: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err := store.Load(); err != nil || creds[DefaultProfile] != "abc" {
+		t.Errorf("Load() = %v, %v", creds, err)
+	}
+
+	other := filepath.Join(dir, "other.key")
+	GenerateAgeKeyFile(other)
+	if _, err := (ageStore{path: store.path, keyFile: other}).Load(); err == nil {
+		t.Error("decrypted with another key")
+	}
+}
+
+// fakeSecretTool puts a secret-tool on PATH that keeps the secret in a file
+func fakeSecretTool(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	script := `#!/bin/sh
+[ "$2 $3 $4 $5" = "service jtpck account credentials" ] || [ "$3 $4 $5 $6" = "service jtpck account credentials" ] || { echo "bad attributes: $*" >&2; exit 2; }
+case "$1" in
+store) cat > ` + secret + ` ;;
+lookup) [ -f ` + secret + ` ] || exit 1; cat ` + secret + ` ;;
+clear) rm -f ` + secret + ` ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return secret
+}
+
+func TestSecretToolStore(t *testing.T) {
+	secret := fakeSecretTool(t)
+	store := secretToolStore{}
+
+	if creds, err := store.Load(); err != nil || len(creds) != 0 {
+		t.Errorf("Load() with nothing stored = %v, %v", creds, err)
+	}
+	creds := Credentials{DefaultProfile: "abc", "acme": "def"}
+	if err := store.Save(creds); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Load(); err != nil || !reflect.DeepEqual(got, creds) {
+		t.Errorf("Load() = %v, %v", got, err)
+	}
+	if err := store.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(secret); !os.IsNotExist(err) {
+		t.Errorf("secret not cleared: %v", err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := store.Load(); err == nil || !strings.Contains(err.Error(), "secret-tool not found") {
+		t.Errorf("Load() without secret-tool = %v", err)
+	}
+}
+
+func TestMoveSecrets(t *testing.T) {
+	useTempHome(t)
+	secret := fakeSecretTool(t)
+	c := &Config{UserID: "abc", Profiles: map[string]Profile{"acme": {UserID: "def"}}}
+	if err := (fileStore{path: CredentialsPath()}).Save(c.credentials()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.MoveSecrets(SecretService, ""); err != nil {
+		t.Fatal(err)
+	}
+	if c.SecretBackend != SecretService {
+		t.Errorf("SecretBackend = %q", c.SecretBackend)
+	}
+	if _, err := os.Stat(CredentialsPath()); !os.IsNotExist(err) {
+		t.Errorf("plaintext credentials left behind: %v", err)
+	}
+	data, _ := os.ReadFile(secret)
+	if stored, _ := parseCredentials(data); !stored.equal(c.credentials()) {
+		t.Errorf("keyring holds %v", stored)
+	}
+
+	if _, err := ParseSecretBackend("keychain"); err == nil {
+		t.Error("accepted an unknown backend")
+	}
+}
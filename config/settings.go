@@ -0,0 +1,609 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Affects names the generated files that depend on a setting, so a change
+// only regenerates what it must
+type Affects int
+
+const (
+	AffectsWrappers Affects = 1 << iota
+	AffectsAliases
+	AffectsCodex
+	AffectsGemini
+
+	AffectsNone Affects = 0
+	AffectsAll          = AffectsWrappers | AffectsAliases | AffectsCodex | AffectsGemini
+)
+
+// SettingKind is the value type of a setting
+type SettingKind string
+
+const (
+	KindString SettingKind = "string"
+	KindBool   SettingKind = "bool"
+	KindInt    SettingKind = "int"
+	KindFloat  SettingKind = "float"
+	KindList   SettingKind = "list"
+)
+
+// Setting describes one key of config.json that `jtpck config` can edit.
+// Values are exchanged as strings; lists are comma-separated.
+type Setting struct {
+	Key         string
+	Kind        SettingKind
+	Description string
+	// Default is the value when no layer sets the key
+	Default string
+	// Allowed restricts the value (or each list item) when set
+	Allowed []string
+	Affects Affects
+	// Project settings are read at launch, so project files may set them.
+	// The others name accounts, endpoints or install-wide files, or decide
+	// what is sent, which a cloned repository must not change.
+	Project bool
+	// ProjectValues restricts what project files may set, e.g. only turning
+	// prompt logging off; empty allows any value
+	ProjectValues []string
+
+	// get returns the stored value, empty when unset
+	get   func(c *Config) string
+	set   func(c *Config, value string) error
+	unset func(c *Config)
+}
+
+// envPolicyPrefix starts the per-variable env_policy.<VAR> keys
+const envPolicyPrefix = "env_policy."
+
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// settings is the schema of `jtpck config`, in listing order
+var settings = []Setting{
+	{
+		Key:         "endpoint",
+		Kind:        KindString,
+		Description: "Telemetry endpoint of the default profile",
+		Default:     DefaultEndpoint,
+		Affects:     AffectsWrappers | AffectsCodex | AffectsGemini,
+		get:         func(c *Config) string { return c.Endpoint },
+		set: func(c *Config, v string) error {
+			if err := validateEndpoint(v); err != nil {
+				return err
+			}
+			c.Endpoint = v
+			return nil
+		},
+		unset: func(c *Config) { c.Endpoint = "" },
+	},
+	{
+		Key:         "failover_endpoints",
+		Kind:        KindList,
+		Description: "Endpoints tried in order when endpoint is unreachable",
+		get:         func(c *Config) string { return strings.Join(c.Failover, ",") },
+		set: func(c *Config, v string) error {
+			endpoints := splitList(v)
+			for _, endpoint := range endpoints {
+				if err := validateEndpoint(endpoint); err != nil {
+					return err
+				}
+			}
+			c.Failover = endpoints
+			return nil
+		},
+		unset: func(c *Config) { c.Failover = nil },
+	},
+	{
+		Key:         "offline",
+		Kind:        KindString,
+		Description: "What to do when no endpoint is reachable",
+		Default:     OfflineDisable,
+		Allowed:     []string{OfflineDisable, OfflineSpool},
+		Project:     true,
+		get:         func(c *Config) string { return c.Offline },
+		set: func(c *Config, v string) error {
+			mode, err := ParseOfflineMode(v)
+			if err != nil {
+				return err
+			}
+			c.Offline = mode
+			return nil
+		},
+		unset: func(c *Config) { c.Offline = "" },
+	},
+	{
+		Key:         "supervise",
+		Kind:        KindBool,
+		Description: "Report each session's start, duration and exit code",
+		Default:     "false",
+		Affects:     AffectsWrappers,
+		Project:     true,
+		get:         func(c *Config) string { return formatBool(c.Supervise) },
+		set:         boolSetter(func(c *Config, b *bool) { c.Supervise = b }),
+		unset:       func(c *Config) { c.Supervise = nil },
+	},
+	{
+		Key:         "hook_timeout",
+		Kind:        KindInt,
+		Description: "Seconds each pre/post hook may run",
+		Default:     "10",
+		Project:     true,
+		get: func(c *Config) string {
+			if c.HookTimeout == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.HookTimeout)
+		},
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid hook_timeout %q (want a whole number of seconds)", v)
+			}
+			c.HookTimeout = n
+			return nil
+		},
+		unset: func(c *Config) { c.HookTimeout = 0 },
+	},
+	{
+		Key:           "log_prompts",
+		Kind:          KindBool,
+		Description:   "Include prompt text in telemetry",
+		Default:       "false",
+		Affects:       AffectsWrappers | AffectsCodex | AffectsGemini,
+		Project:       true,
+		ProjectValues: []string{"false"},
+		get:           func(c *Config) string { return formatBool(c.LogPrompts) },
+		set:           boolSetter(func(c *Config, b *bool) { c.LogPrompts = b }),
+		unset:         func(c *Config) { c.LogPrompts = nil },
+	},
+	{
+		Key:           "log_tool_details",
+		Kind:          KindBool,
+		Description:   "Include MCP server and tool names (Claude Code)",
+		Default:       "false",
+		Affects:       AffectsWrappers,
+		Project:       true,
+		ProjectValues: []string{"false"},
+		get:           func(c *Config) string { return formatBool(c.LogToolDetails) },
+		set:           boolSetter(func(c *Config, b *bool) { c.LogToolDetails = b }),
+		unset:         func(c *Config) { c.LogToolDetails = nil },
+	},
+	{
+		Key:         "sample_rate",
+		Kind:        KindFloat,
+		Description: "Fraction of traces kept, 0 to 1 (Claude Code, Gemini CLI)",
+		Default:     "1",
+		Affects:     AffectsWrappers,
+		get: func(c *Config) string {
+			if c.SampleRate == nil {
+				return ""
+			}
+			return strconv.FormatFloat(*c.SampleRate, 'f', -1, 64)
+		},
+		set: func(c *Config, v string) error {
+			rate, err := strconv.ParseFloat(v, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return fmt.Errorf("invalid sample_rate %q (want a number from 0 to 1)", v)
+			}
+			c.SampleRate = &rate
+			return nil
+		},
+		unset: func(c *Config) { c.SampleRate = nil },
+	},
+	{
+		Key:         "codex_environment",
+		Kind:        KindString,
+		Description: "Codex otel.environment label; {profile} is the Codex profile",
+		Default:     DefaultCodexEnvironment,
+		Affects:     AffectsCodex,
+		Project:     true,
+		get:         func(c *Config) string { return c.CodexEnvironment },
+		set: func(c *Config, v string) error {
+			if strings.ContainsAny(v, "\r\n") {
+				return fmt.Errorf("invalid codex_environment %q (want a single line)", v)
+			}
+			c.CodexEnvironment = v
+			return nil
+		},
+		unset: func(c *Config) { c.CodexEnvironment = "" },
+	},
+	{
+		Key:         "codex_profiles",
+		Kind:        KindList,
+		Description: "Codex profiles the wrapper sends telemetry for (empty = all, default = no profile)",
+		get:         func(c *Config) string { return strings.Join(c.CodexProfiles, ",") },
+		set: func(c *Config, v string) error {
+			c.CodexProfiles = splitList(v)
+			return nil
+		},
+		unset: func(c *Config) { c.CodexProfiles = nil },
+	},
+	{
+		Key:         "codex_exporters",
+		Kind:        KindString,
+		Description: "What setup does with Codex exporters that send elsewhere",
+		Default:     CodexExportersAsk,
+		Allowed:     []string{CodexExportersAsk, CodexExportersReplace, CodexExportersKeep, CodexExportersAlongside},
+		Affects:     AffectsCodex | AffectsWrappers,
+		get:         func(c *Config) string { return c.CodexExporters },
+		set: func(c *Config, v string) error {
+			policy, err := ParseCodexExporters(v)
+			if err != nil {
+				return err
+			}
+			c.CodexExporters = policy
+			return nil
+		},
+		unset: func(c *Config) { c.CodexExporters = "" },
+	},
+	{
+		Key:         "claude_mode",
+		Kind:        KindString,
+		Description: "Where Claude Code gets its telemetry env: wrapper or settings.json",
+		Default:     ClaudeModeWrapper,
+		Allowed:     []string{ClaudeModeWrapper, ClaudeModeSettings},
+		Affects:     AffectsWrappers | AffectsAliases,
+		get:         func(c *Config) string { return c.ClaudeMode },
+		set: func(c *Config, v string) error {
+			mode, err := ParseClaudeMode(v)
+			if err != nil {
+				return err
+			}
+			c.ClaudeMode = mode
+			return nil
+		},
+		unset: func(c *Config) { c.ClaudeMode = "" },
+	},
+	{
+		Key:         "tools",
+		Kind:        KindList,
+		Description: "Tools JTPCK configures",
+		Default:     strings.Join(AllTools, ","),
+		Allowed:     AllTools,
+		Affects:     AffectsAll,
+		get:         func(c *Config) string { return strings.Join(c.Tools, ",") },
+		set: func(c *Config, v string) error {
+			tools := splitList(v)
+			if len(tools) == 0 {
+				return fmt.Errorf("tools cannot be empty; use `jtpck uninstall` to remove JTPCK")
+			}
+			c.Tools = tools
+			return nil
+		},
+		unset: func(c *Config) { c.Tools = nil },
+	},
+	{
+		Key:         "shells",
+		Kind:        KindList,
+		Description: "Shells whose rc file gets the aliases (empty = detected)",
+		Allowed:     []string{"bash", "zsh"},
+		Affects:     AffectsAliases,
+		get:         func(c *Config) string { return strings.Join(c.Shells, ",") },
+		set: func(c *Config, v string) error {
+			c.Shells = splitList(v)
+			return nil
+		},
+		unset: func(c *Config) { c.Shells = nil },
+	},
+}
+
+// Settings returns the schema of `jtpck config`, in listing order
+func Settings() []Setting {
+	return settings
+}
+
+// LookupSetting returns the schema entry for a key, including the
+// env_policy.<VAR> family
+func LookupSetting(key string) (Setting, error) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+
+	if name, ok := strings.CutPrefix(key, envPolicyPrefix); ok {
+		if !envVarName.MatchString(name) {
+			return Setting{}, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		return envPolicySetting(name), nil
+	}
+	return Setting{}, fmt.Errorf("unknown setting %q (see `jtpck config list`)", key)
+}
+
+// envPolicySetting describes env_policy.<name>
+func envPolicySetting(name string) Setting {
+	return Setting{
+		Key:         envPolicyPrefix + name,
+		Kind:        KindString,
+		Description: "How " + name + " combines with an existing value",
+		Default:     string((*Config)(nil).EnvPolicyFor(name)),
+		Allowed:     []string{string(PolicyOverride), string(PolicyMerge), string(PolicyKeep)},
+		Affects:     AffectsWrappers,
+		get:         func(c *Config) string { return c.EnvPolicy[name] },
+		set: func(c *Config, v string) error {
+			policy, err := ParseEnvPolicy(v)
+			if err != nil {
+				return err
+			}
+			if c.EnvPolicy == nil {
+				c.EnvPolicy = map[string]string{}
+			}
+			c.EnvPolicy[name] = string(policy)
+			return nil
+		},
+		unset: func(c *Config) { delete(c.EnvPolicy, name) },
+	}
+}
+
+// SettingKeys returns every key with a value, including env_policy entries
+// set in config.json
+func (c *Config) SettingKeys() []string {
+	keys := make([]string, 0, len(settings)+len(c.EnvPolicy))
+	for _, s := range settings {
+		keys = append(keys, s.Key)
+	}
+
+	var policies []string
+	for name := range c.EnvPolicy {
+		policies = append(policies, envPolicyPrefix+name)
+	}
+	sort.Strings(policies)
+	return append(keys, policies...)
+}
+
+// Get returns the value of a setting, or its default when unset
+func (c *Config) Get(key string) (string, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return "", err
+	}
+	return s.value(c), nil
+}
+
+// IsSet reports whether the config holds a value for a setting
+func (c *Config) IsSet(key string) bool {
+	s, err := LookupSetting(key)
+	return err == nil && s.get(c) != ""
+}
+
+// Set validates and stores a setting, returning the files it affects
+func (c *Config) Set(key, value string) (Affects, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return AffectsNone, err
+	}
+
+	value, err = s.normalize(value)
+	if err != nil {
+		return AffectsNone, err
+	}
+
+	before := s.value(c)
+	if err := s.set(c, value); err != nil {
+		return AffectsNone, err
+	}
+	if s.value(c) == before {
+		return AffectsNone, nil
+	}
+	return s.Affects, nil
+}
+
+// Unset restores a setting's default, returning the files it affects
+func (c *Config) Unset(key string) (Affects, error) {
+	s, err := LookupSetting(key)
+	if err != nil {
+		return AffectsNone, err
+	}
+
+	before := s.value(c)
+	s.unset(c)
+	if s.value(c) == before {
+		return AffectsNone, nil
+	}
+	return s.Affects, nil
+}
+
+// Validate checks every setting, e.g. after config.json was edited by hand
+func (c *Config) Validate() error {
+	for _, key := range c.SettingKeys() {
+		s, err := LookupSetting(key)
+		if err != nil {
+			return err
+		}
+		value := s.get(c)
+		if value == "" {
+			continue
+		}
+		if err := s.checkAllowed(value); err != nil {
+			return err
+		}
+		probe := *c
+		probe.EnvPolicy = maps.Clone(c.EnvPolicy)
+		if err := s.set(&probe, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	for name, p := range c.Profiles {
+		if p.Endpoint == "" {
+			continue
+		}
+		if err := validateEndpoint(p.Endpoint); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ChangedSettings returns the files affected by every setting that differs
+// between two configs
+func ChangedSettings(before, after *Config) Affects {
+	keys := map[string]bool{}
+	for _, key := range append(before.SettingKeys(), after.SettingKeys()...) {
+		keys[key] = true
+	}
+
+	affects := AffectsNone
+	for key := range keys {
+		s, err := LookupSetting(key)
+		if err != nil {
+			continue
+		}
+		if s.value(before) != s.value(after) {
+			affects |= s.Affects
+		}
+	}
+	return affects
+}
+
+// value returns the stored value of the setting, or its default
+func (s Setting) value(c *Config) string {
+	if v := s.get(c); v != "" {
+		return v
+	}
+	return s.Default
+}
+
+// normalize trims a value and lowercases booleans, then validates it
+// against the setting's kind and Allowed values
+func (s Setting) normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if s.Kind == KindBool {
+		value = strings.ToLower(value)
+	}
+	return value, s.checkAllowed(value)
+}
+
+// checkProject reports why a project file may not give a setting a
+// (normalized) value
+func (s Setting) checkProject(value string) error {
+	if !s.Project {
+		return fmt.Errorf("%s can only be set in config.json or %s", s.Key, SystemConfigPath())
+	}
+	if len(s.ProjectValues) > 0 && !contains(s.ProjectValues, value) {
+		return fmt.Errorf("%s can only be set to %s in a project file", s.Key, strings.Join(s.ProjectValues, " or "))
+	}
+	return nil
+}
+
+// checkAllowed validates a value (or each list item) against Allowed
+func (s Setting) checkAllowed(value string) error {
+	if s.Kind == KindBool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid %s %q (want true or false)", s.Key, value)
+		}
+	}
+	if len(s.Allowed) == 0 {
+		return nil
+	}
+
+	items := []string{value}
+	if s.Kind == KindList {
+		items = splitList(value)
+	}
+	for _, item := range items {
+		if !contains(s.Allowed, item) {
+			return fmt.Errorf("invalid %s %q (want %s)", s.Key, item, strings.Join(s.Allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// boolSetter adapts an optional bool field to a Setting's set function
+func boolSetter(apply func(c *Config, b *bool)) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		apply(c, &b)
+		return nil
+	}
+}
+
+// formatBool formats an optional bool, empty when unset
+func formatBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+// isTrue reports whether an optional bool is set and true
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// splitList parses a comma-separated list, dropping blanks and duplicates
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateEndpoint requires an absolute http(s) URL
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %q (want an http:// or https:// URL)", endpoint)
+	}
+	return nil
+}
+
+// ApplyJSON returns the config described by an edited config.json document,
+// validated. Profiles the document leaves without a user ID keep their
+// current one; user IDs it does contain move to the secret store on Save.
+func (c *Config) ApplyJSON(data []byte) (*Config, error) {
+	updated, migrated, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	updated.applyCredentials(migrated)
+	updated.storedCredentials = c.storedCredentials
+
+	if updated.UserID == "" {
+		updated.UserID = c.UserID
+	}
+	for name, p := range updated.Profiles {
+		if old, ok := c.Profiles[name]; ok && p.UserID == "" {
+			p.UserID = old.UserID
+			updated.Profiles[name] = p
+		}
+	}
+
+	if updated.SecretBackend != c.SecretBackend || updated.SecretKeyFile != c.SecretKeyFile {
+		return nil, fmt.Errorf("secret_backend and secret_key_file can only be changed with `jtpck secrets use`")
+	}
+	if _, ok := updated.Profile(updated.ActiveProfileName()); !ok {
+		return nil, fmt.Errorf("active_profile %q does not exist", updated.ActiveProfile)
+	}
+	for dir, name := range updated.Directories {
+		if _, ok := updated.Profile(name); !ok {
+			return nil, fmt.Errorf("directory %s is mapped to unknown profile %q", dir, name)
+		}
+	}
+	if err := updated.Validate(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
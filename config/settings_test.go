@@ -0,0 +1,138 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSetValidates(t *testing.T) {
+	tests := []struct {
+		key, value string
+		ok         bool
+	}{
+		{"endpoint", "https://collector.example.com/v1", true},
+		{"endpoint", "collector.example.com", false},
+		{"failover_endpoints", "https://a.example.com, https://b.example.com", true},
+		{"failover_endpoints", "https://a.example.com,nope", false},
+		{"offline", "spool", true},
+		{"offline", "queue", false},
+		{"supervise", "TRUE", true},
+		{"supervise", "yes", false},
+		{"hook_timeout", "30", true},
+		{"hook_timeout", "-1", false},
+		{"sample_rate", "0.1", true},
+		{"sample_rate", "1.5", false},
+		{"tools", "claude,gemini", true},
+		{"tools", "claude,cursor", false},
+		{"tools", "", false},
+		{"shells", "bash", true},
+		{"shells", "fish", false},
+		{"env_policy.OTEL_SERVICE_NAME", "keep", true},
+		{"env_policy.OTEL_SERVICE_NAME", "sometimes", false},
+		{"env_policy.NOT-A-VAR", "keep", false},
+		{"user_id", "abc", false},
+	}
+
+	for _, tt := range tests {
+		cfg := New("abc", DefaultEndpoint)
+		_, err := cfg.Set(tt.key, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("Set(%q, %q) error = %v, want ok = %v", tt.key, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestSetReportsAffectedFiles(t *testing.T) {
+	cfg := New("abc", DefaultEndpoint)
+
+	affects, err := cfg.Set("log_prompts", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := AffectsWrappers | AffectsCodex | AffectsGemini; affects != want {
+		t.Errorf("log_prompts affects %b, want %b", affects, want)
+	}
+
+	affects, _ = cfg.Set("log_prompts", "true")
+	if affects != AffectsNone {
+		t.Errorf("setting the same value affects %b, want none", affects)
+	}
+
+	affects, _ = cfg.Set("offline", "spool")
+	if affects != AffectsNone {
+		t.Errorf("offline affects %b; it is read at launch", affects)
+	}
+
+	affects, _ = cfg.Unset("log_prompts")
+	if affects == AffectsNone || isTrue(cfg.LogPrompts) {
+		t.Errorf("unset log_prompts: affects %b, LogPrompts %v", affects, cfg.LogPrompts)
+	}
+}
+
+func TestUnsetRestoresDefaults(t *testing.T) {
+	cfg := New("abc", "https://other.example.com")
+	for key, value := range map[string]string{"endpoint": "https://x.example.com", "sample_rate": "0.5", "tools": "claude"} {
+		if _, err := cfg.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for key, want := range map[string]string{"endpoint": DefaultEndpoint, "sample_rate": "1", "tools": "claude,codex,gemini"} {
+		if _, err := cfg.Unset(key); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := cfg.Get(key); got != want {
+			t.Errorf("%s after unset = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestToolEnvsApplySettings(t *testing.T) {
+	cfg := New("abc", DefaultEndpoint)
+	envs := cfg.ToolEnvs("abc", DefaultEndpoint)
+	if _, ok := envs["claude"]["OTEL_LOG_USER_PROMPTS"]; ok {
+		t.Error("prompts are logged by default")
+	}
+	if envs["gemini"]["GEMINI_TELEMETRY_LOG_PROMPTS"] != "false" {
+		t.Error("Gemini prompt logging is not switched off by default")
+	}
+
+	cfg.Set("log_prompts", "true")
+	cfg.Set("sample_rate", "0.25")
+	envs = cfg.ToolEnvs("abc", DefaultEndpoint)
+	if envs["claude"]["OTEL_LOG_USER_PROMPTS"] != "1" || envs["codex"]["CODEX_OTEL_LOG_USER_PROMPT"] != "true" {
+		t.Errorf("log_prompts not applied: %v %v", envs["claude"], envs["codex"])
+	}
+	if envs["claude"]["OTEL_TRACES_SAMPLER_ARG"] != "0.25" || envs["gemini"]["OTEL_TRACES_SAMPLER_ARG"] != "0.25" {
+		t.Errorf("sample_rate not applied")
+	}
+}
+
+func TestApplyJSON(t *testing.T) {
+	cfg := New("abc", DefaultEndpoint)
+	cfg.SetProfile("acme", Profile{UserID: "def"})
+
+	updated, err := cfg.ApplyJSON([]byte(`{"version":2,"endpoint":"https://e.example.com","sample_rate":0.5,
+		"profiles":{"acme":{},"beta":{"user_id":"ghi"}},"active_profile":"beta"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.UserID != "abc" || updated.Profiles["acme"].UserID != "def" || updated.Profiles["beta"].UserID != "ghi" {
+		t.Errorf("user IDs = %q %q %q", updated.UserID, updated.Profiles["acme"].UserID, updated.Profiles["beta"].UserID)
+	}
+	if got := ChangedSettings(cfg, updated); got&AffectsWrappers == 0 || got&AffectsCodex == 0 {
+		t.Errorf("ChangedSettings = %b, want wrappers and codex", got)
+	}
+
+	for _, bad := range []string{
+		`{"version":2,"endpoint":"https://e.example.com","offline":"bogus"}`,
+		`{"version":2,"endpoint":"https://e.example.com","active_profile":"missing"}`,
+		`{"version":2,"endpoint":"https://e.example.com","directories":{"/src":"missing"}}`,
+		`{"version":2,"endpoint":"https://e.example.com","secret_backend":"age"}`,
+		`{"version":2,"endpoint":"https://e.example.com","env_policy":{"OTEL_SERVICE_NAME":"never"}}`,
+		`{"version":2,`,
+	} {
+		if _, err := cfg.ApplyJSON([]byte(bad)); err == nil {
+			t.Errorf("ApplyJSON(%s) accepted invalid config", bad)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// A target lets jtpck provision a home it is not running in, such as a
+// user's home inside a Docker build, a chroot or a mounted VM image. Every
+// path function returns the path to write on this machine; InTarget turns
+// one into the path the installed files see, for embedding in wrappers and
+// shell config.
+var target struct {
+	// root is the target filesystem's mount point ("" = /)
+	root string
+	// home is the target user's home inside root ("" = the current user's)
+	home string
+	// path is the tool search PATH inside root ("" = $PATH)
+	path string
+}
+
+// SetTarget points jtpck at another filesystem root, home directory and
+// tool search PATH. Empty values keep the current user's. A home given
+// with a root is inside it; a home without a root is used as is.
+func SetTarget(root, home, path string) error {
+	if root != "" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return fmt.Errorf("--root %s is not a directory", root)
+		}
+		root = abs
+		if root == "/" {
+			root = ""
+		}
+	}
+
+	if home != "" {
+		if root != "" && !filepath.IsAbs(home) {
+			return fmt.Errorf("--home must be absolute when --root is set")
+		}
+		abs, err := filepath.Abs(home)
+		if err != nil {
+			return err
+		}
+		home = abs
+		if info, err := os.Stat(filepath.Join(root, home)); err != nil || !info.IsDir() {
+			return fmt.Errorf("--home %s is not a directory", filepath.Join(root, home))
+		}
+	} else if root != "" {
+		return fmt.Errorf("--root needs --home to name the user's home inside it")
+	}
+
+	target.root, target.home, target.path = root, home, path
+	return nil
+}
+
+// Targeted reports whether jtpck provisions another home than the current
+// user's
+func Targeted() bool {
+	return target.home != ""
+}
+
+// HomeDir returns the home directory to install into
+func HomeDir() string {
+	if target.home != "" {
+		return filepath.Join(target.root, target.home)
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// InTarget returns the path the target system sees for a path on this
+// machine: unchanged unless a root is set
+func InTarget(path string) string {
+	if target.root == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(target.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join("/", rel)
+	}
+	return path
+}
+
+// OnHost returns where a path of the target system is on this machine
+func OnHost(path string) string {
+	return filepath.Join(target.root, path)
+}
+
+// LookPath finds a tool on the target's PATH and returns its path as the
+// target sees it
+func LookPath(name string) (string, error) {
+	if !Targeted() && target.path == "" {
+		return exec.LookPath(name)
+	}
+
+	path := target.path
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" || !filepath.IsAbs(dir) {
+			continue
+		}
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(OnHost(candidate)); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found on %s", name, path)
+}
+
+// ChownToHome gives each path, its contents and the directories between it
+// and the target home to the home's owner. Provisioning another user's home
+// runs as root, which would otherwise leave the files unreadable to them.
+func ChownToHome(paths ...string) error {
+	if !Targeted() || os.Geteuid() != 0 {
+		return nil
+	}
+	home := HomeDir()
+	info, err := os.Stat(home)
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	uid, gid := int(st.Uid), int(st.Gid)
+
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(p, uid, gid)
+		})
+		if err != nil {
+			return err
+		}
+		for dir := filepath.Dir(path); strings.HasPrefix(dir, home+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			if err := os.Lchown(dir, uid, gid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
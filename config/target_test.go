@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTarget points jtpck at a home inside root until the test ends
+func useTarget(t *testing.T, root, home, path string) {
+	t.Helper()
+	if err := SetTarget(root, home, path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTarget("", "", "") })
+}
+
+func TestTargetPaths(t *testing.T) {
+	useTempHome(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "home", "dev"), 0755)
+	useTarget(t, root, "/home/dev", "")
+
+	if want := filepath.Join(root, "home", "dev", ".config", "jtpck", "config.json"); ConfigPath() != want {
+		t.Errorf("ConfigPath = %s, want %s (XDG variables describe the current user)", ConfigPath(), want)
+	}
+	if got := InTarget(ConfigPath()); got != "/home/dev/.config/jtpck/config.json" {
+		t.Errorf("InTarget = %s", got)
+	}
+	if got := InTarget("/usr/bin/claude"); got != "/usr/bin/claude" {
+		t.Errorf("InTarget changed a path outside the root: %s", got)
+	}
+	if want := filepath.Join(root, "etc", "jtpck", "config.json"); SystemConfigPath() != want {
+		t.Errorf("SystemConfigPath = %s, want %s", SystemConfigPath(), want)
+	}
+}
+
+func TestTargetLookPath(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "home", "dev"), 0755)
+	os.MkdirAll(filepath.Join(root, "opt", "bin"), 0755)
+	os.WriteFile(filepath.Join(root, "opt", "bin", "claude"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(root, "opt", "bin", "codex"), []byte("not executable"), 0644)
+	useTarget(t, root, "/home/dev", "/usr/bin:/opt/bin")
+
+	if path, err := LookPath("claude"); err != nil || path != "/opt/bin/claude" {
+		t.Errorf("LookPath(claude) = %q, %v", path, err)
+	}
+	if _, err := LookPath("codex"); err == nil {
+		t.Error("found a file that is not executable")
+	}
+}
+
+func TestSetTargetValidates(t *testing.T) {
+	defer SetTarget("", "", "")
+	root := t.TempDir()
+	for _, tt := range []struct{ root, home string }{
+		{root, ""},
+		{root, "relative"},
+		{root, "/missing"},
+		{filepath.Join(root, "missing"), "/"},
+	} {
+		if err := SetTarget(tt.root, tt.home, ""); err == nil {
+			t.Errorf("SetTarget(%q, %q) accepted", tt.root, tt.home)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// tomlDoc edits a TOML document in place. It splits the text into
+// statements (table headers, key/value pairs and the blank or comment lines
+// between them) and only rewrites the statements an edit touches, so the
+// user's comments, ordering and formatting survive.
+type tomlDoc struct {
+	stmts []tomlStmt
+}
+
+type tomlStmtKind int
+
+const (
+	tomlTrivia     tomlStmtKind = iota // blank or comment-only line
+	tomlTable                          // [table]
+	tomlArrayTable                     // [[table]]
+	tomlKeyValue                       // key = value
+)
+
+type tomlStmt struct {
+	kind tomlStmtKind
+	// text is the statement's source, including its newline
+	text string
+	// table is the table the statement is in, or the one a header opens
+	table []string
+	// array is set for statements in an array of tables
+	array bool
+	// key is the full path of a key/value pair: table plus dotted key
+	key []string
+	// valueStart and valueEnd delimit a key/value pair's value in text
+	valueStart, valueEnd int
+}
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// parseTOML splits a valid TOML document into statements
+func parseTOML(data []byte) (*tomlDoc, error) {
+	var check map[string]interface{}
+	if err := toml.Unmarshal(data, &check); err != nil {
+		return nil, err
+	}
+
+	s := string(data)
+	doc := &tomlDoc{}
+	var table []string
+	array := false
+	for pos := 0; pos < len(s); {
+		start := pos
+		pos = skipTOMLSpace(s, pos)
+		stmt := tomlStmt{table: table, array: array}
+
+		switch {
+		case pos >= len(s) || s[pos] == '\n' || s[pos] == '\r' || s[pos] == '#':
+			stmt.kind = tomlTrivia
+		case s[pos] == '[':
+			stmt.kind = tomlTable
+			pos++
+			if pos < len(s) && s[pos] == '[' {
+				stmt.kind = tomlArrayTable
+				pos++
+			}
+			key, next, err := parseTOMLKey(s, pos)
+			if err != nil {
+				return nil, err
+			}
+			table, stmt.table = key, key
+			array, stmt.array = stmt.kind == tomlArrayTable, stmt.kind == tomlArrayTable
+			pos = next
+		default:
+			key, next, err := parseTOMLKey(s, pos)
+			if err != nil {
+				return nil, err
+			}
+			next = skipTOMLSpace(s, next)
+			if next >= len(s) || s[next] != '=' {
+				return nil, fmt.Errorf("expected = after key %s", strings.Join(key, "."))
+			}
+			stmt.kind = tomlKeyValue
+			stmt.key = append(append([]string{}, table...), key...)
+			stmt.valueStart = skipTOMLSpace(s, next+1) - start
+			end, err := scanTOMLValue(s, start+stmt.valueStart)
+			if err != nil {
+				return nil, err
+			}
+			stmt.valueEnd = end - start
+			pos = end
+		}
+
+		// The rest of the line: closing brackets, spaces, a comment
+		if nl := strings.IndexByte(s[pos:], '\n'); nl >= 0 {
+			pos += nl + 1
+		} else {
+			pos = len(s)
+		}
+		stmt.text = s[start:pos]
+		doc.stmts = append(doc.stmts, stmt)
+	}
+	return doc, nil
+}
+
+func skipTOMLSpace(s string, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+// parseTOMLKey reads a dotted key of bare and quoted parts
+func parseTOMLKey(s string, pos int) ([]string, int, error) {
+	var key []string
+	for {
+		pos = skipTOMLSpace(s, pos)
+		if pos >= len(s) {
+			return nil, pos, fmt.Errorf("unexpected end of document in key")
+		}
+		switch s[pos] {
+		case '"', '\'':
+			end, err := scanTOMLString(s, pos)
+			if err != nil {
+				return nil, pos, err
+			}
+			part := s[pos+1 : end-1]
+			if s[pos] == '"' {
+				if part, err = strconv.Unquote(s[pos:end]); err != nil {
+					return nil, pos, fmt.Errorf("key %s: %w", s[pos:end], err)
+				}
+			}
+			key = append(key, part)
+			pos = end
+		default:
+			end := pos
+			for end < len(s) && (isBareKeyChar(s[end])) {
+				end++
+			}
+			if end == pos {
+				return nil, pos, fmt.Errorf("unexpected %q in key", s[pos])
+			}
+			key = append(key, s[pos:end])
+			pos = end
+		}
+		pos = skipTOMLSpace(s, pos)
+		if pos >= len(s) || s[pos] != '.' {
+			return key, pos, nil
+		}
+		pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// scanTOMLString returns the end of the string starting at pos
+func scanTOMLString(s string, pos int) (int, error) {
+	quote := s[pos : pos+1]
+	if strings.HasPrefix(s[pos:], quote+quote+quote) {
+		delim := quote + quote + quote
+		for i := pos + 3; i < len(s); i++ {
+			if quote == `"` && s[i] == '\\' {
+				i++
+				continue
+			}
+			if strings.HasPrefix(s[i:], delim) {
+				end := i + 3
+				// Up to two quotes may end the content right before the delimiter
+				for n := 0; n < 2 && end < len(s) && s[end:end+1] == quote; n++ {
+					end++
+				}
+				return end, nil
+			}
+		}
+		return 0, fmt.Errorf("unterminated multi-line string")
+	}
+	for i := pos + 1; i < len(s) && s[i] != '\n'; i++ {
+		if quote == `"` && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i:i+1] == quote {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string")
+}
+
+// scanTOMLValue returns the end of the value starting at pos
+func scanTOMLValue(s string, pos int) (int, error) {
+	if pos >= len(s) {
+		return 0, fmt.Errorf("missing value")
+	}
+	switch s[pos] {
+	case '"', '\'':
+		return scanTOMLString(s, pos)
+	case '[', '{':
+		depth := 0
+		for i := pos; i < len(s); i++ {
+			switch s[i] {
+			case '"', '\'':
+				end, err := scanTOMLString(s, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end - 1
+			case '#':
+				for i < len(s) && s[i] != '\n' {
+					i++
+				}
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unterminated array or inline table")
+	}
+	// Numbers, booleans and dates (which may contain a space) run to the
+	// end of the line or a comment
+	end := pos
+	for end < len(s) && s[end] != '\n' && s[end] != '#' {
+		end++
+	}
+	return pos + len(strings.TrimRight(s[pos:end], " \t\r")), nil
+}
+
+// Bytes returns the edited document
+func (d *tomlDoc) Bytes() []byte {
+	var b bytes.Buffer
+	for _, stmt := range d.stmts {
+		b.WriteString(stmt.text)
+	}
+	return b.Bytes()
+}
+
+// Set gives the key at path a value, replacing its value in place when
+// the key exists. A new key goes next to its siblings, or into a new table
+// after the last table of the same top-level tree. An inline table in the
+// way is expanded into a table keeping its members; any other value in
+// the way is replaced.
+func (d *tomlDoc) Set(path []string, value interface{}) error {
+	literal, err := tomlLiteral(value)
+	if err != nil {
+		return err
+	}
+	parent := path[:len(path)-1]
+
+	for i, stmt := range d.stmts {
+		if stmt.kind != tomlKeyValue || stmt.array {
+			continue
+		}
+		switch {
+		case pathEqual(stmt.key, path):
+			d.stmts[i].text = stmt.text[:stmt.valueStart] + literal + stmt.text[stmt.valueEnd:]
+			d.stmts[i].valueEnd = stmt.valueStart + len(literal)
+			return nil
+		case hasPathPrefix(path, stmt.key):
+			if err := d.expandInline(i); err != nil {
+				return err
+			}
+			return d.Set(path, value)
+		}
+	}
+
+	// Next to the closest relative defined in a table that is one of its
+	// parents, as a dotted key when the relative is one
+	best, bestShared := -1, 0
+	for i, stmt := range d.stmts {
+		if stmt.kind != tomlKeyValue || stmt.array || !hasPathPrefix(parent, stmt.table) {
+			continue
+		}
+		shared := commonPrefix(stmt.key, parent)
+		if (shared == len(parent) || shared > len(stmt.table)) && shared >= bestShared {
+			best, bestShared = i, shared
+		}
+	}
+	if best >= 0 {
+		stmt := d.stmts[best]
+		indent := stmt.text[:len(stmt.text)-len(strings.TrimLeft(stmt.text, " \t"))]
+		d.insertAt(best+1, keyValueStmt(indent+formatTOMLKey(path[len(stmt.table):])+" = ", literal, stmt.table, path))
+		return nil
+	}
+
+	prefix := formatTOMLKey(path[len(parent):]) + " = "
+	if len(parent) == 0 {
+		// Root keys go before the first table
+		at := len(d.stmts)
+		for i, stmt := range d.stmts {
+			if stmt.kind == tomlTable || stmt.kind == tomlArrayTable {
+				at = i
+				break
+			}
+		}
+		d.insertAt(at, keyValueStmt(prefix, literal, nil, path))
+		return nil
+	}
+
+	// Under an empty header for the parent
+	for i, stmt := range d.stmts {
+		if stmt.kind == tomlTable && pathEqual(stmt.table, parent) {
+			d.insertAt(i+1, keyValueStmt(prefix, literal, parent, path))
+			return nil
+		}
+	}
+
+	// In a new table after the statements of its closest relatives
+	at, atShared := len(d.stmts), 0
+	for i, stmt := range d.stmts {
+		var shared int
+		switch stmt.kind {
+		case tomlTable, tomlArrayTable:
+			shared = commonPrefix(stmt.table, parent)
+		case tomlKeyValue:
+			shared = commonPrefix(stmt.key, parent)
+		}
+		if shared > 0 && shared >= atShared {
+			at, atShared = i+1, shared
+		}
+	}
+	if at == len(d.stmts) {
+		d.ensureTrailingNewline()
+	}
+	header := "[" + formatTOMLKey(parent) + "]\n"
+	if at > 0 {
+		header = "\n" + header
+	}
+	d.insertAt(at,
+		tomlStmt{kind: tomlTable, text: header, table: parent},
+		keyValueStmt(prefix, literal, parent, path),
+	)
+	return nil
+}
+
+// Remove deletes the table at path and everything under it: its [table]
+// and [[table]] sections, dotted keys and an inline table or value at
+// path. Comments and blank lines inside a removed section go with it,
+// except those right before the next section, which usually describe that.
+// It reports whether anything was removed.
+func (d *tomlDoc) Remove(path []string) bool {
+	// A key inside an inline table needs a statement of its own first
+	for i := 0; i < len(d.stmts); i++ {
+		stmt := d.stmts[i]
+		if stmt.kind == tomlKeyValue && !stmt.array && len(stmt.key) < len(path) && hasPathPrefix(path, stmt.key) {
+			if err := d.expandInline(i); err != nil {
+				return false
+			}
+			i = -1
+		}
+	}
+
+	inTree := func(stmt tomlStmt) bool {
+		switch stmt.kind {
+		case tomlTable, tomlArrayTable:
+			return hasPathPrefix(stmt.table, path)
+		case tomlKeyValue:
+			return hasPathPrefix(stmt.key, path)
+		}
+		return false
+	}
+
+	remove := make([]bool, len(d.stmts))
+	found := false
+	for i, stmt := range d.stmts {
+		if !inTree(stmt) {
+			continue
+		}
+		remove[i], found = true, true
+		if stmt.kind == tomlTable || stmt.kind == tomlArrayTable {
+			// The blank lines separating the section from the one before
+			for j := i - 1; j >= 0 && d.stmts[j].kind == tomlTrivia && strings.TrimSpace(d.stmts[j].text) == ""; j-- {
+				remove[j] = true
+			}
+		}
+	}
+	if !found {
+		return false
+	}
+
+	// Comments and blank lines in a removed section, up to its last statement
+	for i, stmt := range d.stmts {
+		if stmt.kind != tomlTrivia || len(stmt.table) == 0 || !hasPathPrefix(stmt.table, path) {
+			continue
+		}
+		for j := i + 1; j < len(d.stmts); j++ {
+			if d.stmts[j].kind != tomlTrivia {
+				remove[i] = remove[j] && hasPathPrefix(d.stmts[j].table, path)
+				break
+			}
+			if j == len(d.stmts)-1 {
+				remove[i] = true
+			}
+		}
+	}
+
+	var kept []tomlStmt
+	for i, stmt := range d.stmts {
+		if remove[i] {
+			continue
+		}
+		// A document no longer starts with a blank line
+		if len(kept) == 0 && remove[0] && stmt.kind == tomlTrivia && strings.TrimSpace(stmt.text) == "" {
+			continue
+		}
+		kept = append(kept, stmt)
+	}
+	d.stmts = kept
+	return true
+}
+
+// expandInline replaces the key/value pair at statement i with a table
+// holding its members, when its value is an inline table, or removes it
+func (d *tomlDoc) expandInline(i int) error {
+	stmt := d.stmts[i]
+	var v map[string]interface{}
+	if err := toml.Unmarshal([]byte("v = "+stmt.text[stmt.valueStart:stmt.valueEnd]), &v); err != nil {
+		return fmt.Errorf("expanding %s: %w", formatTOMLKey(stmt.key), err)
+	}
+	d.removeAt(i)
+	if table, ok := v["v"].(map[string]interface{}); ok {
+		return setTOMLTree(d, stmt.key, table)
+	}
+	return nil
+}
+
+// keyValueStmt is a new key-value line, prefix being the key and " = "
+func keyValueStmt(prefix, literal string, table, key []string) tomlStmt {
+	return tomlStmt{
+		kind:       tomlKeyValue,
+		text:       prefix + literal + "\n",
+		table:      table,
+		key:        key,
+		valueStart: len(prefix),
+		valueEnd:   len(prefix) + len(literal),
+	}
+}
+
+func (d *tomlDoc) insertAt(i int, stmts ...tomlStmt) {
+	d.stmts = append(d.stmts[:i], append(stmts, d.stmts[i:]...)...)
+}
+
+func (d *tomlDoc) removeAt(i int) {
+	d.stmts = append(d.stmts[:i], d.stmts[i+1:]...)
+}
+
+func (d *tomlDoc) ensureTrailingNewline() {
+	if n := len(d.stmts); n > 0 && !strings.HasSuffix(d.stmts[n-1].text, "\n") {
+		d.stmts[n-1].text += "\n"
+	}
+}
+
+// tomlLiteral formats a value the way go-toml writes it
+func tomlLiteral(value interface{}) (string, error) {
+	out, err := toml.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(out), "v = "), "\n"), nil
+}
+
+func formatTOMLKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		if bareTOMLKey.MatchString(part) {
+			parts[i] = part
+		} else {
+			parts[i] = strconv.Quote(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// commonPrefix returns how many leading parts a and b share
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func pathEqual(a, b []string) bool {
+	return len(a) == len(b) && hasPathPrefix(a, b)
+}
+
+// hasPathPrefix reports whether prefix is path or one of its parents
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
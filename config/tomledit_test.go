@@ -0,0 +1,254 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func editTOML(t *testing.T, input string, edit func(*tomlDoc)) string {
+	t.Helper()
+	doc, err := parseTOML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit(doc)
+	out := string(doc.Bytes())
+	if err := toml.Unmarshal([]byte(out), new(map[string]interface{})); err != nil {
+		t.Fatalf("edit produced invalid TOML: %v\n%s", err, out)
+	}
+	return out
+}
+
+func set(t *testing.T, doc *tomlDoc, key string, value interface{}) {
+	t.Helper()
+	if err := doc.Set(strings.Split(key, "."), value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTOMLSetKeepsLayout(t *testing.T) {
+	input := `# my settings
+model   = "o3"    # pinned
+notes = """
+[otel] is not a table in here # nor a comment
+"""
+servers = [
+  "a", # first
+  "b]",
+]
+
+[otel]
+environment = "dev"   # staging box
+custom = { a = 1 }
+
+[profiles."fast one"]
+model = 'mini'
+`
+	got := editTOML(t, input, func(doc *tomlDoc) {
+		set(t, doc, "otel.environment", "prod")
+		set(t, doc, "otel.log_user_prompt", false)
+		set(t, doc, "otel.exporter.otlp-http.endpoint", "https://x/v1/logs")
+		set(t, doc, "otel.exporter.otlp-http.headers.Authorization", "Bearer abc")
+		set(t, doc, "profiles.fast one.model", "o4")
+	})
+
+	want := `# my settings
+model   = "o3"    # pinned
+notes = """
+[otel] is not a table in here # nor a comment
+"""
+servers = [
+  "a", # first
+  "b]",
+]
+
+[otel]
+environment = 'prod'   # staging box
+custom = { a = 1 }
+log_user_prompt = false
+
+[otel.exporter.otlp-http]
+endpoint = 'https://x/v1/logs'
+
+[otel.exporter.otlp-http.headers]
+Authorization = 'Bearer abc'
+
+[profiles."fast one"]
+model = 'o4'
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTOMLSetDottedAndInline(t *testing.T) {
+	got := editTOML(t, "[otel]\n  exporter.otlp-http.endpoint = \"mine\"\n  trace_exporter = { otlp-http = { endpoint = \"old\" } }\n", func(doc *tomlDoc) {
+		set(t, doc, "otel.exporter.otlp-http.headers.Authorization", "Bearer abc")
+		set(t, doc, "otel.trace_exporter.otlp-http.endpoint", "new")
+	})
+
+	want := `[otel]
+  exporter.otlp-http.endpoint = "mine"
+  exporter.otlp-http.headers.Authorization = 'Bearer abc'
+
+[otel.trace_exporter.otlp-http]
+endpoint = 'new'
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTOMLExpandsInlineTable(t *testing.T) {
+	input := "model = \"o3\"\notel = { environment = \"dev\", metrics_exporter = \"none\", exporter = { otlp-http = { endpoint = \"old\", headers = { x-key = \"1\" } } } }\n"
+	got := editTOML(t, input, func(doc *tomlDoc) {
+		doc.Remove([]string{"otel", "exporter"})
+		set(t, doc, "otel.log_user_prompt", false)
+	})
+	want := `model = "o3"
+
+[otel]
+environment = 'dev'
+metrics_exporter = 'none'
+log_user_prompt = false
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTOMLSetEmptyAndUnterminated(t *testing.T) {
+	got := editTOML(t, `model = "o3"`, func(doc *tomlDoc) {
+		set(t, doc, "otel.environment", "prod")
+	})
+	if want := "model = \"o3\"\n\n[otel]\nenvironment = 'prod'\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = editTOML(t, "", func(doc *tomlDoc) {
+		set(t, doc, "otel.environment", "prod")
+	})
+	if want := "[otel]\nenvironment = 'prod'\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := parseTOML([]byte("[otel\n")); err == nil {
+		t.Error("parsed invalid TOML")
+	}
+}
+
+func TestTOMLSetTwice(t *testing.T) {
+	got := editTOML(t, "[otel]\n", func(doc *tomlDoc) {
+		set(t, doc, "otel.environment", "dev")
+		set(t, doc, "otel.environment", "prod")
+		set(t, doc, "otel.exporter.otlp-http.endpoint", "a")
+		set(t, doc, "otel.exporter.otlp-http.endpoint", "b")
+	})
+	if want := "[otel]\nenvironment = 'prod'\n\n[otel.exporter.otlp-http]\nendpoint = 'b'\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTOMLRemove(t *testing.T) {
+	input := `model = "o3"
+
+[otel]
+# exporters
+log_user_prompt = true
+
+[otel.exporter.otlp-http]
+endpoint = "https://x"
+headers = { Authorization = "Bearer abc" }
+
+[[otel.extra]]
+name = "a"
+
+# fast profile
+[profiles.fast]
+model = "mini"
+otel = { environment = "fast" }
+`
+	got := editTOML(t, input, func(doc *tomlDoc) {
+		if !doc.Remove([]string{"otel"}) {
+			t.Error("nothing removed")
+		}
+	})
+	want := `model = "o3"
+
+# fast profile
+[profiles.fast]
+model = "mini"
+otel = { environment = "fast" }
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	editTOML(t, want, func(doc *tomlDoc) {
+		if doc.Remove([]string{"otel"}) {
+			t.Error("removed from a file without otel")
+		}
+	})
+
+	// Dotted keys and inline tables at the top level
+	for _, input := range []string{
+		"model = \"o3\"\notel.environment = \"dev\"\notel.exporter.otlp-http.endpoint = \"https://x\"\n",
+		"model = \"o3\"\notel = { environment = \"dev\" }\n",
+	} {
+		got := editTOML(t, input, func(doc *tomlDoc) { doc.Remove([]string{"otel"}) })
+		if got != "model = \"o3\"\n" {
+			t.Errorf("Remove(%q) = %q", input, got)
+		}
+	}
+}
+
+func TestCodexTelemetryRoundTrip(t *testing.T) {
+	useTempHome(t)
+	original := "# Codex\nmodel = \"o3\" # pinned\n\n[profiles.fast]\nmodel = \"mini\"\n"
+	path := CodexConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	if err := WritePrivateFile(path, []byte(original)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnableCodexTelemetry("abc", "https://collector.example.com", TelemetrySettings{}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, err := LoadCodexOtelRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveCodexTelemetry(path, record); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != original {
+		t.Errorf("after enable and remove:\n%s\nwant the original:\n%s", got, original)
+	}
+}
+
+func TestCodexTelemetryKeepsInlineOtel(t *testing.T) {
+	useTempHome(t)
+	path := CodexConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0700)
+	WritePrivateFile(path, []byte("otel = { environment = \"dev\", metrics_exporter = \"none\" }\n"))
+
+	if err := EnableCodexTelemetry("abc", "https://collector.example.com", TelemetrySettings{}, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if otel := codexOtelTable(data); otel["metrics_exporter"] != "none" || otel["exporter"] == nil {
+		t.Errorf("otel after enable = %v", otel)
+	}
+	record, _ := LoadCodexOtelRecord()
+	if err := RemoveCodexTelemetry(path, record); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if otel := codexOtelTable(data); len(otel) != 2 || otel["environment"] != "dev" || otel["metrics_exporter"] != "none" {
+		t.Errorf("otel after remove = %v", otel)
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// backupSuffix names the shell config backups taken by shell.InstallAliases
+const backupSuffix = ".jtpck-backup"
+
+// LayoutMove is a file or directory moved between ~/.jtpck and the XDG
+// directories
+type LayoutMove struct {
+	From string
+	To   string
+}
+
+// xdgDirFor returns the XDG directory an entry of ~/.jtpck belongs in.
+// Anything not listed is a wrapper or spool and goes to the data directory.
+func xdgDirFor(name string) string {
+	switch name {
+	case "config.json", "credentials", "credentials.age", "hooks":
+		return xdgConfigDir()
+	case "paused", "health.json", "codex-otel.json", "claude-settings.json", "gemini-settings.json":
+		return xdgStateDir()
+	}
+	return xdgDataDir()
+}
+
+// isWrapper reports whether an entry of ~/.jtpck is a wrapper script
+func isWrapper(name string) bool {
+	return strings.HasSuffix(name, "-wrapper")
+}
+
+// XDGMigrationPlan lists the moves MigrateToXDG would make
+func XDGMigrationPlan() ([]LayoutMove, error) {
+	entries, err := os.ReadDir(LegacyDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var moves []LayoutMove
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == legacyPinFile || entry.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+		moves = append(moves, LayoutMove{
+			From: filepath.Join(LegacyDir(), name),
+			To:   filepath.Join(xdgDirFor(name), name),
+		})
+	}
+
+	home := HomeDir()
+	backups, _ := filepath.Glob(filepath.Join(home, ".*"+backupSuffix))
+	for _, backup := range backups {
+		moves = append(moves, LayoutMove{
+			From: backup,
+			To:   filepath.Join(xdgStateDir(), "backups", filepath.Base(backup)),
+		})
+	}
+	return moves, nil
+}
+
+// MigrateToXDG moves a ~/.jtpck install into the XDG directories. Wrapper
+// scripts are replaced by symlinks to their new location so shells still
+// holding the old aliases keep working; the caller regenerates the wrappers
+// and alias block. RevertXDG undoes the move.
+func MigrateToXDG() ([]LayoutMove, error) {
+	moves, err := XDGMigrationPlan()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMoves(moves); err != nil {
+		return nil, err
+	}
+
+	for _, move := range moves {
+		if isWrapper(filepath.Base(move.From)) {
+			if err := os.Symlink(InTarget(move.To), move.From); err != nil {
+				return moves, fmt.Errorf("linking %s: %w", move.From, err)
+			}
+		}
+	}
+
+	if err := os.Remove(filepath.Join(LegacyDir(), legacyPinFile)); err != nil && !os.IsNotExist(err) {
+		return moves, err
+	}
+	return moves, nil
+}
+
+// XDGRevertPlan lists the moves RevertXDG would make
+func XDGRevertPlan() ([]LayoutMove, error) {
+	home := HomeDir()
+	backupDir := filepath.Join(xdgStateDir(), "backups")
+
+	var moves []LayoutMove
+	for _, dir := range []string{xdgConfigDir(), xdgStateDir(), xdgDataDir()} {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			from := filepath.Join(dir, entry.Name())
+			if from == backupDir {
+				continue
+			}
+			moves = append(moves, LayoutMove{From: from, To: filepath.Join(LegacyDir(), entry.Name())})
+		}
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(backupDir, "*"+backupSuffix))
+	for _, backup := range backups {
+		moves = append(moves, LayoutMove{From: backup, To: filepath.Join(home, filepath.Base(backup))})
+	}
+	return moves, nil
+}
+
+// RevertXDG moves an XDG install back into ~/.jtpck and pins it there so it
+// is not migrated again. The caller regenerates the wrappers and alias block.
+func RevertXDG() ([]LayoutMove, error) {
+	moves, err := XDGRevertPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the compatibility symlinks MigrateToXDG left behind
+	entries, _ := os.ReadDir(LegacyDir())
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink != 0 && isWrapper(entry.Name()) {
+			if err := os.Remove(filepath.Join(LegacyDir(), entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := applyMoves(moves); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{filepath.Join(xdgStateDir(), "backups"), xdgConfigDir(), xdgStateDir(), xdgDataDir()} {
+		os.Remove(dir) // only succeeds once empty
+	}
+
+	if err := WritePrivateFile(filepath.Join(LegacyDir(), legacyPinFile), nil); err != nil {
+		return moves, err
+	}
+	return moves, nil
+}
+
+// applyMoves renames every entry, refusing to start if any destination
+// already exists so that nothing is overwritten. When a move fails, the
+// ones already made are undone.
+func applyMoves(moves []LayoutMove) error {
+	for _, move := range moves {
+		if _, err := os.Lstat(move.To); err == nil {
+			return fmt.Errorf("%s already exists; move or remove it and try again", move.To)
+		}
+	}
+
+	for i, move := range moves {
+		err := os.MkdirAll(filepath.Dir(move.To), 0700)
+		if err == nil {
+			err = moveEntry(move.From, move.To)
+		}
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if undo := moveEntry(moves[j].To, moves[j].From); undo != nil {
+				return fmt.Errorf("moving %s: %w (and could not move %s back: %v)", move.From, err, moves[j].To, undo)
+			}
+		}
+		return fmt.Errorf("moving %s: %w", move.From, err)
+	}
+	return nil
+}
+
+// renameEntry is os.Rename, swapped in tests
+var renameEntry = os.Rename
+
+// moveEntry renames a file or directory, copying it and removing the
+// original when the two are on different filesystems
+func moveEntry(from, to string) error {
+	err := renameEntry(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(from, to); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyTree copies a file, symlink or directory with its contents, keeping
+// permissions
+func copyTree(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(to, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.Mkdir(dest, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(dest, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dest)
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(dest, data, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(dest, info.Mode().Perm())
+		}
+		return fmt.Errorf("cannot copy %s: not a file, directory or symlink", path)
+	})
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// legacyInstall writes a ~/.jtpck install with a wrapper, hooks and an rc
+// backup
+func legacyInstall(t *testing.T, home string) {
+	t.Helper()
+	files := map[string]string{
+		".jtpck/config.json":                `{"version":3}`,
+		".jtpck/credentials":                "default=abc\n",
+		".jtpck/paused":                     "0",
+		".jtpck/claude-wrapper":             "#!/bin/sh\n",
+		".jtpck/hooks/pre-claude.d/01-note": "#!/bin/sh\n",
+		".zshrc" + backupSuffix:             "# rc\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(home, rel)
+		os.MkdirAll(filepath.Dir(path), 0700)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Chmod(filepath.Join(home, ".jtpck/claude-wrapper"), 0700)
+}
+
+func TestMigrateAndRevertXDG(t *testing.T) {
+	home := useTempHome(t)
+	legacyInstall(t, home)
+
+	moves, err := MigrateToXDG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 6 {
+		t.Errorf("moves = %v", moves)
+	}
+	if UsesLegacyLayout() || ConfigDir() != filepath.Join(home, ".config", "jtpck") {
+		t.Fatalf("still in the legacy layout: %s", ConfigDir())
+	}
+	for _, path := range []string{
+		filepath.Join(home, ".config/jtpck/credentials"),
+		filepath.Join(home, ".config/jtpck/hooks/pre-claude.d/01-note"),
+		filepath.Join(home, ".local/state/jtpck/paused"),
+		filepath.Join(home, ".local/state/jtpck/backups/.zshrc"+backupSuffix),
+		filepath.Join(home, ".local/share/jtpck/claude-wrapper"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("not moved: %v", err)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(home, ".jtpck/claude-wrapper")); err != nil || target != filepath.Join(home, ".local/share/jtpck/claude-wrapper") {
+		t.Errorf("wrapper link = %q, %v", target, err)
+	}
+
+	if _, err := RevertXDG(); err != nil {
+		t.Fatal(err)
+	}
+	if !UsesLegacyLayout() || !LegacyLayoutPinned() {
+		t.Error("revert did not pin the legacy layout")
+	}
+	if info, err := os.Lstat(filepath.Join(home, ".jtpck/claude-wrapper")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("wrapper after revert: %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".zshrc"+backupSuffix)); err != nil {
+		t.Errorf("rc backup not put back: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config/jtpck")); !os.IsNotExist(err) {
+		t.Errorf("empty XDG config dir left behind: %v", err)
+	}
+}
+
+func TestApplyMovesRollsBack(t *testing.T) {
+	home := useTempHome(t)
+	legacyInstall(t, home)
+	moves, _ := XDGMigrationPlan()
+
+	// The third move fails; the first two go back
+	calls := 0
+	renameEntry = func(from, to string) error {
+		if calls++; calls == 3 {
+			return errors.New("disk on fire")
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameEntry = os.Rename })
+
+	if err := applyMoves(moves); err == nil {
+		t.Fatal("a failed move was not reported")
+	}
+	for _, move := range moves {
+		if _, err := os.Lstat(move.From); err != nil {
+			t.Errorf("%s was not moved back: %v", move.From, err)
+		}
+		if _, err := os.Lstat(move.To); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", move.To, err)
+		}
+	}
+}
+
+func TestMoveEntryAcrossFilesystems(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "hooks"), filepath.Join(dir, "moved")
+	os.MkdirAll(filepath.Join(from, "pre-claude.d"), 0700)
+	os.WriteFile(filepath.Join(from, "pre-claude.d", "01-note"), []byte("#!/bin/sh\n"), 0755)
+	os.Symlink("pre-claude.d", filepath.Join(from, "link"))
+
+	renameEntry = func(string, string) error { return &os.LinkError{Op: "rename", Err: syscall.EXDEV} }
+	t.Cleanup(func() { renameEntry = os.Rename })
+
+	if err := moveEntry(from, to); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Errorf("original left behind: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(to, "pre-claude.d", "01-note")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("copied hook = %v, %v", info, err)
+	}
+	if target, _ := os.Readlink(filepath.Join(to, "link")); target != "pre-claude.d" {
+		t.Errorf("copied link points at %q", target)
+	}
+}
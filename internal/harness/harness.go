@@ -0,0 +1,286 @@
+// Package harness runs jtpck against a throwaway home directory for tests:
+// fake claude, codex and gemini binaries that record how they were
+// launched, scripted keys for the interactive screens and golden files for
+// everything setup writes.
+package harness
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Env is an isolated home with fake tools on PATH
+type Env struct {
+	// Home is the temporary $HOME
+	Home string
+	// Bin holds the fake tools and is the only entry on PATH
+	Bin string
+	// Log is where the fake tools record their calls
+	Log string
+}
+
+// New creates a temporary home, clears the XDG variables so the default
+// layout applies, and puts a recording fake of each tool on PATH. The
+// shell is zsh so setup writes ~/.zshrc.
+func New(t *testing.T, tools ...string) *Env {
+	t.Helper()
+	root := t.TempDir()
+	e := &Env{
+		Home: filepath.Join(root, "home"),
+		Bin:  filepath.Join(root, "bin"),
+		Log:  filepath.Join(root, "log"),
+	}
+	for _, dir := range []string{e.Home, e.Bin, e.Log} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("HOME", e.Home)
+	t.Setenv("SHELL", "/bin/zsh")
+	t.Setenv("PATH", e.Bin+string(os.PathListSeparator)+"/usr/bin"+string(os.PathListSeparator)+"/bin")
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_DATA_HOME"} {
+		t.Setenv(name, "")
+	}
+	// Settings and telemetry variables of the real environment would leak
+	// into the tools' launch environment
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, prefix := range []string{"JTPCK_", "OTEL_", "CLAUDE_", "CODEX_", "GEMINI_"} {
+			if strings.HasPrefix(name, prefix) {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+		}
+	}
+
+	for _, tool := range tools {
+		e.FakeTool(t, tool)
+	}
+	return e
+}
+
+// FakeTool installs an executable that records its arguments and
+// environment under Log and exits 0
+func (e *Env) FakeTool(t *testing.T, name string) {
+	t.Helper()
+	script := fmt.Sprintf(`#!/bin/sh
+printf '%%s\n' "$@" > "%[1]s/%[2]s.argv"
+env > "%[1]s/%[2]s.env"
+`, e.Log, name)
+	if err := os.WriteFile(filepath.Join(e.Bin, name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Call is one recorded launch of a fake tool
+type Call struct {
+	Args []string
+	Env  map[string]string
+}
+
+// LastCall returns the most recent launch of a fake tool
+func (e *Env) LastCall(t *testing.T, name string) Call {
+	t.Helper()
+	argv, err := os.ReadFile(filepath.Join(e.Log, name+".argv"))
+	if err != nil {
+		t.Fatalf("%s was not run: %v", name, err)
+	}
+	call := Call{Env: map[string]string{}}
+	if s := strings.TrimSuffix(string(argv), "\n"); s != "" {
+		call.Args = strings.Split(s, "\n")
+	}
+
+	f, err := os.Open(filepath.Join(e.Log, name+".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			call.Env[key] = value
+		}
+	}
+	return call
+}
+
+// Path returns a path inside the home
+func (e *Env) Path(rel string) string {
+	return filepath.Join(e.Home, rel)
+}
+
+// Read returns a file inside the home, or "" when it does not exist
+func (e *Env) Read(t *testing.T, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(e.Path(rel))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Normalize replaces the machine-specific parts of generated files, the
+// temporary directories and generation timestamps, with stable names
+func (e *Env) Normalize(s string, extra ...string) string {
+	pairs := append([]string{e.Home, "$HOME", e.Bin, "$BIN", e.Log, "$LOG"}, extra...)
+	s = strings.NewReplacer(pairs...).Replace(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# Generated: ") {
+			lines[i] = "# Generated: <time>"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Golden compares got with testdata/<name>.golden, or rewrites the file
+// when the tests run with -update
+func Golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s (run go test -update to accept):\n%s", name, path, diff(string(want), got))
+	}
+}
+
+// diff lists the lines only in want (-) or only in got (+)
+func diff(want, got string) string {
+	count := map[string]int{}
+	for _, line := range strings.Split(got, "\n") {
+		count[line]++
+	}
+	var out []string
+	for _, line := range strings.Split(want, "\n") {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		out = append(out, "- "+line)
+	}
+	var added []string
+	for line, n := range count {
+		for ; n > 0; n-- {
+			added = append(added, "+ "+line)
+		}
+	}
+	sort.Strings(added)
+	return strings.Join(append(out, added...), "\n")
+}
+
+// Keys turns typed text into key messages. Lines end with Enter, and
+// "<esc>", "<ctrl+c>" and "<backspace>" stand for those keys.
+func Keys(text string) []tea.Msg {
+	special := map[string]tea.KeyType{
+		"<esc>":       tea.KeyEsc,
+		"<ctrl+c>":    tea.KeyCtrlC,
+		"<backspace>": tea.KeyBackspace,
+	}
+	var msgs []tea.Msg
+	for text != "" {
+		matched := false
+		for name, key := range special {
+			if strings.HasPrefix(text, name) {
+				msgs = append(msgs, tea.KeyMsg{Type: key})
+				text = text[len(name):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r := []rune(text)[0]
+		if r == '\n' {
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEnter})
+		} else {
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		text = text[len(string(r)):]
+	}
+	return msgs
+}
+
+// Drive sends msgs to a model in order, as a running program would, and
+// reports whether the model asked to quit. It stops at the quit and does
+// not run the model's other commands, so timers never fire on their own.
+func Drive(model tea.Model, msgs ...tea.Msg) (tea.Model, bool) {
+	for _, msg := range msgs {
+		var cmd tea.Cmd
+		model, cmd = model.Update(msg)
+		if Quits(cmd) {
+			return model, true
+		}
+	}
+	return model, false
+}
+
+// Quits reports whether cmd is tea.Quit
+func Quits(cmd tea.Cmd) bool {
+	return cmd != nil && reflect.ValueOf(cmd).Pointer() == reflect.ValueOf(tea.Quit).Pointer()
+}
+
+// Screens stands in for running interactive programs: each call takes
+// the next script of messages and drives the model with it
+type Screens struct {
+	t       *testing.T
+	scripts [][]tea.Msg
+	// Shown lists the type of each model run, in order
+	Shown []string
+}
+
+// NewScreens scripts the next interactive programs, one script each
+func NewScreens(t *testing.T, scripts ...[]tea.Msg) *Screens {
+	return &Screens{t: t, scripts: scripts}
+}
+
+// Run has the signature of a function that runs a program to completion
+func (s *Screens) Run(model tea.Model, _ ...tea.ProgramOption) (tea.Model, error) {
+	s.t.Helper()
+	s.Shown = append(s.Shown, fmt.Sprintf("%T", model))
+	if len(s.scripts) == 0 {
+		s.t.Fatalf("unexpected screen %T", model)
+	}
+	script := s.scripts[0]
+	s.scripts = s.scripts[1:]
+
+	final, quit := Drive(model, script...)
+	if !quit {
+		s.t.Fatalf("%T did not quit after its script", model)
+	}
+	return final, nil
+}
+
+// Done fails the test if scripts were left unused
+func (s *Screens) Done() {
+	s.t.Helper()
+	if len(s.scripts) > 0 {
+		s.t.Errorf("%d scripted screens were never shown (shown: %v)", len(s.scripts), s.Shown)
+	}
+}
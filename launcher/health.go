@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	probeTimeout = time.Second
+	// Reachable endpoints are trusted for longer than unreachable ones so a
+	// laptop coming back online recovers quickly
+	healthyTTL   = 5 * time.Minute
+	unhealthyTTL = 30 * time.Second
+)
+
+// healthEntry is one cached probe result
+type healthEntry struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func (e healthEntry) fresh() bool {
+	ttl := unhealthyTTL
+	if e.Healthy {
+		ttl = healthyTTL
+	}
+	return time.Since(e.CheckedAt) < ttl
+}
+
+// SelectEndpoint returns the first reachable endpoint in order, probing those
+// without a fresh cached result in parallel. It reports false when none are.
+func SelectEndpoint(endpoints []string, cachePath string) (string, bool) {
+	cache := loadHealthCache(cachePath)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	probed := false
+	for _, endpoint := range endpoints {
+		if entry, ok := cache[endpoint]; ok && entry.fresh() {
+			continue
+		}
+		probed = true
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			entry := healthEntry{Healthy: probe(endpoint), CheckedAt: time.Now()}
+			mu.Lock()
+			cache[endpoint] = entry
+			mu.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+
+	if probed {
+		saveHealthCache(cachePath, cache)
+	}
+
+	for _, endpoint := range endpoints {
+		if cache[endpoint].Healthy {
+			return endpoint, true
+		}
+	}
+	return "", false
+}
+
+// probe treats any HTTP response below 500 as reachable: the endpoint only
+// accepts OTLP POSTs, so a 404 or 405 still proves the collector is up.
+func probe(endpoint string) bool {
+	client := &http.Client{Timeout: probeTimeout}
+	resp, err := client.Head(endpoint)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 500
+}
+
+func loadHealthCache(path string) map[string]healthEntry {
+	cache := map[string]healthEntry{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// saveHealthCache is best effort; a failed write only costs a re-probe
+func saveHealthCache(path string, cache map[string]healthEntry) {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,84 @@
+package launcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collectorWithStatus answers every request with status and counts them
+func collectorWithStatus(t *testing.T, status int) (*httptest.Server, *int32) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestProbe(t *testing.T) {
+	for status, want := range map[int]bool{200: true, 404: true, 405: true, 500: false, 503: false} {
+		server, _ := collectorWithStatus(t, status)
+		if got := probe(server.URL); got != want {
+			t.Errorf("probe of a %d collector = %v, want %v", status, got, want)
+		}
+	}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	if probe(down.URL) {
+		t.Error("a closed collector probed as reachable")
+	}
+}
+
+func TestSelectEndpointFailsOver(t *testing.T) {
+	broken, _ := collectorWithStatus(t, http.StatusServiceUnavailable)
+	primary, _ := collectorWithStatus(t, http.StatusMethodNotAllowed)
+	secondary, _ := collectorWithStatus(t, http.StatusNotFound)
+	cache := filepath.Join(t.TempDir(), "state", "health.json")
+
+	if got, ok := SelectEndpoint([]string{broken.URL, secondary.URL, primary.URL}, cache); !ok || got != secondary.URL {
+		t.Errorf("SelectEndpoint() = %q, %v, want the first reachable %q", got, ok, secondary.URL)
+	}
+	entries := loadHealthCache(cache)
+	if len(entries) != 3 || entries[broken.URL].Healthy || !entries[primary.URL].Healthy {
+		t.Errorf("cache = %v", entries)
+	}
+
+	broken.Close()
+	secondary.Close()
+	if got, ok := SelectEndpoint([]string{broken.URL, secondary.URL}, filepath.Join(t.TempDir(), "health.json")); ok {
+		t.Errorf("SelectEndpoint() = %q with no collector up", got)
+	}
+}
+
+func TestSelectEndpointUsesCache(t *testing.T) {
+	up, upHits := collectorWithStatus(t, http.StatusOK)
+	down, downHits := collectorWithStatus(t, http.StatusOK)
+	stale, staleHits := collectorWithStatus(t, http.StatusOK)
+	cache := filepath.Join(t.TempDir(), "health.json")
+	saveHealthCache(cache, map[string]healthEntry{
+		up.URL:    {Healthy: true, CheckedAt: time.Now().Add(-time.Minute)},
+		down.URL:  {Healthy: false, CheckedAt: time.Now().Add(-time.Second)},
+		stale.URL: {Healthy: false, CheckedAt: time.Now().Add(-time.Minute)},
+	})
+
+	// A recent failure is trusted even though the collector is back...
+	if got, _ := SelectEndpoint([]string{down.URL, up.URL}, cache); got != up.URL {
+		t.Errorf("SelectEndpoint() = %q, want the cached healthy %q", got, up.URL)
+	}
+	if atomic.LoadInt32(upHits)+atomic.LoadInt32(downHits) != 0 {
+		t.Error("fresh cache entries were probed again")
+	}
+	// ...but an old one is probed again, and the result cached
+	if got, _ := SelectEndpoint([]string{stale.URL, up.URL}, cache); got != stale.URL {
+		t.Errorf("SelectEndpoint() = %q, want the re-probed %q", got, stale.URL)
+	}
+	if atomic.LoadInt32(staleHits) != 1 || !loadHealthCache(cache)[stale.URL].Healthy {
+		t.Errorf("stale entry probed %d times, cached %v", atomic.LoadInt32(staleHits), loadHealthCache(cache)[stale.URL])
+	}
+}
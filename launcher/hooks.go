@@ -0,0 +1,113 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultHookTimeout bounds each hook when the config does not set one
+const DefaultHookTimeout = 10 * time.Second
+
+// HookPhase identifies when a hook runs relative to the tool
+type HookPhase string
+
+const (
+	PreLaunch HookPhase = "pre"
+	PostExit  HookPhase = "post"
+)
+
+// hookDir returns the directory holding hooks for a phase and tool,
+// e.g. ~/.jtpck/hooks/pre-claude.d
+func hookDir(hooksDir string, phase HookPhase, tool string) string {
+	return filepath.Join(hooksDir, fmt.Sprintf("%s-%s.d", phase, tool))
+}
+
+// findHooks lists the executable files in a hook directory in lexical order,
+// so hooks can be sequenced with numeric prefixes (10-branch, 20-timer).
+func findHooks(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var hooks []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		hooks = append(hooks, path)
+	}
+	sort.Strings(hooks)
+	return hooks
+}
+
+// hookEnv returns the session metadata passed to hooks
+func hookEnv(phase HookPhase, opts Options, s *Session) []string {
+	cwd, _ := os.Getwd()
+	env := append(os.Environ(),
+		"JTPCK_HOOK_PHASE="+string(phase),
+		"JTPCK_SESSION_ID="+s.ID,
+		"JTPCK_TOOL="+opts.Tool,
+		"JTPCK_TOOL_PATH="+opts.ToolPath,
+		"JTPCK_CWD="+cwd,
+		"JTPCK_SESSION_START="+s.Start.Format(time.RFC3339),
+	)
+	if phase == PostExit {
+		env = append(env,
+			"JTPCK_SESSION_END="+s.End.Format(time.RFC3339),
+			"JTPCK_EXIT_CODE="+strconv.Itoa(s.ExitCode),
+			"JTPCK_SESSION_DURATION_MS="+strconv.FormatInt(s.Duration().Milliseconds(), 10),
+		)
+	}
+	return env
+}
+
+// runHooks runs every hook for the phase. Hooks get no stdin and write to
+// our stderr; a failing or slow hook is reported and never stops the tool.
+func runHooks(phase HookPhase, opts Options, s *Session) {
+	timeout := opts.HookTimeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+
+	env := hookEnv(phase, opts, s)
+	for _, hook := range findHooks(hookDir(opts.HooksDir, phase, opts.Tool)) {
+		if err := runHook(hook, env, timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "jtpck: %s hook %s failed: %v\n", phase, filepath.Base(hook), err)
+		}
+	}
+}
+
+func runHook(path string, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	// Own process group so a timeout also kills anything the hook spawned
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFindHooks(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "20-timer", "")
+	writeScript(t, dir, "10-branch", "")
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "30-dir"), 0755)
+
+	want := []string{filepath.Join(dir, "10-branch"), filepath.Join(dir, "20-timer")}
+	if got := findHooks(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("findHooks() = %q, want %q", got, want)
+	}
+	if got := findHooks(filepath.Join(dir, "missing")); got != nil {
+		t.Errorf("missing directory: %q", got)
+	}
+}
+
+func TestRunHooksAroundTool(t *testing.T) {
+	t.Setenv(SessionEnvVar, "")
+	dir := t.TempDir()
+	hooks := filepath.Join(dir, "hooks")
+	out := filepath.Join(dir, "out")
+	writeScript(t, filepath.Join(hooks, "pre-codex.d"), "10-pre", `echo "pre $JTPCK_HOOK_PHASE $JTPCK_TOOL $FROM_CONFIG" >> `+out+"\n")
+	writeScript(t, filepath.Join(hooks, "post-codex.d"), "10-fail", "exit 1\n")
+	writeScript(t, filepath.Join(hooks, "post-codex.d"), "20-post", `echo "post $JTPCK_EXIT_CODE $JTPCK_SESSION_ID" >> `+out+"\n")
+	tool := writeScript(t, dir, "codex", `echo "tool $JTPCK_SESSION_ID" >> `+out+"\nexit 4\n")
+
+	if code := Run(Options{Tool: "codex", ToolPath: tool, HooksDir: hooks, Env: map[string]string{"FROM_CONFIG": "yes"}}); code != 4 {
+		t.Fatalf("exit code = %d, want 4", code)
+	}
+	data, _ := os.ReadFile(out)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "pre pre codex yes" {
+		t.Fatalf("hooks ran as %q", lines)
+	}
+	id := strings.TrimPrefix(lines[1], "tool ")
+	if len(id) != 32 || lines[2] != "post 4 "+id {
+		t.Errorf("tool and post hook saw %q", lines[1:])
+	}
+}
+
+func TestRunHookTimeoutKillsGroup(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "pid")
+	hook := writeScript(t, dir, "slow", "sleep 30 &\necho $! > "+pidFile+"\nwait\n")
+
+	start := time.Now()
+	err := runHook(hook, os.Environ(), 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("runHook() = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runHook took %s", elapsed)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	for deadline := time.Now().Add(2 * time.Second); running(pid); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d the hook started is still running", pid)
+		}
+	}
+}
+
+// running reports whether a process exists and is not a zombie waiting for
+// its parent to reap it
+func running(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		// No /proc on this platform: the process exists
+		_, err := os.Stat("/proc/self")
+		return err != nil
+	}
+	_, rest, _ := strings.Cut(string(stat), ") ")
+	return !strings.HasPrefix(rest, "Z")
+}
@@ -0,0 +1,143 @@
+package launcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// SessionEnvVar exposes the session ID to the tool
+const SessionEnvVar = "JTPCK_SESSION_ID"
+
+// Options configures a launch
+type Options struct {
+	Tool     string
+	ToolPath string
+	Args     []string
+	Endpoint string
+	UserID   string
+	Version  string
+	// Env is set on top of the caller's environment for the tool and hooks
+	Env map[string]string
+
+	// Supervise reports the session to the endpoint once the tool exits
+	Supervise bool
+	// HooksDir holds the pre-<tool>.d and post-<tool>.d hook directories
+	HooksDir string
+	// HookTimeout bounds each hook (DefaultHookTimeout when zero)
+	HookTimeout time.Duration
+	// Spool receives exports while offline; it must outlive the tool
+	Spool *Spool
+	// Relay forwards exports to several collectors; it must outlive the tool
+	Relay *Relay
+}
+
+// Run launches the tool, running any hooks around it, and returns the exit
+// code the wrapper should use. Without post-exit hooks or supervision the
+// tool replaces this process, exactly as a plain wrapper exec would.
+func Run(opts Options) int {
+	session := newSession(opts.Tool)
+	for key, value := range opts.Env {
+		os.Setenv(key, value)
+	}
+	os.Setenv(SessionEnvVar, session.ID)
+	env := os.Environ()
+
+	runHooks(PreLaunch, opts, session)
+
+	postHooks := findHooks(hookDir(opts.HooksDir, PostExit, opts.Tool))
+	if !opts.Supervise && len(postHooks) == 0 && opts.Spool == nil && opts.Relay == nil {
+		argv := append([]string{opts.ToolPath}, opts.Args...)
+		err := syscall.Exec(opts.ToolPath, argv, env)
+		fmt.Fprintf(os.Stderr, "jtpck: failed to start %s: %v\n", opts.Tool, err)
+		return 127
+	}
+
+	var versionCh chan string
+	if opts.Supervise {
+		versionCh = make(chan string, 1)
+		go func() { versionCh <- toolVersion(opts.ToolPath) }()
+	}
+
+	session.ExitCode = supervise(opts, env)
+	session.End = time.Now()
+
+	runHooks(PostExit, opts, session)
+
+	if opts.Supervise {
+		select {
+		case session.ToolVersion = <-versionCh:
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		if opts.Endpoint != "" && opts.UserID != "" {
+			if err := emitSession(opts.Endpoint, opts.UserID, opts.Version, session); err != nil && os.Getenv("JTPCK_DEBUG") != "" {
+				fmt.Fprintf(os.Stderr, "jtpck: failed to report session: %v\n", err)
+			}
+		}
+	}
+
+	if opts.Spool != nil {
+		opts.Spool.Close()
+	}
+	if opts.Relay != nil {
+		opts.Relay.Close()
+	}
+
+	return session.ExitCode
+}
+
+// supervise runs the tool as a child process and waits for it.
+//
+// The child shares our stdio and process group, so the terminal delivers
+// Ctrl-C, Ctrl-\ and window-size changes to it directly. We only relay
+// signals addressed to the wrapper itself (kill, hang-up, user signals).
+func supervise(opts Options, env []string) int {
+	cmd := exec.Command(opts.ToolPath, opts.Args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "jtpck: failed to start %s: %v\n", opts.Tool, err)
+		return 127
+	}
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGINT || sig == syscall.SIGQUIT {
+				// Already delivered to the child by the terminal
+				continue
+			}
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	return exitCode(cmd.Wait())
+}
+
+// exitCode maps a Wait error to a shell-style exit status
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
@@ -0,0 +1,129 @@
+package launcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// otlpAttr is an OTLP/JSON key-value attribute
+type otlpAttr struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func stringAttr(key, value string) otlpAttr {
+	return otlpAttr{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+// intAttr encodes an int the way OTLP/JSON expects (64-bit ints as strings)
+func intAttr(key string, value int64) otlpAttr {
+	return otlpAttr{Key: key, Value: map[string]string{"intValue": strconv.FormatInt(value, 10)}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// emitSession sends the jtpck.session span and log record to the endpoint
+func emitSession(endpoint, userID, version string, s *Session) error {
+	resource := map[string]interface{}{
+		"attributes": []otlpAttr{
+			stringAttr("service.name", "jtpck"),
+			stringAttr("user.private_uuid", userID),
+		},
+	}
+	scope := map[string]string{"name": "jtpck", "version": version}
+
+	attrs := []otlpAttr{
+		stringAttr("session.id", s.ID),
+		stringAttr("jtpck.tool", s.Tool),
+		stringAttr("jtpck.tool.version", s.ToolVersion),
+		intAttr("process.exit.code", int64(s.ExitCode)),
+		intAttr("jtpck.session.duration_ms", s.Duration().Milliseconds()),
+	}
+
+	// OTLP status codes: 1 = OK, 2 = ERROR
+	status := map[string]interface{}{"code": 1}
+	severity, severityText := 9, "INFO"
+	if s.ExitCode != 0 {
+		status = map[string]interface{}{"code": 2, "message": fmt.Sprintf("exit code %d", s.ExitCode)}
+		severity, severityText = 17, "ERROR"
+	}
+
+	traces := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": resource,
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": scope,
+				"spans": []interface{}{map[string]interface{}{
+					"traceId":           s.ID,
+					"spanId":            s.SpanID,
+					"name":              "jtpck.session",
+					"kind":              1,
+					"startTimeUnixNano": unixNano(s.Start),
+					"endTimeUnixNano":   unixNano(s.End),
+					"attributes":        attrs,
+					"status":            status,
+				}},
+			}},
+		}},
+	}
+
+	logs := map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": resource,
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope": scope,
+				"logRecords": []interface{}{map[string]interface{}{
+					"timeUnixNano":         unixNano(s.End),
+					"observedTimeUnixNano": unixNano(time.Now()),
+					"severityNumber":       severity,
+					"severityText":         severityText,
+					"body":                 map[string]string{"stringValue": "jtpck.session"},
+					"attributes": append(attrs,
+						intAttr("jtpck.session.start_time_unix_nano", s.Start.UnixNano()),
+						intAttr("jtpck.session.end_time_unix_nano", s.End.UnixNano()),
+					),
+					"traceId": s.ID,
+					"spanId":  s.SpanID,
+				}},
+			}},
+		}},
+	}
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	if err := postOTLP(client, endpoint+"/v1/traces", userID, traces); err != nil {
+		return err
+	}
+	return postOTLP(client, endpoint+"/v1/logs", userID, logs)
+}
+
+// postOTLP posts an OTLP/JSON payload with the JTPCK bearer token
+func postOTLP(client *http.Client, url, userID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding OTLP payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+userID)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("posting to %s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package launcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RelayTarget is a collector URL and the headers it expects
+type RelayTarget struct {
+	URL     string
+	Headers map[string]string
+}
+
+// Relay is a local OTLP/HTTP receiver that forwards every export to
+// several collectors, for tools that export to only one.
+type Relay struct {
+	listener net.Listener
+	server   *http.Server
+	client   *http.Client
+	routes   map[string][]RelayTarget
+}
+
+// StartRelay starts a relay on a random loopback port. routes maps a
+// request path, such as /v1/logs, to the collectors that receive it.
+func StartRelay(routes map[string][]RelayTarget) (*Relay, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting relay: %w", err)
+	}
+
+	r := &Relay{
+		listener: listener,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		routes:   routes,
+	}
+	r.server = &http.Server{Handler: http.HandlerFunc(r.handle)}
+	go r.server.Serve(listener)
+
+	return r, nil
+}
+
+// URL returns the endpoint tools should export to
+func (r *Relay) URL() string {
+	return "http://" + r.listener.Addr().String()
+}
+
+// Close stops the relay
+func (r *Relay) Close() error {
+	return r.server.Close()
+}
+
+// handle forwards an export to each collector of its path and answers
+// with the first response that accepted it, or an error if none did
+func (r *Relay) handle(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	targets, ok := r.routes[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	var reply *http.Response
+	var replyBody []byte
+	for _, target := range targets {
+		resp, err := r.forward(req, target, body)
+		if err != nil {
+			continue
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if reply == nil && resp.StatusCode < 300 {
+			reply, replyBody = resp, respBody
+		}
+	}
+
+	if reply == nil {
+		http.Error(w, "no collector accepted the export", http.StatusBadGateway)
+		return
+	}
+	if contentType := reply.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(reply.StatusCode)
+	w.Write(replyBody)
+}
+
+func (r *Relay) forward(req *http.Request, target RelayTarget, body []byte) (*http.Response, error) {
+	out, err := http.NewRequest(req.Method, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Content-Type", "Content-Encoding"} {
+		if value := req.Header.Get(name); value != "" {
+			out.Header.Set(name, value)
+		}
+	}
+	for name, value := range target.Headers {
+		out.Header.Set(name, value)
+	}
+	return r.client.Do(out)
+}
@@ -0,0 +1,118 @@
+package launcher
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// received is an export as a collector saw it
+type received struct {
+	path, contentType, encoding, auth, body string
+}
+
+// recordingCollector answers every export with status and reply and keeps it
+func recordingCollector(t *testing.T, status int, reply string) (*httptest.Server, func() []received) {
+	var mu sync.Mutex
+	var exports []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		exports = append(exports, received{r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding"), r.Header.Get("Authorization"), string(body)})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), exports...)
+	}
+}
+
+func postExport(t *testing.T, url string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(`{"resourceLogs":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "identity")
+	req.Header.Set("Authorization", "Bearer from-tool")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRelayForwardsToEveryCollector(t *testing.T) {
+	jtpck, jtpckExports := recordingCollector(t, http.StatusOK, `{"from":"jtpck"}`)
+	own, ownExports := recordingCollector(t, http.StatusOK, `{"from":"own"}`)
+	relay, err := StartRelay(map[string][]RelayTarget{
+		"/v1/logs": {
+			{URL: jtpck.URL + "/v1/logs", Headers: map[string]string{"Authorization": "Bearer jtpck-user"}},
+			{URL: own.URL + "/ingest/logs", Headers: map[string]string{"Authorization": "Bearer own-key"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+
+	status, reply := postExport(t, relay.URL()+"/v1/logs")
+	if status != http.StatusOK || reply != `{"from":"jtpck"}` {
+		t.Errorf("relay answered %d %q, want the first collector's reply", status, reply)
+	}
+	want := []received{{"/v1/logs", "application/json", "identity", "Bearer jtpck-user", `{"resourceLogs":[]}`}}
+	if got := jtpckExports(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("JTPCK collector got %+v", got)
+	}
+	want[0].path, want[0].auth = "/ingest/logs", "Bearer own-key"
+	if got := ownExports(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("own collector got %+v", got)
+	}
+
+	if status, _ := postExport(t, relay.URL()+"/v1/traces"); status != http.StatusNotFound {
+		t.Errorf("unrouted path answered %d, want 404", status)
+	}
+}
+
+func TestRelayReplies(t *testing.T) {
+	rejecting, _ := recordingCollector(t, http.StatusUnauthorized, `{"error":"token"}`)
+	accepting, accepted := recordingCollector(t, http.StatusOK, `{"partialSuccess":{}}`)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name    string
+		targets []string
+		status  int
+		reply   string
+	}{
+		{"first accepting collector", []string{down.URL, rejecting.URL, accepting.URL}, http.StatusOK, `{"partialSuccess":{}}`},
+		{"none accepting", []string{down.URL, rejecting.URL}, http.StatusBadGateway, "no collector accepted the export\n"},
+	}
+	for _, tt := range tests {
+		var targets []RelayTarget
+		for _, url := range tt.targets {
+			targets = append(targets, RelayTarget{URL: url})
+		}
+		relay, err := StartRelay(map[string][]RelayTarget{"/v1/metrics": targets})
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, reply := postExport(t, relay.URL()+"/v1/metrics")
+		relay.Close()
+		if status != tt.status || reply != tt.reply {
+			t.Errorf("%s: relay answered %d %q, want %d %q", tt.name, status, reply, tt.status, tt.reply)
+		}
+	}
+	if got := accepted(); len(got) != 1 || got[0].auth != "" {
+		t.Errorf("accepting collector got %+v; the tool's own headers must not be forwarded", got)
+	}
+}
@@ -0,0 +1,60 @@
+package launcher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Session describes one supervised run of a tool
+type Session struct {
+	ID          string
+	SpanID      string
+	Tool        string
+	ToolVersion string
+	Start       time.Time
+	End         time.Time
+	ExitCode    int
+}
+
+// newSession creates a session with fresh trace/span identifiers.
+// The session ID doubles as the OTLP trace ID so the record can be correlated.
+func newSession(tool string) *Session {
+	return &Session{
+		ID:     randomHex(16),
+		SpanID: randomHex(8),
+		Tool:   tool,
+		Start:  time.Now(),
+	}
+}
+
+// Duration returns how long the session ran
+func (s *Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return strings.Repeat("0", n*2)
+	}
+	return hex.EncodeToString(b)
+}
+
+// toolVersion asks the tool for its version, giving up quickly so a slow
+// or interactive tool never delays the session record.
+func toolVersion(toolPath string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, toolPath, "--version").Output()
+	if err != nil {
+		return ""
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line)
+}
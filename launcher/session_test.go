@@ -0,0 +1,151 @@
+package launcher
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// writeScript writes an executable shell script and returns its path
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	os.MkdirAll(dir, 0700)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// otlpCollector records the JSON payloads posted to it by path
+type otlpCollector struct {
+	mu       sync.Mutex
+	payloads map[string]map[string]interface{}
+	auth     string
+}
+
+func newOTLPCollector(t *testing.T) (*otlpCollector, *httptest.Server) {
+	c := &otlpCollector{payloads: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("%s: %v", r.URL.Path, err)
+		}
+		c.mu.Lock()
+		c.payloads[r.URL.Path] = payload
+		c.auth = r.Header.Get("Authorization")
+		c.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+// record digs the first span or log record out of an OTLP/JSON payload
+func record(payload map[string]interface{}, resourceKey, scopeKey, recordKey string) map[string]interface{} {
+	defer func() { recover() }()
+	resource := payload[resourceKey].([]interface{})[0].(map[string]interface{})
+	scope := resource[scopeKey].([]interface{})[0].(map[string]interface{})
+	return scope[recordKey].([]interface{})[0].(map[string]interface{})
+}
+
+// attrs flattens OTLP attributes to their values
+func attrs(rec map[string]interface{}) map[string]string {
+	values := map[string]string{}
+	list, _ := rec["attributes"].([]interface{})
+	for _, a := range list {
+		a := a.(map[string]interface{})
+		for _, v := range a["value"].(map[string]interface{}) {
+			values[a["key"].(string)] = v.(string)
+		}
+	}
+	return values
+}
+
+func TestRunSuperviseReportsSession(t *testing.T) {
+	collector, server := newOTLPCollector(t)
+	t.Setenv(SessionEnvVar, "")
+	dir := t.TempDir()
+	tool := writeScript(t, dir, "tool", `[ "$1" = --version ] && { echo "tool 1.2.3"; exit 0; }
+[ -n "$JTPCK_SESSION_ID" ] || exit 9
+exit 3
+`)
+
+	code := Run(Options{Tool: "claude", ToolPath: tool, Endpoint: server.URL, UserID: "user-1", Version: "test", Supervise: true})
+	if code != 3 {
+		t.Fatalf("exit code = %d, want 3", code)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.auth != "Bearer user-1" {
+		t.Errorf("Authorization = %q", collector.auth)
+	}
+	span := record(collector.payloads["/v1/traces"], "resourceSpans", "scopeSpans", "spans")
+	if span == nil {
+		t.Fatalf("no span in %v", collector.payloads["/v1/traces"])
+	}
+	if span["name"] != "jtpck.session" || len(span["traceId"].(string)) != 32 || len(span["spanId"].(string)) != 16 {
+		t.Errorf("span = %v", span)
+	}
+	if status := span["status"].(map[string]interface{}); status["code"] != 2.0 || status["message"] != "exit code 3" {
+		t.Errorf("status = %v", status)
+	}
+	want := map[string]string{"jtpck.tool": "claude", "jtpck.tool.version": "tool 1.2.3", "process.exit.code": "3"}
+	got := attrs(span)
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("span %s = %q, want %q", key, got[key], value)
+		}
+	}
+
+	log := record(collector.payloads["/v1/logs"], "resourceLogs", "scopeLogs", "logRecords")
+	if log == nil {
+		t.Fatalf("no log record in %v", collector.payloads["/v1/logs"])
+	}
+	if log["severityText"] != "ERROR" || log["traceId"] != span["traceId"] {
+		t.Errorf("log record = %v", log)
+	}
+	if attrs(log)["jtpck.session.start_time_unix_nano"] != span["startTimeUnixNano"] {
+		t.Errorf("log start time %q, span %q", attrs(log)["jtpck.session.start_time_unix_nano"], span["startTimeUnixNano"])
+	}
+}
+
+func TestEmitSessionFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	s := newSession("codex")
+	s.End = s.Start
+	if err := emitSession(server.URL, "user-1", "test", s); err == nil {
+		t.Error("a rejected export was not reported")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		body string
+		want int
+	}{
+		{"exit 0\n", 0},
+		{"exit 42\n", 42},
+		{"kill -TERM $$\n", 128 + 15},
+	}
+	for i, tt := range tests {
+		script := writeScript(t, dir, "script"+strconv.Itoa(i), tt.body)
+		if got := supervise(Options{ToolPath: script}, os.Environ()); got != tt.want {
+			t.Errorf("%q exits %d, want %d", tt.body, got, tt.want)
+		}
+	}
+	if got := supervise(Options{ToolPath: filepath.Join(dir, "missing")}, os.Environ()); got != 127 {
+		t.Errorf("missing tool exits %d, want 127", got)
+	}
+}
@@ -0,0 +1,96 @@
+package launcher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Spool is a local OTLP/HTTP receiver that appends every export to a file
+// while no endpoint is reachable, so offline sessions are kept for later.
+type Spool struct {
+	listener net.Listener
+	server   *http.Server
+	file     *os.File
+	mu       sync.Mutex
+}
+
+// spoolRecord is one line of a spool file
+type spoolRecord struct {
+	Time        time.Time `json:"time"`
+	Path        string    `json:"path"`
+	ContentType string    `json:"content_type"`
+	Body        string    `json:"body"` // base64; OTLP may be protobuf
+}
+
+// StartSpool starts a receiver on a random loopback port, writing to a
+// per-day file in dir.
+func StartSpool(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating spool directory: %w", err)
+	}
+
+	name := filepath.Join(dir, time.Now().Format("2006-01-02")+".jsonl")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening spool file: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("starting spool receiver: %w", err)
+	}
+
+	s := &Spool{listener: listener, file: file}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.handle)}
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// URL returns the endpoint tools should export to
+func (s *Spool) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close stops the receiver and flushes the spool file
+func (s *Spool) Close() error {
+	s.server.Close()
+	return s.file.Close()
+}
+
+func (s *Spool) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	line, _ := json.Marshal(spoolRecord{
+		Time:        time.Now(),
+		Path:        r.URL.Path,
+		ContentType: r.Header.Get("Content-Type"),
+		Body:        base64.StdEncoding.EncodeToString(body),
+	})
+
+	s.mu.Lock()
+	s.file.Write(append(line, '\n'))
+	s.mu.Unlock()
+
+	// An empty export response is valid for both OTLP encodings
+	if r.Header.Get("Content-Type") == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
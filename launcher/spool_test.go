@@ -0,0 +1,87 @@
+package launcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpoolRecordsExports(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	spool, err := StartSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports := []struct {
+		path, contentType, body, reply string
+	}{
+		{"/v1/logs", "application/json", `{"resourceLogs":[]}`, "{}"},
+		{"/v1/traces", "application/x-protobuf", "\x0a\x00", ""},
+	}
+	for _, e := range exports {
+		resp, err := http.Post(spool.URL()+e.path, e.contentType, bytes.NewReader([]byte(e.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(reply) != e.reply {
+			t.Errorf("%s answered %d %q", e.path, resp.StatusCode, reply)
+		}
+	}
+	if err := spool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, time.Now().Format("2006-01-02")+".jsonl")
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("spool file: %v, %v", info, err)
+	}
+	file, _ := os.Open(path)
+	defer file.Close()
+	var records []spoolRecord
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		var r spoolRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != len(exports) {
+		t.Fatalf("%d records, want %d", len(records), len(exports))
+	}
+	for i, e := range exports {
+		body, _ := base64.StdEncoding.DecodeString(records[i].Body)
+		if records[i].Path != e.path || records[i].ContentType != e.contentType || string(body) != e.body {
+			t.Errorf("record %d = %+v (body %q)", i, records[i], body)
+		}
+	}
+}
+
+func TestSpoolAppends(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		spool, err := StartSpool(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(spool.URL()+"/v1/metrics", "application/json", bytes.NewReader([]byte("{}")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		spool.Close()
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, time.Now().Format("2006-01-02")+".jsonl"))
+	if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+		t.Errorf("spool file has %d lines after two sessions, want 2", lines)
+	}
+}
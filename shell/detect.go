@@ -1,17 +1,18 @@
 package shell
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/jtpck/installer/config"
+	"github.com/jtpck/installer/wrapper"
 )
 
 // DetectShellConfig attempts to detect the user's shell config file
 func DetectShellConfig() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
+	home := config.HomeDir()
 
 	// Check SHELL environment variable
 	shell := os.Getenv("SHELL")
@@ -42,12 +43,9 @@ func DetectShellConfig() string {
 
 // GenerateAliasCommands generates shell alias commands for the given tools
 func GenerateAliasCommands(tools []string) string {
-	home, _ := os.UserHomeDir()
-	wrapperDir := filepath.Join(home, ".jtpck")
-
 	var cmds strings.Builder
 	for _, tool := range tools {
-		wrapperPath := filepath.Join(wrapperDir, tool+"-wrapper")
+		wrapperPath := config.InTarget(wrapper.WrapperPath(tool))
 		cmds.WriteString("alias ")
 		cmds.WriteString(tool)
 		cmds.WriteString("='")
@@ -57,14 +55,53 @@ func GenerateAliasCommands(tools []string) string {
 	return cmds.String()
 }
 
+// KnownShells lists the shell names ShellConfigFor accepts
+var KnownShells = []string{"bash", "zsh"}
+
+// rcFiles are the shell configs an alias block may have been written to
+var rcFiles = []string{".zshrc", ".zprofile", ".bashrc", ".bash_profile", ".profile"}
+
+// ShellConfigFor returns the rc file used for a shell name
+func ShellConfigFor(name string) (string, error) {
+	switch name {
+	case "zsh":
+		return ".zshrc", nil
+	case "bash":
+		return ".bashrc", nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (want %s)", name, strings.Join(KnownShells, " or "))
+}
+
+// ShellConfigsWithAliases returns every rc file holding a JTPCK alias block
+func ShellConfigsWithAliases() []string {
+	var found []string
+	for _, rc := range rcFiles {
+		if AliasesInstalledIn(rc) {
+			found = append(found, rc)
+		}
+	}
+	return found
+}
+
+// BackupPath returns where InstallAliases backs up the shell config
+func BackupPath() string {
+	return BackupPathFor(DetectShellConfig())
+}
+
+// BackupPathFor returns where InstallAliasesIn backs up an rc file
+func BackupPathFor(shellConfig string) string {
+	return filepath.Join(config.BackupDir(), shellConfig+".jtpck-backup")
+}
+
 // InstallAliases appends aliases to shell config with backup
 func InstallAliases(tools []string) error {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
+	return InstallAliasesIn(DetectShellConfig(), tools)
+}
+
+// InstallAliasesIn appends aliases to an rc file in $HOME, with backup
+func InstallAliasesIn(shellConfig string, tools []string) error {
+	home := config.HomeDir()
 
-	shellConfig := DetectShellConfig()
 	configPath := filepath.Join(home, shellConfig)
 
 	// Check if config file exists, create if not
@@ -76,36 +113,94 @@ func InstallAliases(tools []string) error {
 	}
 
 	// Backup existing config
-	backupPath := configPath + ".jtpck-backup"
+	backupPath := BackupPathFor(shellConfig)
 	input, err := os.ReadFile(configPath)
 	if err != nil {
 		return err
 	}
-	if err := os.WriteFile(backupPath, input, 0644); err != nil {
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0700); err != nil {
+		return err
+	}
+	// Re-running setup must not back up its own alias block, or uninstall
+	// would restore it
+	backup := string(input)
+	if strings.Contains(backup, "# JTPCK Telemetry Aliases - START") {
+		backup = strings.TrimRight(stripAliasBlock(backup), "\n") + "\n"
+	}
+	if err := os.WriteFile(backupPath, []byte(backup), 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(backupPath, 0600); err != nil {
 		return err
 	}
 
-	// Read current config
-	content := string(input)
+	return writeAliasBlock(configPath, string(input), tools)
+}
+
+// UpdateAliases rewrites an installed alias block, e.g. after the wrappers
+// moved, without replacing the backup taken at install time
+func UpdateAliases(tools []string) error {
+	return UpdateAliasesIn(DetectShellConfig(), tools)
+}
 
-	// Check if JTPCK aliases already exist
-	if strings.Contains(content, "# JTPCK Telemetry Aliases") {
-		// Remove old JTPCK section
-		lines := strings.Split(content, "\n")
-		var newLines []string
-		skipUntilEnd := false
-		for _, line := range lines {
-			if strings.Contains(line, "# JTPCK Telemetry Aliases - START") {
-				skipUntilEnd = true
-			}
-			if !skipUntilEnd {
-				newLines = append(newLines, line)
-			}
-			if strings.Contains(line, "# JTPCK Telemetry Aliases - END") {
-				skipUntilEnd = false
-			}
+// UpdateAliasesIn rewrites the alias block in an rc file, if it has one
+func UpdateAliasesIn(shellConfig string, tools []string) error {
+	if !AliasesInstalledIn(shellConfig) {
+		return nil
+	}
+
+	home := config.HomeDir()
+
+	configPath := filepath.Join(home, shellConfig)
+	input, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	return writeAliasBlock(configPath, string(input), tools)
+}
+
+// RemoveAliasesFrom deletes the alias block from an rc file
+func RemoveAliasesFrom(shellConfig string) error {
+	if !AliasesInstalledIn(shellConfig) {
+		return nil
+	}
+
+	home := config.HomeDir()
+
+	configPath := filepath.Join(home, shellConfig)
+	input, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	content := strings.TrimRight(stripAliasBlock(string(input)), "\n") + "\n"
+	return os.WriteFile(configPath, []byte(content), 0644)
+}
+
+// stripAliasBlock removes the JTPCK alias block from rc file content
+func stripAliasBlock(content string) string {
+	lines := strings.Split(content, "\n")
+	var newLines []string
+	skipUntilEnd := false
+	for _, line := range lines {
+		if strings.Contains(line, "# JTPCK Telemetry Aliases - START") {
+			skipUntilEnd = true
+		}
+		if !skipUntilEnd {
+			newLines = append(newLines, line)
 		}
-		content = strings.Join(newLines, "\n")
+		if strings.Contains(line, "# JTPCK Telemetry Aliases - END") {
+			skipUntilEnd = false
+		}
+	}
+	return strings.Join(newLines, "\n")
+}
+
+// writeAliasBlock replaces any JTPCK alias block in content with one for
+// tools and writes the result to configPath
+func writeAliasBlock(configPath, content string, tools []string) error {
+	// Remove old JTPCK section
+	if strings.Contains(content, "# JTPCK Telemetry Aliases") {
+		content = stripAliasBlock(content)
 	}
 
 	// Generate alias commands
@@ -123,3 +218,19 @@ func InstallAliases(tools []string) error {
 	// Write updated config
 	return os.WriteFile(configPath, []byte(sb.String()), 0644)
 }
+
+// AliasesInstalled reports whether the JTPCK alias block is present in the shell config
+func AliasesInstalled() bool {
+	return AliasesInstalledIn(DetectShellConfig())
+}
+
+// AliasesInstalledIn reports whether an rc file holds the JTPCK alias block
+func AliasesInstalledIn(shellConfig string) bool {
+	home := config.HomeDir()
+
+	content, err := os.ReadFile(filepath.Join(home, shellConfig))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), "# JTPCK Telemetry Aliases - START")
+}
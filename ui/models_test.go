@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jtpck/installer/internal/harness"
+)
+
+func TestInputModel(t *testing.T) {
+	tests := []struct {
+		typed, wantErr string
+	}{
+		{"\n", "User ID cannot be empty"},
+		{"not-a-uuid\n", "must be a valid UUID"},
+	}
+	for _, tt := range tests {
+		model, quit := harness.Drive(NewInputModel(""), harness.Keys(tt.typed)...)
+		if quit {
+			t.Errorf("%q: quit on invalid input", tt.typed)
+		}
+		if view := model.View(); !strings.Contains(view, tt.wantErr) {
+			t.Errorf("%q: view does not show %q:\n%s", tt.typed, tt.wantErr, view)
+		}
+	}
+
+	// A valid ID is accepted after an error, whatever its case
+	keys := harness.Keys("oops\n" + strings.Repeat("<backspace>", 4) + " 12345678-1234-1234-1234-123456789ABC\n")
+	model, quit := harness.Drive(NewInputModel(""), keys...)
+	input := model.(InputModel)
+	if !quit || !input.Done() || input.GetUserID() != "12345678-1234-1234-1234-123456789abc" {
+		t.Errorf("quit = %v, done = %v, user ID = %q", quit, input.Done(), input.GetUserID())
+	}
+	if model.View() != "" {
+		t.Error("the screen is not cleared after submitting")
+	}
+}
+
+func TestInputModelReconfigure(t *testing.T) {
+	current := "12345678-1234-1234-1234-123456789abc"
+	model := NewInputModel(current)
+	if view := model.View(); !strings.Contains(view, "Current User ID") {
+		t.Errorf("view does not show the current ID:\n%s", view)
+	}
+
+	// Enter keeps the prefilled value
+	final, quit := harness.Drive(model, harness.Keys("\n")...)
+	if !quit || final.(InputModel).GetUserID() != current {
+		t.Errorf("quit = %v, user ID = %q", quit, final.(InputModel).GetUserID())
+	}
+}
+
+func TestInputModelCancel(t *testing.T) {
+	for _, key := range []string{"<esc>", "<ctrl+c>"} {
+		model, quit := harness.Drive(NewInputModel(""), harness.Keys("1234"+key)...)
+		if !quit || model.(InputModel).Done() {
+			t.Errorf("%s: quit = %v, done = %v", key, quit, model.(InputModel).Done())
+		}
+	}
+}
+
+func TestSuccessModel(t *testing.T) {
+	model := NewSuccessModel(".zshrc", []string{"claude"}, "alias claude='x'\n", true, []string{"Enabled Claude Code telemetry"})
+	view := model.View()
+	for _, want := range []string{"Success!", "Enabled Claude Code telemetry", "source ~/.zshrc", "Press any key to exit"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view does not contain %q:\n%s", want, view)
+		}
+	}
+
+	manual := NewSuccessModel(".bashrc", []string{"claude"}, "alias claude='x'\n", false, nil)
+	if view := manual.View(); !strings.Contains(view, "echo 'alias claude='x'' >> ~/.bashrc") {
+		t.Errorf("manual view has no copy command:\n%s", view)
+	}
+
+	// Messages other than keys leave the screen up
+	final, quit := harness.Drive(model, tea.WindowSizeMsg{Width: 80, Height: 24})
+	if quit {
+		t.Error("quit on a resize")
+	}
+	final, quit = harness.Drive(final, harness.Keys("q")...)
+	if !quit || !final.(SuccessModel).Done() || final.View() != "" {
+		t.Errorf("quit = %v, done = %v after a key", quit, final.(SuccessModel).Done())
+	}
+}
+
+func TestAnimationModel(t *testing.T) {
+	model := NewAnimationModel()
+	if view := model.View(); !strings.Contains(view, "Installing JTPCK") {
+		t.Errorf("view has no title:\n%s", view)
+	}
+
+	// The animation ends after its last frame
+	ticks := make([]tea.Msg, len(model.frames))
+	for i := range ticks {
+		ticks[i] = AnimationDoneMsg{}
+	}
+	final, quit := harness.Drive(model, ticks[:len(ticks)-1]...)
+	if quit {
+		t.Fatal("quit before the last frame")
+	}
+	if _, quit := harness.Drive(final, ticks[len(ticks)-1]); !quit {
+		t.Error("did not quit after the last frame")
+	}
+
+	// Any key skips it
+	final, quit = harness.Drive(model, AnimationDoneMsg{}, tea.KeyMsg{Type: tea.KeySpace})
+	if !quit || final.View() != "" {
+		t.Errorf("quit = %v, view = %q after a key", quit, final.View())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jtpck/installer/shell"
 )
 
 type SuccessModel struct {
@@ -64,7 +65,7 @@ func (m SuccessModel) View() string {
 		}
 		sb.WriteString(HelpStyle.Render("Restart your terminal or run: ") + CodeStyle.Render("source ~/"+m.shellConfig))
 		sb.WriteString("\n")
-		sb.WriteString(HelpStyle.Render("Backup: ~/" + m.shellConfig + ".jtpck-backup"))
+		sb.WriteString(HelpStyle.Render("Backup: " + shell.BackupPath()))
 	} else {
 		// Manual installation - show copy/paste instructions
 		sb.WriteString(LabelStyle.Render("To activate telemetry, add to your ~/" + m.shellConfig + ":"))
@@ -1,7 +1,7 @@
 package validator
 
 import (
-	"os/exec"
+	"github.com/jtpck/installer/config"
 )
 
 // ToolStatus represents the installation status of a tool
@@ -16,7 +16,7 @@ func CheckTools(tools []string) []ToolStatus {
 	var statuses []ToolStatus
 
 	for _, tool := range tools {
-		path, err := exec.LookPath(tool)
+		path, err := config.LookPath(tool)
 		status := ToolStatus{
 			Name:      tool,
 			Installed: err == nil,
@@ -3,14 +3,14 @@ package wrapper
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+
+	"github.com/jtpck/installer/config"
 )
 
-// WrapperDir returns the path to .jtpck directory
+// WrapperDir returns the directory holding the wrapper scripts
 func WrapperDir() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".jtpck")
+	return config.DataDir()
 }
 
 // WrapperPath returns the path for a specific tool wrapper
@@ -18,16 +18,37 @@ func WrapperPath(toolName string) string {
 	return filepath.Join(WrapperDir(), fmt.Sprintf("%s-wrapper", toolName))
 }
 
+// Options controls how wrapper scripts launch their tool
+type Options struct {
+	// Supervise asks `jtpck run` to report each session
+	Supervise bool
+	// Launcher is the jtpck binary that sets up and launches the tool
+	Launcher string
+	// Policy decides how each variable combines with an existing value
+	Policy func(key string) config.EnvPolicy
+	// Token is replaced in the fallback env by a lookup of Profile in the
+	// credentials file, so scripts never embed it
+	Token   string
+	Profile string
+}
+
+func (o Options) policyFor(key string) config.EnvPolicy {
+	if o.Policy == nil {
+		return config.PolicyOverride
+	}
+	return o.Policy(key)
+}
+
 // CreateWrappers creates wrapper scripts for all tools with per-tool env vars.
-func CreateWrappers(envs map[string]map[string]string, tools []string) error {
+func CreateWrappers(envs map[string]map[string]string, tools []string, opts Options) error {
 	// Ensure directory exists
-	if err := os.MkdirAll(WrapperDir(), 0755); err != nil {
+	if err := os.MkdirAll(WrapperDir(), 0700); err != nil {
 		return fmt.Errorf("failed to create wrapper directory: %w", err)
 	}
 
 	for _, tool := range tools {
 		// Find tool path
-		toolPath, err := exec.LookPath(tool)
+		toolPath, err := config.LookPath(tool)
 		if err != nil {
 			// Tool not found, skip but continue
 			continue
@@ -35,13 +56,16 @@ func CreateWrappers(envs map[string]map[string]string, tools []string) error {
 
 		env := envs[tool]
 		// Generate script
-		script := GenerateScript(tool, toolPath, env)
+		script := GenerateScript(tool, toolPath, env, opts)
 
 		// Write to file
 		wrapperPath := WrapperPath(tool)
-		if err := os.WriteFile(wrapperPath, []byte(script), 0755); err != nil {
+		if err := os.WriteFile(wrapperPath, []byte(script), 0700); err != nil {
 			return fmt.Errorf("failed to write %s wrapper: %w", tool, err)
 		}
+		if err := os.Chmod(wrapperPath, 0700); err != nil {
+			return fmt.Errorf("failed to secure %s wrapper: %w", tool, err)
+		}
 	}
 
 	return nil
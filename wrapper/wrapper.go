@@ -2,8 +2,11 @@ package wrapper
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/jtpck/installer/config"
 )
 
 // shellEscape escapes a string for safe use in shell double quotes
@@ -17,17 +20,60 @@ func shellEscape(s string) string {
 }
 
 // GenerateScript creates a wrapper script for the given tool
-func GenerateScript(toolName, toolPath string, env map[string]string) string {
+func GenerateScript(toolName, toolPath string, env map[string]string, opts Options) string {
 	var sb strings.Builder
 
 	sb.WriteString("#!/bin/bash\n")
 	sb.WriteString(fmt.Sprintf("# JTPCK Telemetry Wrapper for %s\n", toolName))
 	sb.WriteString(fmt.Sprintf("# Generated: %s\n\n", time.Now().Format(time.RFC3339)))
 
+	// Run the tool with telemetry off while paused; the config files of
+	// Codex and Gemini still name JTPCK. Expired pauses are cleared.
+	pauseEnv, pauseArgs := config.PausedLaunch(toolName)
+	sb.WriteString("# Switch telemetry off while paused (see `jtpck pause`)\n")
+	sb.WriteString(fmt.Sprintf("PAUSE_FILE=\"%s\"\n", shellEscape(config.InTarget(config.PausePath()))))
+	sb.WriteString("if [ -f \"$PAUSE_FILE\" ]; then\n")
+	sb.WriteString("  PAUSE_UNTIL=\"$(cat \"$PAUSE_FILE\" 2>/dev/null)\"\n")
+	sb.WriteString("  if [ \"$PAUSE_UNTIL\" = \"0\" ] || [ \"$(date +%s)\" -lt \"${PAUSE_UNTIL:-0}\" ] 2>/dev/null; then\n")
+	for _, key := range sortedKeys(pauseEnv) {
+		sb.WriteString(fmt.Sprintf("    export %s=\"%s\"\n", key, shellEscape(pauseEnv[key])))
+	}
+	sb.WriteString(fmt.Sprintf("    exec \"%s\"%s \"$@\"\n", shellEscape(toolPath), shellArgs(pauseArgs)))
+	sb.WriteString("  fi\n")
+	sb.WriteString("  rm -f \"$PAUSE_FILE\"\n")
+	sb.WriteString("fi\n\n")
+
+	if opts.Launcher != "" {
+		// The launcher merges the telemetry env into the caller's environment
+		runFlags := "--tool " + toolName
+		if opts.Supervise {
+			runFlags += " --supervise"
+		}
+		sb.WriteString("# Launch through jtpck to merge telemetry env, run hooks and report sessions (see `jtpck run`)\n")
+		sb.WriteString(fmt.Sprintf("JTPCK_BIN=\"%s\"\n", shellEscape(config.InTarget(opts.Launcher))))
+		sb.WriteString("if [ -x \"$JTPCK_BIN\" ]; then\n")
+		sb.WriteString(fmt.Sprintf("  exec \"$JTPCK_BIN\" run %s -- \"%s\" \"$@\"\n", runFlags, shellEscape(toolPath)))
+		sb.WriteString("fi\n\n")
+		sb.WriteString("# Fallback if the jtpck binary has since been removed\n")
+	}
+
+	// Read the token at launch rather than embedding it
+	if opts.Token != "" {
+		sb.WriteString(fmt.Sprintf("JTPCK_TOKEN=\"$(awk -F= -v p=\"%s\" '$1 == p { print substr($0, length(p) + 2); exit }' \"%s\" 2>/dev/null)\"\n",
+			shellEscape(opts.Profile), shellEscape(config.InTarget(config.CredentialsPath()))))
+		// Encrypted or keyring-held credentials need the jtpck binary
+		sb.WriteString("if [ -z \"$JTPCK_TOKEN\" ]; then\n")
+		sb.WriteString(fmt.Sprintf("  exec \"%s\" \"$@\"\n", shellEscape(toolPath)))
+		sb.WriteString("fi\n")
+	}
+
 	// Export environment variables with proper escaping
-	for key, value := range env {
-		escapedValue := shellEscape(value)
-		sb.WriteString(fmt.Sprintf("export %s=\"%s\"\n", key, escapedValue))
+	for _, key := range sortedKeys(env) {
+		value := shellEscape(env[key])
+		if opts.Token != "" {
+			value = strings.ReplaceAll(value, shellEscape(opts.Token), "${JTPCK_TOKEN}")
+		}
+		sb.WriteString(exportLine(key, value, opts.policyFor(key)))
 	}
 
 	sb.WriteString("\n")
@@ -36,3 +82,35 @@ func GenerateScript(toolName, toolPath string, env map[string]string) string {
 
 	return sb.String()
 }
+
+// shellArgs renders arguments for a command line, each in double quotes
+func shellArgs(args []string) string {
+	var sb strings.Builder
+	for _, arg := range args {
+		sb.WriteString(fmt.Sprintf(" \"%s\"", shellEscape(arg)))
+	}
+	return sb.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exportLine renders an export honoring the variable's env policy. It is a
+// plain-shell approximation of config.MergeEnv for when the launcher is
+// unavailable: lists are comma-joined without de-duplication or warnings.
+func exportLine(key, escapedValue string, policy config.EnvPolicy) string {
+	switch {
+	case policy == config.PolicyKeep:
+		return fmt.Sprintf("export %s=\"${%s:-%s}\"\n", key, key, escapedValue)
+	case policy == config.PolicyMerge && config.IsListEnv(key):
+		return fmt.Sprintf("export %s=\"${%s:+$%s,}%s\"\n", key, key, key, escapedValue)
+	default:
+		return fmt.Sprintf("export %s=\"%s\"\n", key, escapedValue)
+	}
+}